@@ -71,6 +71,9 @@ type Alarm struct {
 	AdditionalFields map[string]interface{} `jsonc:"flat"`
 }
 
+// The json fields of an alarm, which can not be updated
+var alarmReadOnlyFields = []string{"id", "self", "creationTime", "type", "time", "source", "count", "firstOccurrenceTime"}
+
 /*
 Represents cumulocity's alarm structure for update purposes.
 See: https://cumulocity.com/guides/reference/alarms/#update-an-alarm
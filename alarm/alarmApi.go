@@ -7,6 +7,8 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
 type AlarmApi interface {
@@ -19,9 +21,19 @@ type AlarmApi interface {
 	// Updates an exiting alarm and returns the updated alarm entity.
 	Update(alarmId string, alarm *UpdateAlarm) (*Alarm, *generic.Error)
 
+	// Compares an alarm before and after local modifications and updates only the changed fields.
+	// Removed custom fragments are set to null. If nothing has changed, `before` is returned without a request.
+	UpdateChanges(before *Alarm, after *Alarm) (*Alarm, *generic.Error)
+
 	// Updates status of many alarms.
 	BulkStatusUpdate(query *UpdateAlarmsFilter, newStatus Status) *generic.Error
 
+	// Updates the status of many alarms like `BulkStatusUpdate`. When cumulocity continues the update in the
+	// background, it waits until no alarm matching the filter is left in its old status.
+	// The returned summary tells how many alarms were transitioned. It is returned with the error on timeout as well.
+	// options - Timeout, polling backoff and progress callback. If nil, the defaults are used.
+	BulkStatusUpdateAndWait(query *UpdateAlarmsFilter, newStatus Status, options *BulkUpdateOptions) (*BulkUpdateSummary, *generic.Error)
+
 	// Deletion by alarm id is not supported/allowed by cumulocity.
 	// Deletes alarms by filter. If error is nil, alarms were deleted successfully.
 	// ATTENTION: at least one filter should be set otherwise an error will be thrown.
@@ -40,6 +52,10 @@ type AlarmApi interface {
 	// All query parameters are AND concatenated.
 	Find(query *AlarmFilter, pageSize int) (*AlarmCollection, *generic.Error)
 
+	// Returns the number of alarms matching the given filter, using cumulocity's count endpoint.
+	// If the filter is nil, all alarms are counted.
+	Count(query *AlarmFilter) (int, *generic.Error)
+
 	// Gets the next page from an existing alarm collection.
 	// If there is no next page, nil is returned.
 	NextPage(c *AlarmCollection) (*AlarmCollection, *generic.Error)
@@ -119,57 +135,44 @@ func (alarmApi *alarmApi) Update(alarmId string, alarm *UpdateAlarm) (*Alarm, *g
 		return nil, generic.ClientError(fmt.Sprintf("Error while marshalling the update alarm: %s", err.Error()), "UpdateAlarm")
 	}
 
-	path := fmt.Sprintf("%s/%s", alarmApi.basePath, url.QueryEscape(alarmId))
-	headers := generic.AcceptAndContentTypeHeader(ALARM_TYPE, ALARM_TYPE)
-
-	body, status, err := alarmApi.client.Put(path, bytes, headers)
-	if err != nil {
-		return nil, generic.ClientError(fmt.Sprintf("Error while updating an alarm: %s", err.Error()), "UpdateAlarm")
-	}
-	if status != http.StatusOK {
-		return nil, generic.CreateErrorFromResponse(body, status)
-	}
-
-	return parseAlarmResponse(body)
+	return alarmApi.update(alarmId, bytes, "UpdateAlarm")
 }
 
 /*
-Updates the status of many alarms at once searching by filter.
+Updates the changed fields of an alarm. `before` is the alarm as received from cumulocity,
+`after` the locally modified copy. Changes of read-only fields, e.g. the source or count, are not sent.
 
-See: https://cumulocity.com/guides/reference/alarms/#put-bulk-update-of-alarm-collection
+See: https://cumulocity.com/guides/reference/alarms/#update-an-alarm
 */
-func (alarmApi *alarmApi) BulkStatusUpdate(updateAlarmsFilter *UpdateAlarmsFilter, newStatus Status) *generic.Error {
-	alarmStatus := UpdateAlarm{Status: newStatus}
-
-	bytes, err := json.Marshal(alarmStatus)
-	if err != nil {
-		return generic.ClientError(fmt.Sprintf("Error while marshalling the update of alarms: %s", err.Error()), "BulkStatusUpdate")
+func (alarmApi *alarmApi) UpdateChanges(before *Alarm, after *Alarm) (*Alarm, *generic.Error) {
+	if before == nil || after == nil || len(before.Id) == 0 {
+		return nil, generic.ClientError("Updating changes needs an existing alarm with id before and after the changes", "UpdateAlarmChanges")
 	}
 
-	queryParamsValues := &url.Values{}
-	err = updateAlarmsFilter.QueryParams(queryParamsValues)
+	diff, err := generic.UpdateDiff(before, after, alarmReadOnlyFields...)
 	if err != nil {
-		return generic.ClientError(fmt.Sprintf("Error while building query parameters for update of alarms: %s", err.Error()), "BulkStatusUpdate")
+		return nil, generic.ClientError(fmt.Sprintf("Error while comparing the alarms: %s", err.Error()), "UpdateAlarmChanges")
+	}
+	if len(diff) == 0 {
+		return before, nil
 	}
 
-	path := fmt.Sprintf("%s?%s", alarmApi.basePath, queryParamsValues.Encode())
-	headers := generic.AcceptHeader(ALARM_TYPE)
-
-	body, status, err := alarmApi.client.Put(path, bytes, headers)
+	bytes, err := json.Marshal(diff)
 	if err != nil {
-		return generic.ClientError(fmt.Sprintf("Error while updating alarms: %s", err.Error()), "BulkStatusUpdate")
+		return nil, generic.ClientError(fmt.Sprintf("Error while marshalling the alarm changes: %s", err.Error()), "UpdateAlarmChanges")
 	}
 
-	// Since this operations can take a lot of time, request returns after maximum 0.5 sec of processing,
-	// and updating is continued as a background process in the platform.
-	// Therefore following possible response statuses can be interpret as successful:
-	//	200 - if the process has completed, all alarms have been updated
-	//	202 - if process continues in background (maybe )
-	if status != http.StatusOK && status != http.StatusAccepted {
-		return generic.CreateErrorFromResponse(body, status)
-	}
+	return alarmApi.update(before.Id, bytes, "UpdateAlarmChanges")
+}
 
-	return nil
+/*
+Updates the status of many alarms at once searching by filter.
+
+See: https://cumulocity.com/guides/reference/alarms/#put-bulk-update-of-alarm-collection
+*/
+func (alarmApi *alarmApi) BulkStatusUpdate(updateAlarmsFilter *UpdateAlarmsFilter, newStatus Status) *generic.Error {
+	_, err := alarmApi.bulkStatusUpdate(updateAlarmsFilter, newStatus, "BulkStatusUpdate")
+	return err
 }
 
 /*
@@ -186,6 +189,8 @@ func (alarmApi *alarmApi) Delete(alarmFilter *AlarmFilter) *generic.Error {
 	if err != nil {
 		return generic.ClientError(fmt.Sprintf("Error while building query parameters for deletion of alarms: %s", err.Error()), "DeleteAlarms")
 	}
+	// withTotalElements is no filter: it must neither be sent nor protect from deleting all alarms
+	queryParamsValues.Del("withTotalElements")
 	if len(*queryParamsValues) == 0 {
 		return generic.ClientError("No filter set. At least one filter has to be set to avoid accident deletion of all alarms. Use `DeleteAll()` if you really want to remove them all", "DeleteAlarms")
 	}
@@ -238,6 +243,42 @@ func (alarmApi *alarmApi) Find(alarmFilter *AlarmFilter, pageSize int) (*AlarmCo
 	return alarmApi.getCommon(fmt.Sprintf("%s?%s", alarmApi.basePath, queryParamsValues.Encode()))
 }
 
+/*
+Counts the alarms by filter.
+
+See: https://cumulocity.com/guides/reference/alarms/#get-number-of-alarms
+*/
+func (alarmApi *alarmApi) Count(alarmFilter *AlarmFilter) (int, *generic.Error) {
+	queryParamsValues := &url.Values{}
+	if alarmFilter != nil {
+		err := alarmFilter.QueryParams(queryParamsValues)
+		if err != nil {
+			return 0, generic.ClientError(fmt.Sprintf("Error while building query parameters to count alarms: %s", err.Error()), "CountAlarms")
+		}
+		queryParamsValues.Del("withTotalElements")
+	}
+
+	path := fmt.Sprintf("%s/count", alarmApi.basePath)
+	if len(*queryParamsValues) > 0 {
+		path = fmt.Sprintf("%s?%s", path, queryParamsValues.Encode())
+	}
+
+	body, status, err := alarmApi.client.Get(path, generic.AcceptHeader("text/plain"))
+	if err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while counting alarms: %s", err.Error()), "CountAlarms")
+	}
+	if status != http.StatusOK {
+		return 0, generic.CreateErrorFromResponse(body, status)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(string(body)))
+	if err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while parsing the number of alarms: %s", err.Error()), "CountAlarms")
+	}
+
+	return count, nil
+}
+
 func (alarmApi *alarmApi) NextPage(c *AlarmCollection) (*AlarmCollection, *generic.Error) {
 	return alarmApi.getPage(c.Next)
 }
@@ -248,6 +289,21 @@ func (alarmApi *alarmApi) PreviousPage(c *AlarmCollection) (*AlarmCollection, *g
 
 // -- internal
 
+func (alarmApi *alarmApi) update(alarmId string, bytes []byte, operation string) (*Alarm, *generic.Error) {
+	path := fmt.Sprintf("%s/%s", alarmApi.basePath, url.QueryEscape(alarmId))
+	headers := generic.AcceptAndContentTypeHeader(ALARM_TYPE, ALARM_TYPE)
+
+	body, status, err := alarmApi.client.Put(path, bytes, headers)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while updating an alarm: %s", err.Error()), operation)
+	}
+	if status != http.StatusOK {
+		return nil, generic.CreateErrorFromResponse(body, status)
+	}
+
+	return parseAlarmResponse(body)
+}
+
 func parseAlarmResponse(body []byte) (*Alarm, *generic.Error) {
 	var result Alarm
 	if len(body) > 0 {
@@ -305,3 +361,38 @@ func (alarmApi *alarmApi) getCommon(path string) (*AlarmCollection, *generic.Err
 
 	return &result, nil
 }
+
+// Sends the bulk update and returns the response status, which is either 200 or 202.
+func (alarmApi *alarmApi) bulkStatusUpdate(updateAlarmsFilter *UpdateAlarmsFilter, newStatus Status, operation string) (int, *generic.Error) {
+	alarmStatus := UpdateAlarm{Status: newStatus}
+
+	bytes, err := json.Marshal(alarmStatus)
+	if err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while marshalling the update of alarms: %s", err.Error()), operation)
+	}
+
+	queryParamsValues := &url.Values{}
+	err = updateAlarmsFilter.QueryParams(queryParamsValues)
+	if err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while building query parameters for update of alarms: %s", err.Error()), operation)
+	}
+
+	path := fmt.Sprintf("%s?%s", alarmApi.basePath, queryParamsValues.Encode())
+	headers := generic.AcceptHeader(ALARM_TYPE)
+
+	body, status, err := alarmApi.client.Put(path, bytes, headers)
+	if err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while updating alarms: %s", err.Error()), operation)
+	}
+
+	// Since this operations can take a lot of time, request returns after maximum 0.5 sec of processing,
+	// and updating is continued as a background process in the platform.
+	// Therefore following possible response statuses can be interpret as successful:
+	//	200 - if the process has completed, all alarms have been updated
+	//	202 - if process continues in background (maybe )
+	if status != http.StatusOK && status != http.StatusAccepted {
+		return status, generic.CreateErrorFromResponse(body, status)
+	}
+
+	return status, nil
+}
@@ -0,0 +1,81 @@
+package alarm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAlarmApi_Count(t *testing.T) {
+	var capturedUrl string
+	var capturedAccept string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUrl = r.URL.String()
+		capturedAccept = r.Header.Get("Accept")
+		_, _ = w.Write([]byte("42"))
+	}))
+	defer ts.Close()
+
+	api := buildAlarmApi(ts.URL)
+
+	tests := []struct {
+		name        string
+		filter      *AlarmFilter
+		expectedUrl string
+	}{
+		{"NoFilter", nil, "/alarm/alarms/count"},
+		{"EmptyFilter", &AlarmFilter{}, "/alarm/alarms/count"},
+		{"Filter", &AlarmFilter{SourceId: deviceId, Status: []Status{ACTIVE}, WithTotalElements: true}, "/alarm/alarms/count?source=1111111&status=ACTIVE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := api.Count(tt.filter)
+
+			if err != nil {
+				t.Fatalf("Count() got an unexpected error: %s", err.Error())
+			}
+			if count != 42 {
+				t.Errorf("Count() = %d, want 42", count)
+			}
+			if capturedUrl != tt.expectedUrl {
+				t.Errorf("Count() url = %s, want %s", capturedUrl, tt.expectedUrl)
+			}
+			if capturedAccept != "text/plain" {
+				t.Errorf("Count() accept header = %s, want text/plain", capturedAccept)
+			}
+		})
+	}
+}
+
+func TestAlarmApi_Count_InvalidFilter(t *testing.T) {
+	api := buildAlarmApi("test.url")
+
+	_, err := api.Count(&AlarmFilter{WithSourceDevices: true})
+
+	if err == nil {
+		t.Errorf("Count() expected an error for an invalid filter")
+	}
+}
+
+func TestAlarmApi_Count_InvalidResponse(t *testing.T) {
+	ts := buildHttpServer(http.StatusOK, "many")
+	defer ts.Close()
+
+	_, err := buildAlarmApi(ts.URL).Count(nil)
+
+	if err == nil {
+		t.Errorf("Count() expected an error for an invalid response")
+	}
+}
+
+func TestAlarmApi_Count_Error(t *testing.T) {
+	ts := buildHttpServer(http.StatusInternalServerError, "")
+	defer ts.Close()
+
+	_, err := buildAlarmApi(ts.URL).Count(nil)
+
+	if err == nil {
+		t.Errorf("Count() expected an error")
+	}
+}
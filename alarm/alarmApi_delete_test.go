@@ -65,6 +65,17 @@ func TestMeasurementApi_Delete_Many_WithoutFilter(t *testing.T) {
 	}
 }
 
+func TestAlarmApi_Delete_Many_WithTotalElementsOnly(t *testing.T) {
+	// given: the api as system under test
+	api := buildAlarmApi("")
+
+	err := api.Delete(&AlarmFilter{WithTotalElements: true})
+
+	if err == nil || !strings.Contains(err.Message, "No filter set") {
+		t.Errorf("Delete() expected 'No filter set' error. Got: %v", err)
+	}
+}
+
 func TestAlarmApi_Delete_Alarm_NotFound(t *testing.T) {
 	// given: A test server
 	ts := buildHttpServer(http.StatusNotFound, "")
@@ -71,6 +71,31 @@ func TestAlarmApi_FindWithFilter(t *testing.T) {
 			AlarmFilter{WithSourceDevices: true, SourceId: "123"},
 			"pageSize=1&source=123&withSourceDevices=true",
 		},
+		{
+			"CreationTime",
+			AlarmFilter{CreatedFrom: &dateFrom, CreatedTo: &dateTo},
+			"createdFrom=2020-06-01T01%3A00%3A00Z&createdTo=2020-06-30T01%3A00%3A00Z&pageSize=1",
+		},
+		{
+			"LastUpdated",
+			AlarmFilter{LastUpdatedFrom: &dateFrom, LastUpdatedTo: &dateTo},
+			"lastUpdatedFrom=2020-06-01T01%3A00%3A00Z&lastUpdatedTo=2020-06-30T01%3A00%3A00Z&pageSize=1",
+		},
+		{
+			"Severities",
+			AlarmFilter{Severity: CRITICAL, Severities: []Severity{CRITICAL, MAJOR}},
+			"pageSize=1&severity=CRITICAL%2CMAJOR",
+		},
+		{
+			"Types",
+			AlarmFilter{Types: []string{"testAlarm", "otherAlarm"}},
+			"pageSize=1&type=testAlarm%2CotherAlarm",
+		},
+		{
+			"WithTotalElements",
+			AlarmFilter{WithTotalElements: true},
+			"pageSize=1&withTotalElements=true",
+		},
 		{
 			"All",
 			AlarmFilter{
@@ -127,6 +152,21 @@ func TestAlarmApi_Find_WithInvalidFilter(t *testing.T) {
 			AlarmFilter{WithSourceDevices: true},
 			"when 'WithSourceDevices' parameter is defined also SourceID must be set",
 		},
+		{
+			"DateWindow",
+			AlarmFilter{DateFrom: &dateTo, DateTo: &dateFrom},
+			"'dateFrom' must not be after 'dateTo'",
+		},
+		{
+			"CreationTimeWindow",
+			AlarmFilter{CreatedFrom: &dateTo, CreatedTo: &dateFrom},
+			"'createdFrom' must not be after 'createdTo'",
+		},
+		{
+			"LastUpdatedWindow",
+			AlarmFilter{LastUpdatedFrom: &dateTo, LastUpdatedTo: &dateFrom},
+			"'lastUpdatedFrom' must not be after 'lastUpdatedTo'",
+		},
 	}
 
 	api := buildAlarmApi("test.url")
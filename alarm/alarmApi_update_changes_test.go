@@ -0,0 +1,93 @@
+package alarm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAlarmApi_UpdateChanges_SendsOnlyChanges(t *testing.T) {
+	// given: A test server
+	ts := updateAlarmHttpServer(200)
+	defer ts.Close()
+
+	// and: the api as system under test
+	api := buildAlarmApi(ts.URL)
+
+	// and: An alarm with a changed status, a new and a removed fragment
+	before := &Alarm{Id: alarmId, Type: "TestAlarm", Text: "Hello", Status: ACTIVE, Severity: MAJOR,
+		AdditionalFields: map[string]interface{}{"custom1": "foo", "custom2": "bar"}}
+	after := &Alarm{Id: alarmId, Type: "TestAlarm", Text: "Hello", Status: CLEARED, Severity: MAJOR,
+		AdditionalFields: map[string]interface{}{"custom1": "foo", "custom3": "baz"}}
+
+	_, err := api.UpdateChanges(before, after)
+
+	if err != nil {
+		t.Fatalf("UpdateChanges() got an unexpected error: %s", err.Error())
+	}
+
+	if urlCapture != "/alarm/alarms/"+alarmId {
+		t.Errorf("UpdateChanges() url = %s, want /alarm/alarms/%s", urlCapture, alarmId)
+	}
+
+	want := `{"custom2":null,"custom3":"baz","status":"CLEARED"}`
+	if string(*bodyCapture) != want {
+		t.Errorf("UpdateChanges() body = %s, want %s", *bodyCapture, want)
+	}
+}
+
+func TestAlarmApi_UpdateChanges_WithoutReadOnlyFields(t *testing.T) {
+	ts := updateAlarmHttpServer(200)
+	defer ts.Close()
+
+	api := buildAlarmApi(ts.URL)
+
+	// and: An alarm with changed read-only fields and a changed text
+	before := &Alarm{Id: alarmId, Self: "http://c8y/alarm/alarms/1", Type: "TestAlarm", Text: "Hello", Source: Source{Id: "1"}, Count: 1}
+	after := &Alarm{Id: "4711", Type: "OtherAlarm", Text: "World", Source: Source{Id: "2"}, Count: 2}
+
+	_, err := api.UpdateChanges(before, after)
+
+	if err != nil {
+		t.Fatalf("UpdateChanges() got an unexpected error: %s", err.Error())
+	}
+	want := `{"text":"World"}`
+	if string(*bodyCapture) != want {
+		t.Errorf("UpdateChanges() body = %s, want %s", *bodyCapture, want)
+	}
+}
+
+func TestAlarmApi_UpdateChanges_NoChanges(t *testing.T) {
+	// given: A test server, which must not be called
+	called := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer ts.Close()
+
+	api := buildAlarmApi(ts.URL)
+	before := &Alarm{Id: alarmId, Text: "Hello"}
+	after := &Alarm{Id: alarmId, Text: "Hello"}
+
+	alarm, err := api.UpdateChanges(before, after)
+
+	if err != nil {
+		t.Fatalf("UpdateChanges() got an unexpected error: %s", err.Error())
+	}
+	if called {
+		t.Errorf("UpdateChanges() sent a request without changes")
+	}
+	if alarm != before {
+		t.Errorf("UpdateChanges() alarm = %v, want %v", alarm, before)
+	}
+}
+
+func TestAlarmApi_UpdateChanges_MissingId(t *testing.T) {
+	api := buildAlarmApi("http://localhost")
+
+	_, err := api.UpdateChanges(&Alarm{}, &Alarm{Text: "Hello"})
+
+	if err == nil {
+		t.Errorf("UpdateChanges() expected error for alarm without id")
+	}
+}
@@ -84,3 +84,59 @@ func TestAlarmApi_BulkStatusUpdate_Alarm_BadRequest(t *testing.T) {
 		return
 	}
 }
+
+func TestAlarmApi_BulkStatusUpdate_ExtendedFilter(t *testing.T) {
+	// given: A test server
+	ts := updateAlarmHttpServer(200)
+	defer ts.Close()
+
+	// and: the api as system under test
+	api := buildAlarmApi(ts.URL)
+
+	filter := &UpdateAlarmsFilter{
+		Status:            ACTIVE,
+		SourceId:          "123",
+		WithSourceAssets:  true,
+		WithSourceDevices: true,
+		Severities:        []Severity{MAJOR, MINOR},
+		CreatedFrom:       &dateFrom,
+		LastUpdatedTo:     &dateTo,
+		Type:              "testAlarm",
+		Types:             []string{"otherAlarm"},
+	}
+	expected := "/alarm/alarms?createdFrom=2020-06-29T10%3A11%3A12Z&lastUpdatedTo=2020-06-30T13%3A14%3A15Z&severity=MAJOR%2CMINOR&source=123&status=ACTIVE&type=testAlarm%2CotherAlarm&withSourceAssets=true&withSourceDevices=true"
+
+	err := api.BulkStatusUpdate(filter, newAlarmStatus)
+
+	if err != nil {
+		t.Fatalf("BulkStatusUpdate() got an unexpected error: %s", err.Error())
+	}
+
+	if strings.Contains(urlCapture, expected) == false {
+		t.Errorf("BulkStatusUpdate() url = [%s], expected [%s]", urlCapture, expected)
+	}
+}
+
+func TestAlarmApi_BulkStatusUpdate_InvalidFilter(t *testing.T) {
+	tests := []struct {
+		name          string
+		filter        UpdateAlarmsFilter
+		expectedError string
+	}{
+		{"WithSourceAssets", UpdateAlarmsFilter{WithSourceAssets: true}, "when 'WithSourceAssets' parameter is defined also SourceID must be set"},
+		{"WithSourceDevices", UpdateAlarmsFilter{WithSourceDevices: true}, "when 'WithSourceDevices' parameter is defined also SourceID must be set"},
+		{"CreationTimeWindow", UpdateAlarmsFilter{CreatedFrom: &dateTo, CreatedTo: &dateFrom}, "'createdFrom' must not be after 'createdTo'"},
+	}
+
+	api := buildAlarmApi("test.url")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := api.BulkStatusUpdate(&tt.filter, newAlarmStatus)
+
+			if err == nil || !strings.Contains(err.Message, tt.expectedError) {
+				t.Errorf("BulkStatusUpdate() error = [%v], expected: [%v]", err, tt.expectedError)
+			}
+		})
+	}
+}
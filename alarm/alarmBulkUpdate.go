@@ -0,0 +1,194 @@
+package alarm
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultBulkUpdateTimeout         = 5 * time.Minute
+	defaultBulkUpdatePollInterval    = time.Second
+	defaultBulkUpdateMaxPollInterval = 30 * time.Second
+	defaultBulkUpdateBackoffFactor   = 2.0
+)
+
+/*
+BulkUpdateOptions control how `BulkStatusUpdateAndWait` waits for an update, which cumulocity continues in the background.
+Zero values are replaced by the defaults.
+*/
+type BulkUpdateOptions struct {
+	Timeout         time.Duration                   // Maximum time to wait for the update. Default: 5 minutes
+	PollInterval    time.Duration                   // Time to wait before the first poll. Default: 1 second
+	MaxPollInterval time.Duration                   // Upper bound of the time between two polls. Default: 30 seconds
+	BackoffFactor   float64                         // The poll interval is multiplied by this factor after each poll. Default: 2
+	Progress        func(summary BulkUpdateSummary) // Called after each poll with the current state of the update
+}
+
+/*
+BulkUpdateSummary describes the state of a bulk status update.
+*/
+type BulkUpdateSummary struct {
+	Matched      int           // Alarms matching the filter in their old status, before the update was sent
+	Transitioned int           // Alarms, which are not in their old status anymore
+	Remaining    int           // Alarms, which are still in their old status
+	Polls        int           // Number of polls until now
+	Elapsed      time.Duration // Time since the update was started
+	Completed    bool          // True, when no alarm is left in its old status
+}
+
+func (alarmApi *alarmApi) BulkStatusUpdateAndWait(updateAlarmsFilter *UpdateAlarmsFilter, newStatus Status, options *BulkUpdateOptions) (*BulkUpdateSummary, *generic.Error) {
+	if updateAlarmsFilter == nil {
+		return nil, generic.ClientError("No filter set. A filter is needed to track the update of alarms.", "BulkStatusUpdateAndWait")
+	}
+	opts := bulkUpdateOptionsWithDefaults(options)
+	start := time.Now()
+
+	pending := pendingAlarmsFilter(updateAlarmsFilter, newStatus)
+	summary := BulkUpdateSummary{}
+	if pending != nil {
+		matched, err := alarmApi.Count(pending)
+		if err != nil {
+			return nil, err
+		}
+		summary.Matched = matched
+		summary.Remaining = matched
+	}
+
+	status, err := alarmApi.bulkStatusUpdate(updateAlarmsFilter, newStatus, "BulkStatusUpdateAndWait")
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusAccepted || pending == nil {
+		summary.complete(start)
+		return &summary, nil
+	}
+
+	deadline := start.Add(opts.Timeout)
+	interval := opts.PollInterval
+	for {
+		wait := interval
+		if remaining := time.Until(deadline); remaining < wait {
+			wait = remaining
+		}
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+
+		remaining, err := alarmApi.Count(pending)
+		if err != nil {
+			return nil, err
+		}
+
+		summary.Polls++
+		summary.Remaining = remaining
+		summary.Transitioned = transitioned(summary.Matched, remaining)
+		summary.Elapsed = time.Since(start)
+		if remaining == 0 {
+			summary.Completed = true
+		}
+		if opts.Progress != nil {
+			opts.Progress(summary)
+		}
+
+		if summary.Completed {
+			return &summary, nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return &summary, generic.ClientError(
+				fmt.Sprintf("Timeout after %v while waiting for the update of alarms: %d of %d alarms are still in their old status",
+					opts.Timeout, remaining, summary.Matched), "BulkStatusUpdateAndWait")
+		}
+
+		interval = time.Duration(float64(interval) * opts.BackoffFactor)
+		if interval > opts.MaxPollInterval {
+			interval = opts.MaxPollInterval
+		}
+	}
+}
+
+// -- internal
+
+func bulkUpdateOptionsWithDefaults(options *BulkUpdateOptions) BulkUpdateOptions {
+	opts := BulkUpdateOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultBulkUpdateTimeout
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultBulkUpdatePollInterval
+	}
+	if opts.MaxPollInterval <= 0 {
+		opts.MaxPollInterval = defaultBulkUpdateMaxPollInterval
+	}
+	if opts.MaxPollInterval < opts.PollInterval {
+		opts.MaxPollInterval = opts.PollInterval
+	}
+	if opts.BackoffFactor < 1 {
+		opts.BackoffFactor = defaultBulkUpdateBackoffFactor
+	}
+
+	return opts
+}
+
+/*
+Returns the filter for alarms, which match the update filter and are still in their old status.
+The old statuses are the status of the filter or the statuses selected by `Resolved` - without the new status.
+Returns nil, if no alarm can be in an old status.
+*/
+func pendingAlarmsFilter(updateAlarmsFilter *UpdateAlarmsFilter, newStatus Status) *AlarmFilter {
+	oldStatuses := resolvedStatuses(updateAlarmsFilter.Resolved)
+	if len(updateAlarmsFilter.Status) > 0 {
+		oldStatuses = []Status{updateAlarmsFilter.Status}
+	} else if oldStatuses == nil {
+		oldStatuses = allStatuses
+	}
+
+	var pendingStatuses []Status
+	for _, status := range oldStatuses {
+		if status != newStatus {
+			pendingStatuses = append(pendingStatuses, status)
+		}
+	}
+	if len(pendingStatuses) == 0 {
+		return nil
+	}
+
+	// Resolved is not set, as it would override the statuses
+	return &AlarmFilter{
+		Status:            pendingStatuses,
+		SourceId:          updateAlarmsFilter.SourceId,
+		WithSourceAssets:  updateAlarmsFilter.WithSourceAssets,
+		WithSourceDevices: updateAlarmsFilter.WithSourceDevices,
+		Severity:          updateAlarmsFilter.Severity,
+		Severities:        updateAlarmsFilter.Severities,
+		DateFrom:          updateAlarmsFilter.DateFrom,
+		DateTo:            updateAlarmsFilter.DateTo,
+		CreatedFrom:       updateAlarmsFilter.CreatedFrom,
+		CreatedTo:         updateAlarmsFilter.CreatedTo,
+		LastUpdatedFrom:   updateAlarmsFilter.LastUpdatedFrom,
+		LastUpdatedTo:     updateAlarmsFilter.LastUpdatedTo,
+		Type:              updateAlarmsFilter.Type,
+		Types:             updateAlarmsFilter.Types,
+	}
+}
+
+func transitioned(matched int, remaining int) int {
+	if remaining >= matched {
+		return 0
+	}
+	return matched - remaining
+}
+
+func (summary *BulkUpdateSummary) complete(start time.Time) {
+	summary.Transitioned = summary.Matched
+	summary.Remaining = 0
+	summary.Elapsed = time.Since(start)
+	summary.Completed = true
+}
@@ -0,0 +1,144 @@
+package alarm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A test server, which answers the bulk update with the given status and the following searches with the
+// given counts - one after another. The last count is repeated.
+func buildBulkUpdateHttpServer(updateStatus int, counts []int, queries *[]string) *httptest.Server {
+	var mutex sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if r.Method == http.MethodPut {
+			w.WriteHeader(updateStatus)
+			_, _ = w.Write([]byte(alarm))
+			return
+		}
+
+		*queries = append(*queries, r.URL.String())
+		count := counts[0]
+		if len(counts) > 1 {
+			counts = counts[1:]
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf("%d", count)))
+	}))
+}
+
+var fastPolling = &BulkUpdateOptions{PollInterval: time.Millisecond, MaxPollInterval: 2 * time.Millisecond, Timeout: time.Second}
+
+func TestAlarmApi_BulkStatusUpdateAndWait_PollsUntilCompleted(t *testing.T) {
+	var queries []string
+	ts := buildBulkUpdateHttpServer(http.StatusAccepted, []int{10, 7, 2, 0}, &queries)
+	defer ts.Close()
+
+	api := buildAlarmApi(ts.URL)
+
+	var progress []BulkUpdateSummary
+	options := *fastPolling
+	options.Progress = func(summary BulkUpdateSummary) {
+		progress = append(progress, summary)
+	}
+
+	summary, err := api.BulkStatusUpdateAndWait(&UpdateAlarmsFilter{SourceId: "123", Status: ACTIVE}, CLEARED, &options)
+
+	if err != nil {
+		t.Fatalf("BulkStatusUpdateAndWait() got an unexpected error: %s", err.Error())
+	}
+	if summary.Matched != 10 || summary.Transitioned != 10 || summary.Remaining != 0 || !summary.Completed || summary.Polls != 3 {
+		t.Errorf("BulkStatusUpdateAndWait() summary = %+v", *summary)
+	}
+
+	var remaining []int
+	for _, p := range progress {
+		remaining = append(remaining, p.Remaining)
+	}
+	if fmt.Sprint(remaining) != "[7 2 0]" {
+		t.Errorf("BulkStatusUpdateAndWait() progress remaining = %v, want [7 2 0]", remaining)
+	}
+
+	want := "/alarm/alarms/count?source=123&status=ACTIVE"
+	for _, query := range queries {
+		if query != want {
+			t.Errorf("BulkStatusUpdateAndWait() query = %s, want %s", query, want)
+		}
+	}
+}
+
+func TestAlarmApi_BulkStatusUpdateAndWait_CompletedImmediately(t *testing.T) {
+	var queries []string
+	ts := buildBulkUpdateHttpServer(http.StatusOK, []int{4}, &queries)
+	defer ts.Close()
+
+	api := buildAlarmApi(ts.URL)
+
+	summary, err := api.BulkStatusUpdateAndWait(&UpdateAlarmsFilter{SourceId: "123", Resolved: "false"}, CLEARED, fastPolling)
+
+	if err != nil {
+		t.Fatalf("BulkStatusUpdateAndWait() got an unexpected error: %s", err.Error())
+	}
+	if summary.Matched != 4 || summary.Transitioned != 4 || !summary.Completed || summary.Polls != 0 {
+		t.Errorf("BulkStatusUpdateAndWait() summary = %+v", *summary)
+	}
+	if len(queries) != 1 || !strings.Contains(queries[0], "status=ACTIVE%2CACKNOWLEDGED") || strings.Contains(queries[0], "resolved") {
+		t.Errorf("BulkStatusUpdateAndWait() queries = %v, want one count of active and acknowledged alarms", queries)
+	}
+}
+
+func TestAlarmApi_BulkStatusUpdateAndWait_Timeout(t *testing.T) {
+	var queries []string
+	ts := buildBulkUpdateHttpServer(http.StatusAccepted, []int{5, 3}, &queries)
+	defer ts.Close()
+
+	api := buildAlarmApi(ts.URL)
+
+	options := *fastPolling
+	options.Timeout = 20 * time.Millisecond
+
+	summary, err := api.BulkStatusUpdateAndWait(&UpdateAlarmsFilter{Status: ACTIVE}, CLEARED, &options)
+
+	if err == nil || !strings.Contains(err.Message, "3 of 5 alarms are still in their old status") {
+		t.Fatalf("BulkStatusUpdateAndWait() expected timeout error, got %v", err)
+	}
+	if summary == nil || summary.Transitioned != 2 || summary.Completed {
+		t.Errorf("BulkStatusUpdateAndWait() summary = %+v", summary)
+	}
+}
+
+func TestAlarmApi_BulkStatusUpdateAndWait_NothingToTrack(t *testing.T) {
+	var queries []string
+	ts := buildBulkUpdateHttpServer(http.StatusAccepted, []int{1}, &queries)
+	defer ts.Close()
+
+	api := buildAlarmApi(ts.URL)
+
+	summary, err := api.BulkStatusUpdateAndWait(&UpdateAlarmsFilter{Status: CLEARED}, CLEARED, fastPolling)
+
+	if err != nil {
+		t.Fatalf("BulkStatusUpdateAndWait() got an unexpected error: %s", err.Error())
+	}
+	if len(queries) != 0 || !summary.Completed || summary.Matched != 0 {
+		t.Errorf("BulkStatusUpdateAndWait() summary = %+v, queries = %v", *summary, queries)
+	}
+}
+
+func TestAlarmApi_BulkStatusUpdateAndWait_Error(t *testing.T) {
+	ts := buildHttpServer(http.StatusInternalServerError, "")
+	defer ts.Close()
+
+	api := buildAlarmApi(ts.URL)
+
+	summary, err := api.BulkStatusUpdateAndWait(&UpdateAlarmsFilter{Status: ACTIVE}, CLEARED, fastPolling)
+
+	if err == nil || summary != nil {
+		t.Errorf("BulkStatusUpdateAndWait() expected error without summary, got %v, %v", summary, err)
+	}
+}
@@ -0,0 +1,272 @@
+package alarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"regexp"
+	"time"
+)
+
+const (
+	escalationPageSize = 100
+
+	// The fragment of an escalated alarm, which records its last escalation. See `EscalationAudit`.
+	ESCALATION_FRAGMENT = "gomulocity_Escalation"
+)
+
+// Matches the audit text of an earlier escalation at the end of an alarm text
+var auditTextPattern = regexp.MustCompile(` \[escalated from [^\]]* to [^\]]* by rule '.*'(: [^\]]*)?\]$`)
+
+/*
+EscalationRule describes when alarms of a severity are escalated to another severity, e.g.
+WARNING alarms, which are not acknowledged within 30 minutes, become MAJOR alarms.
+When both conditions are set, an alarm is escalated only if it fulfills both of them.
+*/
+type EscalationRule struct {
+	Name              string        // The name of the rule, used in the audit text
+	From              Severity      // Alarms with this severity are checked
+	To                Severity      // The new severity of escalated alarms
+	UnacknowledgedFor time.Duration // Condition: the alarm is ACTIVE at least for this duration since its first occurrence or last escalation
+	CountExceeds      int           // Condition: the alarm count is greater than this value
+	Filter            *AlarmFilter  // Optional filter to restrict the rule, e.g. to a source or type. Severity and status are set by the rule
+}
+
+/*
+Escalation describes an alarm escalated by a rule.
+*/
+type Escalation struct {
+	Rule  string
+	From  Severity
+	To    Severity
+	Alarm *Alarm // The updated alarm
+}
+
+/*
+EscalationAudit is the content of the `ESCALATION_FRAGMENT` of an escalated alarm.
+*/
+type EscalationAudit struct {
+	Rule    string    `json:"rule"`
+	From    Severity  `json:"from"`
+	To      Severity  `json:"to"`
+	Reasons string    `json:"reasons"`
+	Time    time.Time `json:"time"`
+}
+
+/*
+Escalator evaluates escalation rules against the alarms found in cumulocity and updates the severity of
+matching alarms. The last escalation is recorded in the `ESCALATION_FRAGMENT` and noted at the end of the alarm text.
+*/
+type Escalator struct {
+	api   AlarmApi
+	rules []EscalationRule
+	now   func() time.Time
+}
+
+// Creates a new escalator. Returns an error, if a rule is invalid.
+// api - The alarm api used to find and update alarms.
+// rules - The rules, evaluated in the given order.
+func NewEscalator(api AlarmApi, rules ...EscalationRule) (*Escalator, error) {
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid escalation rule %d (%s): %s", i, rule.Name, err.Error()))
+		}
+	}
+
+	return &Escalator{api: api, rules: rules, now: time.Now}, nil
+}
+
+/*
+Evaluates all rules once and escalates the matching alarms. An alarm is escalated at most once per evaluation.
+Returns the escalations - also the ones done before an error occurred.
+*/
+func (escalator *Escalator) Evaluate() ([]Escalation, *generic.Error) {
+	var escalations []Escalation
+	escalated := map[string]bool{}
+
+	for _, rule := range escalator.rules {
+		candidates, err := escalator.findCandidates(rule)
+		if err != nil {
+			return escalations, err
+		}
+
+		for i := range candidates {
+			alarm := &candidates[i]
+			if escalated[alarm.Id] || !rule.matches(alarm, escalator.now()) {
+				continue
+			}
+
+			updated, err := escalator.api.Update(alarm.Id, &UpdateAlarm{
+				Severity:         rule.To,
+				Text:             rule.auditText(alarm),
+				AdditionalFields: map[string]interface{}{ESCALATION_FRAGMENT: rule.audit(escalator.now())},
+			})
+			if err != nil {
+				return escalations, err
+			}
+
+			escalated[alarm.Id] = true
+			escalations = append(escalations, Escalation{Rule: rule.Name, From: rule.From, To: rule.To, Alarm: updated})
+		}
+	}
+
+	return escalations, nil
+}
+
+/*
+Evaluates the rules immediately and then periodically, until the context is done.
+The result of each evaluation is passed to `report`, if it is not nil.
+A non-positive interval is reported as error without evaluating the rules.
+*/
+func (escalator *Escalator) Run(ctx context.Context, interval time.Duration, report func(escalations []Escalation, err *generic.Error)) {
+	if interval <= 0 {
+		if report != nil {
+			report(nil, generic.ClientError(fmt.Sprintf("The interval must be positive, but was %v", interval), "RunEscalator"))
+		}
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		escalations, err := escalator.Evaluate()
+		if report != nil {
+			report(escalations, err)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// -- internal
+
+func (escalator *Escalator) findCandidates(rule EscalationRule) ([]Alarm, *generic.Error) {
+	filter := AlarmFilter{}
+	if rule.Filter != nil {
+		filter = *rule.Filter
+	}
+	filter.Severity = rule.From
+	filter.Severities = nil
+	filter.Resolved = ""
+	filter.WithTotalElements = false
+	if rule.UnacknowledgedFor > 0 {
+		filter.Status = []Status{ACTIVE}
+	} else {
+		filter.Status = []Status{ACTIVE, ACKNOWLEDGED}
+	}
+
+	var candidates []Alarm
+	collection, err := escalator.api.Find(&filter, escalationPageSize)
+	for ; collection != nil && err == nil; collection, err = escalator.api.NextPage(collection) {
+		candidates = append(candidates, collection.Alarms...)
+		if len(collection.Alarms) < escalationPageSize {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+func (rule EscalationRule) validate() error {
+	if len(rule.From) == 0 || len(rule.To) == 0 {
+		return errors.New("the severities 'From' and 'To' must be set")
+	}
+	if rule.From == rule.To {
+		return errors.New("the severities 'From' and 'To' must differ")
+	}
+	if rule.UnacknowledgedFor <= 0 && rule.CountExceeds <= 0 {
+		return errors.New("at least one condition 'UnacknowledgedFor' or 'CountExceeds' must be set")
+	}
+	return nil
+}
+
+// The severity and status are checked again, as they may have changed since the alarm was found.
+func (rule EscalationRule) matches(alarm *Alarm, now time.Time) bool {
+	if alarm.Severity != rule.From || alarm.Status == CLEARED {
+		return false
+	}
+
+	if rule.UnacknowledgedFor > 0 {
+		since := lastSeverityChange(alarm)
+		if alarm.Status != ACTIVE || since == nil || now.Sub(*since) < rule.UnacknowledgedFor {
+			return false
+		}
+	}
+
+	if rule.CountExceeds > 0 && alarm.Count <= rule.CountExceeds {
+		return false
+	}
+
+	return true
+}
+
+func (rule EscalationRule) reasons() string {
+	var reasons string
+	if rule.UnacknowledgedFor > 0 {
+		reasons = fmt.Sprintf("unacknowledged for %v", rule.UnacknowledgedFor)
+	}
+	if rule.CountExceeds > 0 {
+		if len(reasons) > 0 {
+			reasons += ", "
+		}
+		reasons += fmt.Sprintf("count exceeds %d", rule.CountExceeds)
+	}
+	return reasons
+}
+
+// Notes the escalation at the end of the alarm text. The note of an earlier escalation is replaced.
+func (rule EscalationRule) auditText(alarm *Alarm) string {
+	text := auditTextPattern.ReplaceAllString(alarm.Text, "")
+	return fmt.Sprintf("%s [escalated from %s to %s by rule '%s': %s]", text, rule.From, rule.To, rule.Name, rule.reasons())
+}
+
+func (rule EscalationRule) audit(now time.Time) EscalationAudit {
+	return EscalationAudit{Rule: rule.Name, From: rule.From, To: rule.To, Reasons: rule.reasons(), Time: now}
+}
+
+/*
+Returns the time of the last escalation of an alarm, so chained rules do not escalate an alarm through
+several severities at once. Falls back to the first occurrence.
+*/
+func lastSeverityChange(alarm *Alarm) *time.Time {
+	since := alarmOccurrence(alarm)
+
+	value, ok := alarm.AdditionalFields[ESCALATION_FRAGMENT]
+	if !ok {
+		return since
+	}
+	var audit EscalationAudit
+	if bytes, err := json.Marshal(value); err != nil || json.Unmarshal(bytes, &audit) != nil || audit.Time.IsZero() {
+		return since
+	}
+	if since == nil || audit.Time.After(*since) {
+		return &audit.Time
+	}
+	return since
+}
+
+// Returns the first occurrence of an alarm. Falls back to the alarm time and the creation time.
+func alarmOccurrence(alarm *Alarm) *time.Time {
+	switch {
+	case alarm.FirstOccurrenceTime != nil:
+		return alarm.FirstOccurrenceTime
+	case alarm.Time != nil:
+		return alarm.Time
+	default:
+		return alarm.CreationTime
+	}
+}
@@ -0,0 +1,219 @@
+package alarm
+
+import (
+	"context"
+	"github.com/tarent/gomulocity/generic"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var escalationNow, _ = time.Parse(time.RFC3339, "2020-06-30T12:00:00Z")
+
+func escalationAlarm(id string, severity Severity, status Status, age time.Duration, count int) Alarm {
+	firstOccurrence := escalationNow.Add(-age)
+	return Alarm{Id: id, Type: "escalation-Alarm", Text: "Too hot", Source: Source{Id: deviceId}, Severity: severity,
+		Status: status, Count: count, FirstOccurrenceTime: &firstOccurrence}
+}
+
+// A test server keeping the given alarms. Searches are filtered by severity and status, updates change the alarms.
+func buildEscalationHttpServer(alarms map[string]*Alarm, updates *[]string) *httptest.Server {
+	var mutex sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if r.Method == http.MethodPut {
+			body, _ := ioutil.ReadAll(r.Body)
+			*updates = append(*updates, string(body))
+
+			var update UpdateAlarm
+			_ = generic.ObjectFromJson(body, &update)
+			alarm := alarms[strings.TrimPrefix(r.URL.Path, "/alarm/alarms/")]
+			alarm.Severity = update.Severity
+			alarm.Text = update.Text
+			if alarm.AdditionalFields == nil {
+				alarm.AdditionalFields = map[string]interface{}{}
+			}
+			for name, value := range update.AdditionalFields {
+				alarm.AdditionalFields[name] = value
+			}
+
+			response, _ := generic.JsonFromObject(alarm)
+			_, _ = w.Write(response)
+			return
+		}
+
+		query := r.URL.Query()
+		found := []Alarm{}
+		for _, alarm := range alarms {
+			if string(alarm.Severity) == query.Get("severity") && strings.Contains(query.Get("status"), string(alarm.Status)) {
+				found = append(found, *alarm)
+			}
+		}
+		response, _ := generic.JsonFromObject(&AlarmCollection{Alarms: found})
+		_, _ = w.Write(response)
+	}))
+}
+
+func TestEscalator_Evaluate(t *testing.T) {
+	alarms := map[string]*Alarm{}
+	for _, alarm := range []Alarm{
+		escalationAlarm("old-warning", WARNING, ACTIVE, time.Hour, 1),
+		escalationAlarm("new-warning", WARNING, ACTIVE, time.Minute, 1),
+		escalationAlarm("acknowledged-warning", WARNING, ACKNOWLEDGED, time.Hour, 1),
+		escalationAlarm("frequent-major", MAJOR, ACKNOWLEDGED, time.Minute, 11),
+		escalationAlarm("rare-major", MAJOR, ACTIVE, time.Minute, 10),
+	} {
+		alarm := alarm
+		alarms[alarm.Id] = &alarm
+	}
+
+	var updates []string
+	ts := buildEscalationHttpServer(alarms, &updates)
+	defer ts.Close()
+
+	escalator, err := NewEscalator(buildAlarmApi(ts.URL),
+		EscalationRule{Name: "unacknowledged", From: WARNING, To: MAJOR, UnacknowledgedFor: 30 * time.Minute},
+		EscalationRule{Name: "frequent", From: MAJOR, To: CRITICAL, CountExceeds: 10},
+	)
+	if err != nil {
+		t.Fatalf("NewEscalator() got an unexpected error: %s", err.Error())
+	}
+	escalator.now = func() time.Time { return escalationNow }
+
+	escalations, genErr := escalator.Evaluate()
+
+	if genErr != nil {
+		t.Fatalf("Evaluate() got an unexpected error: %s", genErr.Error())
+	}
+
+	// then: The old warning is escalated once - and not again by the second rule within the same evaluation
+	if len(escalations) != 2 || escalations[0].Alarm.Id != "old-warning" || escalations[1].Alarm.Id != "frequent-major" {
+		t.Fatalf("Evaluate() escalations = %+v", escalations)
+	}
+	if alarms["old-warning"].Severity != MAJOR || alarms["frequent-major"].Severity != CRITICAL {
+		t.Errorf("Evaluate() severities = %s, %s", alarms["old-warning"].Severity, alarms["frequent-major"].Severity)
+	}
+	for _, id := range []string{"new-warning", "acknowledged-warning", "rare-major"} {
+		if alarms[id].Text != "Too hot" {
+			t.Errorf("Evaluate() alarm %s was updated: %+v", id, alarms[id])
+		}
+	}
+
+	wantText := "Too hot [escalated from WARNING to MAJOR by rule 'unacknowledged': unacknowledged for 30m0s]"
+	if escalations[0].Alarm.Text != wantText {
+		t.Errorf("Evaluate() audit text = %s, want %s", escalations[0].Alarm.Text, wantText)
+	}
+	if len(updates) != 2 || !strings.Contains(updates[0], `"severity":"MAJOR"`) || !strings.Contains(updates[0], `"gomulocity_Escalation":{`) {
+		t.Errorf("Evaluate() updates = %v", updates)
+	}
+
+	// when: The rules are evaluated again
+	escalations, _ = escalator.Evaluate()
+
+	// then: Nothing is escalated, as the escalated warning does not exceed the count of the second rule
+	if len(escalations) != 0 {
+		t.Errorf("Evaluate() second escalations = %+v, want none", escalations)
+	}
+}
+
+func TestEscalator_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule EscalationRule
+	}{
+		{"NoSeverity", EscalationRule{To: MAJOR, CountExceeds: 1}},
+		{"SameSeverity", EscalationRule{From: MAJOR, To: MAJOR, CountExceeds: 1}},
+		{"NoCondition", EscalationRule{From: WARNING, To: MAJOR}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEscalator(buildAlarmApi("test.url"), tt.rule)
+			if err == nil {
+				t.Errorf("NewEscalator() expected an error")
+			}
+		})
+	}
+}
+
+func TestEscalator_Run(t *testing.T) {
+	var updates []string
+	ts := buildEscalationHttpServer(map[string]*Alarm{}, &updates)
+	defer ts.Close()
+
+	escalator, _ := NewEscalator(buildAlarmApi(ts.URL), EscalationRule{Name: "frequent", From: MAJOR, To: CRITICAL, CountExceeds: 10})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	evaluations := 0
+	escalator.Run(ctx, time.Millisecond, func(escalations []Escalation, err *generic.Error) {
+		if err != nil {
+			t.Errorf("Run() got an unexpected error: %s", err.Error())
+		}
+		evaluations++
+		if evaluations == 3 {
+			cancel()
+		}
+	})
+
+	if evaluations != 3 {
+		t.Errorf("Run() evaluations = %d, want 3", evaluations)
+	}
+}
+
+func TestEscalator_ChainedRules(t *testing.T) {
+	alarm := escalationAlarm("old-minor", MINOR, ACTIVE, 2*time.Hour, 1)
+	alarms := map[string]*Alarm{alarm.Id: &alarm}
+
+	var updates []string
+	ts := buildEscalationHttpServer(alarms, &updates)
+	defer ts.Close()
+
+	escalator, _ := NewEscalator(buildAlarmApi(ts.URL),
+		EscalationRule{Name: "minor", From: MINOR, To: MAJOR, UnacknowledgedFor: 30 * time.Minute},
+		EscalationRule{Name: "major", From: MAJOR, To: CRITICAL, UnacknowledgedFor: 30 * time.Minute},
+	)
+	now := escalationNow
+	escalator.now = func() time.Time { return now }
+
+	// when: The rules are evaluated twice within the duration of the second rule
+	_, _ = escalator.Evaluate()
+	now = now.Add(10 * time.Minute)
+	escalations, _ := escalator.Evaluate()
+
+	// then: The alarm is not escalated again, as its age is measured from the first escalation
+	if len(escalations) != 0 || alarms["old-minor"].Severity != MAJOR {
+		t.Fatalf("Evaluate() escalations = %+v, severity = %s", escalations, alarms["old-minor"].Severity)
+	}
+
+	// when: The duration of the second rule is over
+	now = now.Add(20 * time.Minute)
+	escalations, _ = escalator.Evaluate()
+
+	// then: The alarm is escalated with a single audit text
+	if len(escalations) != 1 || alarms["old-minor"].Severity != CRITICAL {
+		t.Fatalf("Evaluate() escalations = %+v, severity = %s", escalations, alarms["old-minor"].Severity)
+	}
+	wantText := "Too hot [escalated from MAJOR to CRITICAL by rule 'major': unacknowledged for 30m0s]"
+	if alarms["old-minor"].Text != wantText {
+		t.Errorf("Evaluate() audit text = %s, want %s", alarms["old-minor"].Text, wantText)
+	}
+}
+
+func TestEscalator_Run_InvalidInterval(t *testing.T) {
+	escalator, _ := NewEscalator(buildAlarmApi("test.url"), EscalationRule{Name: "frequent", From: MAJOR, To: CRITICAL, CountExceeds: 10})
+
+	var reported *generic.Error
+	escalator.Run(context.Background(), 0, func(escalations []Escalation, err *generic.Error) {
+		reported = err
+	})
+
+	if reported == nil || reported.ErrorType != "ClientError" {
+		t.Errorf("Run() error = %v, want a client error", reported)
+	}
+}
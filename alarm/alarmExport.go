@@ -0,0 +1,58 @@
+package alarm
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"io"
+)
+
+// The default columns of a csv export of alarms.
+var DefaultCsvColumns = []string{"id", "time", "creationTime", "type", "severity", "status", "count", "source.id", "source.name", "text"}
+
+/*
+Writes all alarms found by the filter to the export writer, page by page. The writer is flushed at the end.
+Returns the number of exported alarms.
+*/
+func Export(api AlarmApi, alarmFilter *AlarmFilter, pageSize int, writer generic.ExportWriter) (int, *generic.Error) {
+	var collection *AlarmCollection
+	nextPage := func() ([]generic.ExportObject, *generic.Error) {
+		var err *generic.Error
+		if collection == nil {
+			collection, err = api.Find(alarmFilter, pageSize)
+		} else {
+			collection, err = api.NextPage(collection)
+		}
+		if err != nil || collection == nil {
+			return nil, err
+		}
+
+		page := make([]generic.ExportObject, len(collection.Alarms))
+		for i := range collection.Alarms {
+			page[i] = generic.ExportObject{Id: collection.Alarms[i].Id, Object: &collection.Alarms[i]}
+		}
+		return page, nil
+	}
+
+	return generic.ExportPages(nextPage, pageSize, writer, "alarm", "ExportAlarms")
+}
+
+/*
+Exports all alarms found by the filter as CSV. If no columns are given, `DefaultCsvColumns` are used.
+Custom fragments can be exported by their name or a dot separated path, e.g. `c8y_Position.lat`.
+*/
+func ExportCsv(api AlarmApi, alarmFilter *AlarmFilter, pageSize int, w io.Writer, columns ...string) (int, *generic.Error) {
+	if len(columns) == 0 {
+		columns = DefaultCsvColumns
+	}
+
+	writer, err := generic.NewCsvExportWriter(w, columns)
+	if err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while exporting alarms: %s", err.Error()), "ExportAlarms")
+	}
+	return Export(api, alarmFilter, pageSize, writer)
+}
+
+// Exports all alarms found by the filter as newline delimited json.
+func ExportNdjson(api AlarmApi, alarmFilter *AlarmFilter, pageSize int, w io.Writer) (int, *generic.Error) {
+	return Export(api, alarmFilter, pageSize, generic.NewNdjsonExportWriter(w))
+}
@@ -0,0 +1,97 @@
+package alarm
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// A test server with two pages of alarms: the first page is full with two alarms, the second contains one alarm.
+func buildExportHttpServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		alarm := `{"id": "%s", "type": "export-Alarm", "severity": "MAJOR", "status": "ACTIVE", "source": {"id": "1111111"}, "text": "Export, %s", "c8y_Position": {"lat": 52.5}}`
+
+		switch r.URL.Query().Get("currentPage") {
+		case "":
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"next": "%s/alarm/alarms?pageSize=2&currentPage=2", "alarms": [%s, %s]}`,
+				"http://"+r.Host, fmt.Sprintf(alarm, "1", "first"), fmt.Sprintf(alarm, "2", "second"))))
+		default:
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"next": "%s/alarm/alarms?pageSize=2&currentPage=3", "alarms": [%s]}`,
+				"http://"+r.Host, fmt.Sprintf(alarm, "3", "third"))))
+		}
+	}))
+}
+
+func TestAlarmExport_Csv(t *testing.T) {
+	ts := buildExportHttpServer()
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	count, err := ExportCsv(buildAlarmApi(ts.URL), &AlarmFilter{Type: "export-Alarm"}, 2, &buf, "id", "source.id", "text", "c8y_Position.lat")
+
+	if err != nil {
+		t.Fatalf("ExportCsv() got an unexpected error: %s", err.Error())
+	}
+	if count != 3 {
+		t.Errorf("ExportCsv() count = %d, want 3", count)
+	}
+
+	want := "id,source.id,text,c8y_Position.lat\n" +
+		"1,1111111,\"Export, first\",52.5\n" +
+		"2,1111111,\"Export, second\",52.5\n" +
+		"3,1111111,\"Export, third\",52.5\n"
+	if buf.String() != want {
+		t.Errorf("ExportCsv() = \n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestAlarmExport_CsvDefaultColumns(t *testing.T) {
+	ts := buildExportHttpServer()
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	_, err := ExportCsv(buildAlarmApi(ts.URL), &AlarmFilter{}, 2, &buf)
+
+	if err != nil {
+		t.Fatalf("ExportCsv() got an unexpected error: %s", err.Error())
+	}
+
+	header := strings.Join(DefaultCsvColumns, ",") + "\n"
+	if !strings.HasPrefix(buf.String(), header) {
+		t.Errorf("ExportCsv() = %s, want header %s", buf.String(), header)
+	}
+}
+
+func TestAlarmExport_Ndjson(t *testing.T) {
+	ts := buildExportHttpServer()
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	count, err := ExportNdjson(buildAlarmApi(ts.URL), &AlarmFilter{}, 2, &buf)
+
+	if err != nil {
+		t.Fatalf("ExportNdjson() got an unexpected error: %s", err.Error())
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if count != 3 || len(lines) != 3 {
+		t.Fatalf("ExportNdjson() count = %d, lines = %d, want 3", count, len(lines))
+	}
+	if !strings.Contains(lines[2], `"id":"3"`) || !strings.Contains(lines[2], `"c8y_Position":{"lat":52.5}`) {
+		t.Errorf("ExportNdjson() line = %s", lines[2])
+	}
+}
+
+func TestAlarmExport_Error(t *testing.T) {
+	ts := buildHttpServer(http.StatusInternalServerError, "")
+	defer ts.Close()
+
+	_, err := ExportNdjson(buildAlarmApi(ts.URL), &AlarmFilter{}, 2, &bytes.Buffer{})
+
+	if err == nil {
+		t.Errorf("ExportNdjson() expected an error")
+	}
+}
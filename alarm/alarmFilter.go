@@ -2,6 +2,7 @@ package alarm
 
 import (
 	"fmt"
+	"github.com/tarent/gomulocity/generic"
 	"net/url"
 	"strconv"
 	"strings"
@@ -23,9 +24,17 @@ type AlarmFilter struct {
 									// When set to true only resolved alarms will be removed (the one with status CLEARED),
 									// false means alarms with status ACTIVE or ACKNOWLEDGED.
 	Severity			Severity	// Alarm severity, for example MINOR.
+	Severities			[]Severity	// Alarm severities. Combined with Severity, if both are set.
 	DateFrom			*time.Time	// Start date or date and time of alarm occurrence.
 	DateTo				*time.Time	// End date or date and time of alarm occurrence.
+	CreatedFrom			*time.Time	// Start date or date and time of alarm creation.
+	CreatedTo			*time.Time	// End date or date and time of alarm creation.
+	LastUpdatedFrom		*time.Time	// Start date or date and time of the last update of the alarm.
+	LastUpdatedTo		*time.Time	// End date or date and time of the last update of the alarm.
 	Type				string		// Alarm type.
+	Types				[]string	// Alarm types. Combined with Type, if both are set.
+	WithTotalElements	bool		// When set to true the statistics of a found alarm collection contain the total number
+									// of elements. It is not a filter and ignored when deleting alarms.
 }
 
 /*
@@ -34,10 +43,19 @@ https://cumulocity.com/guides/reference/alarms/#put-bulk-update-of-alarm-collect
 type UpdateAlarmsFilter struct {
 	Status				Status
 	SourceId 			string
-	Resolved			string // PLEASE NOTE: when status parameter is set then resolved parameter will be ignored
+	WithSourceAssets	bool		// When this parameter is provided also source must be defined.
+	WithSourceDevices	bool		// When this parameter is provided also source must be defined.
+	Resolved			string		// PLEASE NOTE: when status parameter is set then resolved parameter will be ignored
 	Severity			Severity
+	Severities			[]Severity	// Combined with Severity, if both are set.
 	DateFrom			*time.Time
 	DateTo				*time.Time
+	CreatedFrom			*time.Time
+	CreatedTo			*time.Time
+	LastUpdatedFrom		*time.Time
+	LastUpdatedTo		*time.Time
+	Type				string
+	Types				[]string	// Combined with Type, if both are set.
 }
 
 // Appends the filter query parameters to the provided parameter values for a request
@@ -55,46 +73,28 @@ func (alarmFilter AlarmFilter) QueryParams(params *url.Values) (error) {
 		params.Add("status", strings.Join(statusesAsString, ","))
 	}
 
-	if len(alarmFilter.SourceId) > 0 {
-		params.Add("source", alarmFilter.SourceId)
+	err := sourceParams(params, alarmFilter.SourceId, alarmFilter.WithSourceAssets, alarmFilter.WithSourceDevices)
+	if err != nil {
+		return err
 	}
 
-	if alarmFilter.WithSourceAssets {
-		if len(alarmFilter.SourceId) == 0 {
-			return fmt.Errorf("failed to build filter: when 'WithSourceAssets' parameter is defined also SourceID must be set.")
-		}
-		params.Add("withSourceAssets", "true")
-	}
-
-	if alarmFilter.WithSourceDevices {
-		if len(alarmFilter.SourceId) == 0 {
-			return fmt.Errorf("failed to build filter: when 'WithSourceDevices' parameter is defined also SourceID must be set.")
-		}
-		params.Add("withSourceDevices", "true")
+	err = resolvedParam(params, alarmFilter.Resolved)
+	if err != nil {
+		return err
 	}
 
-	if len(alarmFilter.Resolved) > 0 {
-		resolved, err := strconv.ParseBool(alarmFilter.Resolved)
-		if err != nil {
-			return fmt.Errorf("failed to build filter: if 'Resolved' parameter is set, only 'true' and 'false' values are accepted.")
-		}
-		params.Add("resolved", strconv.FormatBool(resolved))
-	}
-
-	if len(alarmFilter.Severity) > 0 {
-		params.Add("severity", fmt.Sprintf("%s", alarmFilter.Severity))
-	}
+	severityParam(params, alarmFilter.Severity, alarmFilter.Severities)
 
-	if alarmFilter.DateFrom != nil {
-		params.Add("dateFrom", alarmFilter.DateFrom.Format(time.RFC3339))
+	err = timeWindowParams(params, alarmFilter.DateFrom, alarmFilter.DateTo, alarmFilter.CreatedFrom, alarmFilter.CreatedTo,
+		alarmFilter.LastUpdatedFrom, alarmFilter.LastUpdatedTo)
+	if err != nil {
+		return err
 	}
 
-	if alarmFilter.DateTo != nil {
-		params.Add("dateTo", alarmFilter.DateTo.Format(time.RFC3339))
-	}
+	typeParam(params, alarmFilter.Type, alarmFilter.Types)
 
-	if len(alarmFilter.Type) > 0 {
-		params.Add("type", alarmFilter.Type)
+	if alarmFilter.WithTotalElements {
+		params.Add("withTotalElements", "true")
 	}
 
 	return nil
@@ -111,28 +111,125 @@ func (updateAlarmsFilter UpdateAlarmsFilter) QueryParams(params *url.Values) (er
 		params.Add("status", fmt.Sprintf("%s", updateAlarmsFilter.Status))
 	}
 
-	if len(updateAlarmsFilter.SourceId) > 0 {
-		params.Add("source", updateAlarmsFilter.SourceId)
+	err := sourceParams(params, updateAlarmsFilter.SourceId, updateAlarmsFilter.WithSourceAssets, updateAlarmsFilter.WithSourceDevices)
+	if err != nil {
+		return err
 	}
 
-	if len(updateAlarmsFilter.Resolved) > 0 {
-		resolved, err := strconv.ParseBool(updateAlarmsFilter.Resolved)
+	err = resolvedParam(params, updateAlarmsFilter.Resolved)
+	if err != nil {
+		return err
+	}
+
+	severityParam(params, updateAlarmsFilter.Severity, updateAlarmsFilter.Severities)
+
+	err = timeWindowParams(params, updateAlarmsFilter.DateFrom, updateAlarmsFilter.DateTo, updateAlarmsFilter.CreatedFrom,
+		updateAlarmsFilter.CreatedTo, updateAlarmsFilter.LastUpdatedFrom, updateAlarmsFilter.LastUpdatedTo)
+	if err != nil {
+		return err
+	}
+
+	typeParam(params, updateAlarmsFilter.Type, updateAlarmsFilter.Types)
+
+	return nil
+}
+
+// -- internal
+
+func sourceParams(params *url.Values, sourceId string, withSourceAssets bool, withSourceDevices bool) error {
+	if len(sourceId) > 0 {
+		params.Add("source", sourceId)
+	}
+
+	if withSourceAssets {
+		if len(sourceId) == 0 {
+			return fmt.Errorf("failed to build filter: when 'WithSourceAssets' parameter is defined also SourceID must be set.")
+		}
+		params.Add("withSourceAssets", "true")
+	}
+
+	if withSourceDevices {
+		if len(sourceId) == 0 {
+			return fmt.Errorf("failed to build filter: when 'WithSourceDevices' parameter is defined also SourceID must be set.")
+		}
+		params.Add("withSourceDevices", "true")
+	}
+
+	return nil
+}
+
+func resolvedParam(params *url.Values, resolved string) error {
+	if len(resolved) > 0 {
+		resolved, err := strconv.ParseBool(resolved)
 		if err != nil {
 			return fmt.Errorf("failed to build filter: if 'Resolved' parameter is set, only 'true' and 'false' values are accepted.")
 		}
 		params.Add("resolved", strconv.FormatBool(resolved))
 	}
 
-	if len(updateAlarmsFilter.Severity) > 0 {
-		params.Add("severity", fmt.Sprintf("%s", updateAlarmsFilter.Severity))
+	return nil
+}
+
+// Returns the statuses selected by the resolved parameter or nil, if it is not set.
+func resolvedStatuses(resolved string) []Status {
+	if len(resolved) == 0 {
+		return nil
+	}
+	if isResolved, err := strconv.ParseBool(resolved); err != nil {
+		return nil
+	} else if isResolved {
+		return []Status{CLEARED}
+	}
+	return []Status{ACTIVE, ACKNOWLEDGED}
+}
+
+func severityParam(params *url.Values, severity Severity, severities []Severity) {
+	var severitiesAsString []string
+	if len(severity) > 0 {
+		severitiesAsString = append(severitiesAsString, string(severity))
+	}
+	for _, s := range severities {
+		if s != severity {
+			severitiesAsString = append(severitiesAsString, string(s))
+		}
 	}
 
-	if updateAlarmsFilter.DateFrom != nil {
-		params.Add("dateFrom", updateAlarmsFilter.DateFrom.Format(time.RFC3339))
+	if len(severitiesAsString) > 0 {
+		params.Add("severity", strings.Join(severitiesAsString, ","))
 	}
+}
 
-	if updateAlarmsFilter.DateTo != nil {
-		params.Add("dateTo", updateAlarmsFilter.DateTo.Format(time.RFC3339))
+func typeParam(params *url.Values, alarmType string, alarmTypes []string) {
+	var types []string
+	if len(alarmType) > 0 {
+		types = append(types, alarmType)
+	}
+	for _, t := range alarmTypes {
+		if t != alarmType {
+			types = append(types, t)
+		}
+	}
+
+	if len(types) > 0 {
+		params.Add("type", strings.Join(types, ","))
+	}
+}
+
+// Adds the time windows dateFrom/dateTo, createdFrom/createdTo and lastUpdatedFrom/lastUpdatedTo.
+func timeWindowParams(params *url.Values, dateFrom, dateTo, createdFrom, createdTo, lastUpdatedFrom, lastUpdatedTo *time.Time) error {
+	windows := []struct {
+		name     string
+		from, to *time.Time
+	}{
+		{"date", dateFrom, dateTo},
+		{"created", createdFrom, createdTo},
+		{"lastUpdated", lastUpdatedFrom, lastUpdatedTo},
+	}
+
+	for _, window := range windows {
+		if err := generic.TimeWindowParameters(window.name, window.from, window.to, params); err != nil {
+			return err
+		}
 	}
 
 	return nil
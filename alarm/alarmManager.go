@@ -0,0 +1,221 @@
+package alarm
+
+import (
+	"github.com/tarent/gomulocity/generic"
+	"sync"
+)
+
+const managerSyncPageSize = 100
+
+/*
+Manager keeps track of the active alarms per source and alarm type. It raises an alarm when a condition
+starts and clears it when the condition ends. As long as the condition does not change, no further
+requests are sent.
+
+A Manager is safe for concurrent use. Requests for the same source and alarm type are sent one after another,
+requests for different ones in parallel.
+*/
+type Manager struct {
+	api     AlarmApi
+	mutex   sync.Mutex
+	active  map[managedAlarm]*Alarm
+	pending map[managedAlarm]chan struct{} // Closed, when the request in progress for the key is done
+	syncing int                            // The number of running syncs
+	changed map[managedAlarm]bool          // The keys raised or cleared during a running sync
+}
+
+type managedAlarm struct {
+	sourceId  string
+	alarmType string
+}
+
+// Creates a new alarm manager without any known active alarms. Use `Sync` to load them.
+// api - The alarm api used to raise, clear and find alarms.
+func NewManager(api AlarmApi) *Manager {
+	return &Manager{
+		api:     api,
+		active:  map[managedAlarm]*Alarm{},
+		pending: map[managedAlarm]chan struct{}{},
+		changed: map[managedAlarm]bool{},
+	}
+}
+
+/*
+Loads all active alarms of a source from cumulocity and replaces the known state of this source.
+If `sourceId` is empty, the active alarms of all sources are loaded. Alarms raised or cleared by the manager
+while loading are kept as they are, because the loaded state may be older.
+*/
+func (manager *Manager) Sync(sourceId string) *generic.Error {
+	manager.mutex.Lock()
+	manager.syncing++
+	manager.mutex.Unlock()
+
+	found := map[managedAlarm]*Alarm{}
+
+	collection, err := manager.api.Find(&AlarmFilter{SourceId: sourceId, Status: []Status{ACTIVE}}, managerSyncPageSize)
+	for ; collection != nil && err == nil; collection, err = manager.api.NextPage(collection) {
+		for i := range collection.Alarms {
+			alarm := collection.Alarms[i]
+			found[managedAlarm{alarm.Source.Id, alarm.Type}] = &alarm
+		}
+		if len(collection.Alarms) < managerSyncPageSize {
+			break
+		}
+	}
+
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	changed := manager.changed
+	if manager.syncing--; manager.syncing == 0 {
+		manager.changed = map[managedAlarm]bool{}
+	}
+	if err != nil {
+		return err
+	}
+
+	for key := range manager.active {
+		if (sourceId == "" || key.sourceId == sourceId) && !changed[key] {
+			delete(manager.active, key)
+		}
+	}
+	for key, alarm := range found {
+		if !changed[key] {
+			manager.active[key] = alarm
+		}
+	}
+
+	return nil
+}
+
+/*
+Raises the given alarm, if there is no active alarm of the same source and type yet.
+Returns the new or the already active alarm.
+*/
+func (manager *Manager) Raise(newAlarm *NewAlarm) (*Alarm, *generic.Error) {
+	key := managedAlarm{newAlarm.Source.Id, newAlarm.Type}
+
+	release := manager.reserve(key)
+	defer release()
+
+	if alarm, ok := manager.Active(key.sourceId, key.alarmType); ok {
+		return alarm, nil
+	}
+
+	if len(newAlarm.Status) == 0 {
+		raised := *newAlarm
+		raised.Status = ACTIVE
+		newAlarm = &raised
+	}
+
+	alarm, err := manager.api.Create(newAlarm)
+	if err != nil {
+		return nil, err
+	}
+
+	manager.setActive(key, alarm)
+	return alarm, nil
+}
+
+/*
+Clears the active alarm of the given source and type. If there is no active alarm, nothing is done.
+*/
+func (manager *Manager) Clear(sourceId string, alarmType string) *generic.Error {
+	key := managedAlarm{sourceId, alarmType}
+
+	release := manager.reserve(key)
+	defer release()
+
+	alarm, ok := manager.Active(sourceId, alarmType)
+	if !ok {
+		return nil
+	}
+
+	_, err := manager.api.Update(alarm.Id, &UpdateAlarm{Status: CLEARED})
+	if err != nil {
+		return err
+	}
+
+	manager.setActive(key, nil)
+	return nil
+}
+
+/*
+Reports the current state of a condition: raises the given alarm, when the condition is true,
+and clears the alarm of the same source and type otherwise.
+Returns the active alarm or nil, if the condition is false.
+*/
+func (manager *Manager) Report(condition bool, newAlarm *NewAlarm) (*Alarm, *generic.Error) {
+	if condition {
+		return manager.Raise(newAlarm)
+	}
+
+	return nil, manager.Clear(newAlarm.Source.Id, newAlarm.Type)
+}
+
+// Returns the known active alarm of the given source and type.
+func (manager *Manager) Active(sourceId string, alarmType string) (*Alarm, bool) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	alarm, ok := manager.active[managedAlarm{sourceId, alarmType}]
+	return alarm, ok
+}
+
+// Returns all known active alarms.
+func (manager *Manager) ActiveAlarms() []Alarm {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	alarms := make([]Alarm, 0, len(manager.active))
+	for _, alarm := range manager.active {
+		alarms = append(alarms, *alarm)
+	}
+	return alarms
+}
+
+// -- internal
+
+// Sets or - if alarm is nil - removes the active alarm of the key and notes the change for running syncs.
+func (manager *Manager) setActive(key managedAlarm, alarm *Alarm) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	if alarm == nil {
+		delete(manager.active, key)
+	} else {
+		manager.active[key] = alarm
+	}
+	if manager.syncing > 0 {
+		manager.changed[key] = true
+	}
+}
+
+/*
+Waits until no other request of the key is in progress and reserves the key for a request. The mutex is not held
+during the request. The returned function releases the key.
+*/
+func (manager *Manager) reserve(key managedAlarm) func() {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	for {
+		done, ok := manager.pending[key]
+		if !ok {
+			break
+		}
+		manager.mutex.Unlock()
+		<-done
+		manager.mutex.Lock()
+	}
+
+	done := make(chan struct{})
+	manager.pending[key] = done
+	return func() {
+		manager.mutex.Lock()
+		defer manager.mutex.Unlock()
+
+		delete(manager.pending, key)
+		close(done)
+	}
+}
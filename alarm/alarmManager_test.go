@@ -0,0 +1,244 @@
+package alarm
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// A test server, which knows one active alarm of type "sync-Alarm" and counts the requests by method.
+func buildManagerHttpServer(requests map[string]int, bodies *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests[r.Method]++
+		*bodies = append(*bodies, string(body))
+
+		switch r.Method {
+		case http.MethodGet:
+			activeAlarm := strings.Replace(alarm, "test-gomulocity-Alarm", "sync-Alarm", 1)
+			_, _ = w.Write([]byte(fmt.Sprintf(alarmCollectionTemplate, activeAlarm)))
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(alarm))
+		default:
+			_, _ = w.Write([]byte(alarm))
+		}
+	}))
+}
+
+func TestManager_RaisesAndClearsOnConditionChange(t *testing.T) {
+	requests := map[string]int{}
+	var bodies []string
+	ts := buildManagerHttpServer(requests, &bodies)
+	defer ts.Close()
+
+	manager := NewManager(buildAlarmApi(ts.URL))
+	newAlarm := &NewAlarm{Type: "test-gomulocity-Alarm", Source: Source{Id: deviceId}, Severity: MAJOR, Text: "Too hot"}
+
+	// when: The condition starts and stays
+	for i := 0; i < 3; i++ {
+		active, err := manager.Report(true, newAlarm)
+		if err != nil {
+			t.Fatalf("Report() got an unexpected error: %s", err.Error())
+		}
+		if active == nil || active.Id != alarmId {
+			t.Fatalf("Report() active alarm = %v, want id %s", active, alarmId)
+		}
+	}
+
+	// then: The alarm is raised once and as active
+	if requests[http.MethodPost] != 1 {
+		t.Errorf("Report() create requests = %d, want 1", requests[http.MethodPost])
+	}
+	if !strings.Contains(bodies[0], `"status":"ACTIVE"`) {
+		t.Errorf("Report() create body = %s, want status ACTIVE", bodies[0])
+	}
+
+	// when: The condition ends and stays
+	for i := 0; i < 3; i++ {
+		active, err := manager.Report(false, newAlarm)
+		if err != nil {
+			t.Fatalf("Report() got an unexpected error: %s", err.Error())
+		}
+		if active != nil {
+			t.Errorf("Report() active alarm = %v, want nil", active)
+		}
+	}
+
+	// then: The alarm is cleared once
+	if requests[http.MethodPut] != 1 {
+		t.Errorf("Report() update requests = %d, want 1", requests[http.MethodPut])
+	}
+	if !strings.Contains(bodies[1], `"status":"CLEARED"`) {
+		t.Errorf("Report() update body = %s, want status CLEARED", bodies[1])
+	}
+	if _, ok := manager.Active(deviceId, newAlarm.Type); ok {
+		t.Errorf("Active() found a cleared alarm")
+	}
+}
+
+func TestManager_Sync(t *testing.T) {
+	requests := map[string]int{}
+	var bodies []string
+	ts := buildManagerHttpServer(requests, &bodies)
+	defer ts.Close()
+
+	manager := NewManager(buildAlarmApi(ts.URL))
+
+	err := manager.Sync(deviceId)
+	if err != nil {
+		t.Fatalf("Sync() got an unexpected error: %s", err.Error())
+	}
+
+	active, ok := manager.Active(deviceId, "sync-Alarm")
+	if !ok || active.Id != alarmId {
+		t.Fatalf("Active() = %v, want the synced alarm", active)
+	}
+
+	// when: The synced alarm is raised again
+	_, _ = manager.Raise(&NewAlarm{Type: "sync-Alarm", Source: Source{Id: deviceId}})
+
+	// then: No alarm is created
+	if requests[http.MethodPost] != 0 {
+		t.Errorf("Raise() create requests = %d, want 0", requests[http.MethodPost])
+	}
+
+	// and: The synced alarm can be cleared
+	_ = manager.Clear(deviceId, "sync-Alarm")
+	if requests[http.MethodPut] != 1 {
+		t.Errorf("Clear() update requests = %d, want 1", requests[http.MethodPut])
+	}
+	if len(manager.ActiveAlarms()) != 0 {
+		t.Errorf("ActiveAlarms() = %v, want none", manager.ActiveAlarms())
+	}
+}
+
+func TestManager_KeepsStateOnError(t *testing.T) {
+	ts := buildHttpServer(500, `{"error": "internal", "message": "failed"}`)
+	defer ts.Close()
+
+	manager := NewManager(buildAlarmApi(ts.URL))
+
+	_, err := manager.Raise(&NewAlarm{Type: "test-gomulocity-Alarm", Source: Source{Id: deviceId}})
+	if err == nil {
+		t.Fatalf("Raise() expected error on 500")
+	}
+	if _, ok := manager.Active(deviceId, "test-gomulocity-Alarm"); ok {
+		t.Errorf("Active() found an alarm, which could not be raised")
+	}
+
+	if err := manager.Sync(deviceId); err == nil {
+		t.Errorf("Sync() expected error on 500")
+	}
+}
+
+func TestManager_DoesNotBlockOtherAlarmsDuringRequests(t *testing.T) {
+	received, unblock := make(chan struct{}, 2), make(chan struct{})
+	var mutex sync.Mutex
+	posts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		posts++
+		mutex.Unlock()
+		if strings.Contains(string(body), "slow-device") {
+			received <- struct{}{}
+			<-unblock
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(alarm))
+	}))
+	defer ts.Close()
+
+	manager := NewManager(buildAlarmApi(ts.URL))
+	slowAlarm := &NewAlarm{Type: "test-gomulocity-Alarm", Source: Source{Id: "slow-device"}, Severity: MAJOR, Text: "Too hot"}
+
+	// given: Two raises of the same alarm, while the first request is in progress
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := manager.Raise(slowAlarm); err != nil {
+				t.Errorf("Raise() got an unexpected error: %s", err.Error())
+			}
+		}()
+	}
+	<-received
+
+	// then: Other alarms and the known state are not blocked
+	if _, err := manager.Raise(&NewAlarm{Type: "test-gomulocity-Alarm", Source: Source{Id: deviceId}, Severity: MAJOR, Text: "Too hot"}); err != nil {
+		t.Errorf("Raise() got an unexpected error: %s", err.Error())
+	}
+	if _, ok := manager.Active("slow-device", "test-gomulocity-Alarm"); ok {
+		t.Errorf("Active() found an alarm, which is not raised yet")
+	}
+
+	close(unblock)
+	wg.Wait()
+
+	// then: The same alarm is raised only once
+	if posts != 2 {
+		t.Errorf("Raise() sent %d requests, want 2", posts)
+	}
+	if _, ok := manager.Active("slow-device", "test-gomulocity-Alarm"); !ok {
+		t.Errorf("Active() did not find the raised alarm")
+	}
+}
+
+func TestManager_SyncKeepsChangesDuringSync(t *testing.T) {
+	received, unblock := make(chan struct{}), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			// The loaded state is older than the raise and clear during the sync
+			received <- struct{}{}
+			<-unblock
+			activeAlarm := strings.Replace(alarm, "test-gomulocity-Alarm", "sync-Alarm", 1)
+			_, _ = w.Write([]byte(fmt.Sprintf(alarmCollectionTemplate, activeAlarm)))
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(alarm))
+		default:
+			_, _ = w.Write([]byte(alarm))
+		}
+	}))
+	defer ts.Close()
+
+	manager := NewManager(buildAlarmApi(ts.URL))
+	if _, err := manager.Raise(&NewAlarm{Type: "sync-Alarm", Source: Source{Id: deviceId}}); err != nil {
+		t.Fatalf("Raise() got an unexpected error: %s", err.Error())
+	}
+
+	// given: A sync, which loads the alarms
+	done := make(chan *generic.Error)
+	go func() {
+		done <- manager.Sync(deviceId)
+	}()
+	<-received
+
+	// when: An alarm is cleared and another one is raised, before the sync finishes
+	if err := manager.Clear(deviceId, "sync-Alarm"); err != nil {
+		t.Fatalf("Clear() got an unexpected error: %s", err.Error())
+	}
+	if _, err := manager.Raise(&NewAlarm{Type: "other-Alarm", Source: Source{Id: deviceId}}); err != nil {
+		t.Fatalf("Raise() got an unexpected error: %s", err.Error())
+	}
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatalf("Sync() got an unexpected error: %s", err.Error())
+	}
+
+	// then: The sync neither resurrects the cleared alarm nor drops the raised one
+	if _, ok := manager.Active(deviceId, "sync-Alarm"); ok {
+		t.Errorf("Active() found the alarm cleared during the sync")
+	}
+	if _, ok := manager.Active(deviceId, "other-Alarm"); !ok {
+		t.Errorf("Active() did not find the alarm raised during the sync")
+	}
+}
@@ -0,0 +1,151 @@
+package alarm
+
+import (
+	"github.com/tarent/gomulocity/generic"
+	"sync"
+)
+
+const defaultSummaryConcurrency = 4
+
+var allSeverities = []Severity{CRITICAL, MAJOR, MINOR, WARNING}
+var allStatuses = []Status{ACTIVE, ACKNOWLEDGED, CLEARED}
+
+/*
+AlarmSummary contains the number of alarms matching a filter - in total and grouped by severity, status and source.
+*/
+type AlarmSummary struct {
+	Total      int
+	BySeverity map[Severity]int
+	ByStatus   map[Status]int
+	BySource   map[string]int
+}
+
+/*
+Counts the alarms matching the filter in total and grouped by severity, status and the given sources.
+Each number is requested with `Count`; at most `concurrency` requests are sent at the same time.
+
+Severities and statuses restricted by the filter are grouped only, e.g. a filter for ACTIVE alarms results in
+a single status group. The given source ids replace the source of the filter.
+filter - If nil, all alarms are counted.
+concurrency - Maximum number of parallel requests. If not positive, 4 requests are sent in parallel.
+*/
+func Summary(api AlarmApi, filter *AlarmFilter, sourceIds []string, concurrency int) (*AlarmSummary, *generic.Error) {
+	base := AlarmFilter{}
+	if filter != nil {
+		base = *filter
+	}
+	base.WithTotalElements = false
+	if concurrency <= 0 {
+		concurrency = defaultSummaryConcurrency
+	}
+
+	summary := &AlarmSummary{BySeverity: map[Severity]int{}, ByStatus: map[Status]int{}, BySource: map[string]int{}}
+	var counts []summaryCount
+
+	counts = append(counts, summaryCount{filter: base, store: func(count int) { summary.Total = count }})
+
+	for _, severity := range summarySeverities(base) {
+		severityFilter := base
+		severityFilter.Severity = severity
+		severityFilter.Severities = nil
+		severity := severity
+		counts = append(counts, summaryCount{filter: severityFilter, store: func(count int) { summary.BySeverity[severity] = count }})
+	}
+
+	for _, status := range summaryStatuses(base) {
+		statusFilter := base
+		statusFilter.Status = []Status{status}
+		statusFilter.Resolved = ""
+		status := status
+		counts = append(counts, summaryCount{filter: statusFilter, store: func(count int) { summary.ByStatus[status] = count }})
+	}
+
+	for _, sourceId := range sourceIds {
+		sourceFilter := base
+		sourceFilter.SourceId = sourceId
+		sourceId := sourceId
+		counts = append(counts, summaryCount{filter: sourceFilter, store: func(count int) { summary.BySource[sourceId] = count }})
+	}
+
+	err := countConcurrently(api, counts, concurrency)
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// -- internal
+
+type summaryCount struct {
+	filter AlarmFilter
+	store  func(count int)
+}
+
+// Counts all filters with at most `concurrency` parallel requests. Returns the first error.
+func countConcurrently(api AlarmApi, counts []summaryCount, concurrency int) *generic.Error {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var firstErr *generic.Error
+	semaphore := make(chan struct{}, concurrency)
+
+	for i := range counts {
+		semaphore <- struct{}{}
+
+		mutex.Lock()
+		failed := firstErr != nil
+		mutex.Unlock()
+		if failed {
+			<-semaphore
+			break
+		}
+
+		wg.Add(1)
+		go func(c *summaryCount) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			count, err := api.Count(&c.filter)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			c.store(count)
+		}(&counts[i])
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+func summarySeverities(filter AlarmFilter) []Severity {
+	if len(filter.Severity) == 0 && len(filter.Severities) == 0 {
+		return allSeverities
+	}
+
+	var severities []Severity
+	if len(filter.Severity) > 0 {
+		severities = append(severities, filter.Severity)
+	}
+	for _, severity := range filter.Severities {
+		if severity != filter.Severity {
+			severities = append(severities, severity)
+		}
+	}
+	return severities
+}
+
+// The resolved parameter overrides the statuses of a filter.
+func summaryStatuses(filter AlarmFilter) []Status {
+	if statuses := resolvedStatuses(filter.Resolved); statuses != nil {
+		return statuses
+	}
+	if len(filter.Status) > 0 {
+		return filter.Status
+	}
+	return allStatuses
+}
@@ -0,0 +1,112 @@
+package alarm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A test server, which counts alarms of a test data set and tracks the maximum number of parallel requests.
+func buildSummaryHttpServer(parallel *int) *httptest.Server {
+	alarms := []struct {
+		severity Severity
+		status   Status
+		source   string
+	}{
+		{CRITICAL, ACTIVE, "1"},
+		{MAJOR, ACTIVE, "1"},
+		{MAJOR, ACKNOWLEDGED, "2"},
+		{MINOR, CLEARED, "2"},
+		{MINOR, ACTIVE, "3"},
+	}
+
+	var mutex sync.Mutex
+	current := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		current++
+		if current > *parallel {
+			*parallel = current
+		}
+		mutex.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		query := r.URL.Query()
+		matches := func(param string, value string) bool {
+			values := query.Get(param)
+			return values == "" || strings.Contains(","+values+",", ","+value+",")
+		}
+
+		count := 0
+		for _, a := range alarms {
+			if matches("severity", string(a.severity)) && matches("status", string(a.status)) && matches("source", a.source) {
+				count++
+			}
+		}
+
+		mutex.Lock()
+		current--
+		mutex.Unlock()
+		_, _ = w.Write([]byte(strconv.Itoa(count)))
+	}))
+}
+
+func TestAlarmSummary(t *testing.T) {
+	parallel := 0
+	ts := buildSummaryHttpServer(&parallel)
+	defer ts.Close()
+
+	summary, err := Summary(buildAlarmApi(ts.URL), nil, []string{"1", "2"}, 2)
+
+	if err != nil {
+		t.Fatalf("Summary() got an unexpected error: %s", err.Error())
+	}
+
+	want := &AlarmSummary{
+		Total:      5,
+		BySeverity: map[Severity]int{CRITICAL: 1, MAJOR: 2, MINOR: 2, WARNING: 0},
+		ByStatus:   map[Status]int{ACTIVE: 3, ACKNOWLEDGED: 1, CLEARED: 1},
+		BySource:   map[string]int{"1": 2, "2": 2},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("Summary() = %+v, want %+v", summary, want)
+	}
+	if parallel > 2 {
+		t.Errorf("Summary() sent %d parallel requests, want at most 2", parallel)
+	}
+}
+
+func TestAlarmSummary_GroupsRestrictedStatusesOnly(t *testing.T) {
+	parallel := 0
+	ts := buildSummaryHttpServer(&parallel)
+	defer ts.Close()
+
+	summary, err := Summary(buildAlarmApi(ts.URL), &AlarmFilter{Status: []Status{ACTIVE}, Severities: []Severity{MAJOR, MINOR}}, nil, 0)
+
+	if err != nil {
+		t.Fatalf("Summary() got an unexpected error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(summary.ByStatus, map[Status]int{ACTIVE: 2}) {
+		t.Errorf("Summary() by status = %v", summary.ByStatus)
+	}
+	if !reflect.DeepEqual(summary.BySeverity, map[Severity]int{MAJOR: 1, MINOR: 1}) {
+		t.Errorf("Summary() by severity = %v", summary.BySeverity)
+	}
+}
+
+func TestAlarmSummary_Error(t *testing.T) {
+	ts := buildHttpServer(http.StatusInternalServerError, "")
+	defer ts.Close()
+
+	summary, err := Summary(buildAlarmApi(ts.URL), nil, nil, 3)
+
+	if err == nil || summary != nil {
+		t.Errorf("Summary() expected an error without summary, got %v, %v", summary, err)
+	}
+}
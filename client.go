@@ -3,6 +3,7 @@ package gomulocity
 import (
 	"github.com/tarent/gomulocity/alarm"
 	"github.com/tarent/gomulocity/device_bootstrap"
+	"github.com/tarent/gomulocity/events"
 	"github.com/tarent/gomulocity/generic"
 	"github.com/tarent/gomulocity/inventory"
 	"github.com/tarent/gomulocity/measurement"
@@ -14,6 +15,7 @@ type Gomulocity struct {
 	DeviceCredentials  device_bootstrap.DeviceCredentialsApi
 	DeviceRegistration device_bootstrap.DeviceRegistrationApi
 	AlarmApi           alarm.AlarmApi
+	EventsApi          events.Events
 	MeasurementApi     measurement.MeasurementApi
 	Inventory          inventory.InventoryApi
 }
@@ -41,6 +43,7 @@ func NewGomulocity(baseURL, username, password string, bootstrapUsername, bootst
 		DeviceCredentials:  device_bootstrap.NewDeviceCredentialsApi(bootstrapClient),
 		DeviceRegistration: device_bootstrap.NewDeviceRegistrationApi(client),
 		AlarmApi:           alarm.NewAlarmApi(client),
+		EventsApi:          events.NewEventsApi(client),
 		MeasurementApi:     measurement.NewMeasurementApi(client),
 		Inventory:          inventory.NewInventoryApi(client),
 	}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Top level keys of cumulocity objects, which are no custom fragments.
+var standardKeys = []string{
+	"id", "self", "type", "name", "owner", "time", "creationTime", "lastUpdated", "source", "text",
+	"status", "severity", "count", "firstOccurrenceTime",
+	"additionParents", "assetParents", "deviceParents", "childAdditions", "childAssets", "childDevices",
+}
+
+// Keys of cumulocity collections. A sample with such a key contains a list of samples.
+var collectionKeys = []string{"managedObjects", "measurements", "events", "alarms"}
+
+/*
+Generator infers Go structs from sample json documents.
+Every top level field of the samples, whose value is an object and which is not a standard field of a
+cumulocity object, is handled as custom fragment: A struct is generated and registered with
+`generic.RegisterFragment`.
+*/
+type Generator struct {
+	Package  string // The package of the generated code
+	RootType string // If set, a struct with this name is generated for the samples themselves
+
+	root *shape
+}
+
+func NewGenerator(packageName string, rootType string) *Generator {
+	return &Generator{Package: packageName, RootType: rootType, root: &shape{}}
+}
+
+/*
+Adds a sample json document. The document may be a single object (e.g. a managed object or a measurement),
+a cumulocity collection (e.g. a measurement collection) or a list of objects.
+*/
+func (g *Generator) AddSample(j []byte) error {
+	var sample interface{}
+	err := json.Unmarshal(j, &sample)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error while unmarshaling sample json: %v", err))
+	}
+
+	switch sample := sample.(type) {
+	case []interface{}:
+		for _, element := range sample {
+			if err := g.addObject(element); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		for _, key := range collectionKeys {
+			if elements, ok := sample[key].([]interface{}); ok {
+				for _, element := range elements {
+					if err := g.addObject(element); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
+	}
+
+	return g.addObject(sample)
+}
+
+func (g *Generator) addObject(sample interface{}) error {
+	if _, ok := sample.(map[string]interface{}); !ok {
+		return errors.New(fmt.Sprintf("sample is not a json object: %v", sample))
+	}
+
+	g.root.add(sample)
+	return nil
+}
+
+// Returns the formatted Go source with all structs and registrations.
+func (g *Generator) Generate() ([]byte, error) {
+	if len(g.root.fields) == 0 {
+		return nil, errors.New("no samples with fields given")
+	}
+
+	gen := &generation{typeNames: map[string]bool{}}
+	fragmentTypes := map[string]string{}
+
+	standard := map[string]bool{}
+	for _, key := range standardKeys {
+		standard[key] = true
+	}
+	if g.RootType != "" {
+		gen.typeNames[g.RootType] = true
+	}
+
+	for _, key := range sortedKeys(g.root.fields) {
+		field := g.root.fields[key]
+		if standard[key] || !field.isObject() {
+			continue
+		}
+
+		typeName := gen.uniqueTypeName(goName(key))
+		gen.declareStruct(typeName, field)
+		fragmentTypes[key] = typeName
+		gen.fragments = append(gen.fragments, key)
+	}
+
+	if g.RootType != "" {
+		gen.declareRoot(g.RootType, g.root, fragmentTypes)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by fragmentgen. DO NOT EDIT.\n\npackage %s\n\n", g.Package)
+
+	var imports []string
+	if gen.usesTime {
+		imports = append(imports, `"time"`)
+	}
+	if len(gen.fragments) > 0 {
+		imports = append(imports, `"github.com/tarent/gomulocity/generic"`)
+	}
+	if len(imports) > 0 {
+		fmt.Fprintf(&src, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+
+	if len(gen.fragments) > 0 {
+		src.WriteString("func init() {\n")
+		for _, key := range gen.fragments {
+			fmt.Fprintf(&src, "if err := generic.RegisterFragment(%q, %s{}); err != nil {\npanic(err)\n}\n", key, fragmentTypes[key])
+		}
+		src.WriteString("}\n\n")
+	}
+
+	src.WriteString(strings.Join(gen.declarations, "\n"))
+
+	return format.Source(src.Bytes())
+}
+
+// -- internal
+
+/*
+shape is the merged structure of all sample values at the same position.
+*/
+type shape struct {
+	null, boolean, number, text, array, object bool
+
+	noTime  bool   // at least one string is not a RFC3339 time
+	element *shape // array elements
+	fields  map[string]*shape
+}
+
+func (s *shape) add(value interface{}) {
+	switch value := value.(type) {
+	case nil:
+		s.null = true
+	case bool:
+		s.boolean = true
+	case float64:
+		s.number = true
+	case string:
+		s.text = true
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			s.noTime = true
+		}
+	case []interface{}:
+		s.array = true
+		if s.element == nil {
+			s.element = &shape{}
+		}
+		for _, element := range value {
+			s.element.add(element)
+		}
+	case map[string]interface{}:
+		s.object = true
+		if s.fields == nil {
+			s.fields = map[string]*shape{}
+		}
+		for key, fieldValue := range value {
+			field, ok := s.fields[key]
+			if !ok {
+				field = &shape{}
+				s.fields[key] = field
+			}
+			field.add(fieldValue)
+		}
+	}
+}
+
+// Returns the number of different json types except null.
+func (s *shape) kinds() int {
+	kinds := 0
+	for _, kind := range []bool{s.boolean, s.number, s.text, s.array, s.object} {
+		if kind {
+			kinds++
+		}
+	}
+	return kinds
+}
+
+func (s *shape) isObject() bool {
+	return s.object && s.kinds() == 1
+}
+
+type generation struct {
+	declarations []string
+	typeNames    map[string]bool
+	usesTime     bool
+	fragments    []string // registered fragment names
+}
+
+func (gen *generation) declareRoot(typeName string, s *shape, fragmentTypes map[string]string) {
+	declaration := gen.reserveDeclaration()
+	var fields []string
+	fieldNames := map[string]bool{"AdditionalFields": true}
+
+	for _, key := range sortedKeys(s.fields) {
+		var fieldType string
+		if fragmentType, ok := fragmentTypes[key]; ok {
+			fieldType = "*" + fragmentType
+		} else {
+			fieldType = gen.goType(typeName+goName(key), s.fields[key])
+		}
+		fields = append(fields, structField(uniqueFieldName(goName(key), fieldNames), fieldType, key))
+	}
+	fields = append(fields, "AdditionalFields map[string]interface{} `jsonc:\"flat\"`")
+
+	gen.declarations[declaration] = structDeclaration(typeName, fields)
+}
+
+func (gen *generation) declareStruct(typeName string, s *shape) {
+	declaration := gen.reserveDeclaration()
+	var fields []string
+	fieldNames := map[string]bool{}
+
+	for _, key := range sortedKeys(s.fields) {
+		fieldType := gen.goType(typeName+goName(key), s.fields[key])
+		fields = append(fields, structField(uniqueFieldName(goName(key), fieldNames), fieldType, key))
+	}
+
+	gen.declarations[declaration] = structDeclaration(typeName, fields)
+}
+
+// Reserves the position of a declaration, so a struct is declared before its nested structs.
+func (gen *generation) reserveDeclaration() int {
+	gen.declarations = append(gen.declarations, "")
+	return len(gen.declarations) - 1
+}
+
+func structDeclaration(typeName string, fields []string) string {
+	return fmt.Sprintf("type %s struct {\n%s\n}\n", typeName, strings.Join(fields, "\n"))
+}
+
+// Numbers and booleans are never omitted, as their zero values are valid values.
+func structField(fieldName string, fieldType string, key string) string {
+	if fieldType == "float64" || fieldType == "bool" {
+		return fmt.Sprintf("%s %s `json:\"%s\"`", fieldName, fieldType, key)
+	}
+	return fmt.Sprintf("%s %s `json:\"%s,omitempty\"`", fieldName, fieldType, key)
+}
+
+// Returns the Go type for a shape. Nested objects are declared as structs with the given name.
+func (gen *generation) goType(typeName string, s *shape) string {
+	if s.kinds() != 1 {
+		return "interface{}"
+	}
+
+	switch {
+	case s.boolean:
+		return "bool"
+	case s.number:
+		return "float64"
+	case s.text && !s.noTime:
+		gen.usesTime = true
+		return "*time.Time"
+	case s.text:
+		return "string"
+	case s.array:
+		if s.element.object && s.element.kinds() == 1 {
+			elementTypeName := gen.uniqueTypeName(typeName)
+			gen.declareStruct(elementTypeName, s.element)
+			return "[]" + elementTypeName
+		}
+		return "[]" + gen.goType(typeName, s.element)
+	default:
+		structTypeName := gen.uniqueTypeName(typeName)
+		gen.declareStruct(structTypeName, s)
+		return "*" + structTypeName
+	}
+}
+
+func (gen *generation) uniqueTypeName(name string) string {
+	unique := name
+	for i := 2; gen.typeNames[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+
+	gen.typeNames[unique] = true
+	return unique
+}
+
+func uniqueFieldName(name string, fieldNames map[string]bool) string {
+	unique := name
+	for i := 2; fieldNames[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+
+	fieldNames[unique] = true
+	return unique
+}
+
+// Converts a json key into an exported Go identifier, e.g. `c8y_Temperature` -> `C8yTemperature`.
+func goName(key string) string {
+	parts := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var name strings.Builder
+	for _, part := range parts {
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		name.WriteString(string(runes))
+	}
+
+	if name.Len() == 0 {
+		return "Field"
+	}
+	if unicode.IsDigit([]rune(name.String())[0]) {
+		return "F" + name.String()
+	}
+	return name.String()
+}
+
+func sortedKeys(fields map[string]*shape) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleMeasurements = `{
+	"self": "https://t0815.cumulocity.com/measurement/measurements",
+	"measurements": [
+		{
+			"id": "1",
+			"time": "2020-06-30T08:32:04.261Z",
+			"type": "c8y_Thermometer",
+			"source": {"id": "4711"},
+			"c8y_Temperature": {"T": {"value": 21.5, "unit": "C"}},
+			"c8y_Status": {"ok": true, "parts": [{"name": "sensor"}]}
+		},
+		{
+			"id": "2",
+			"time": "2020-06-30T08:33:04.261Z",
+			"type": "c8y_Thermometer",
+			"source": {"id": "4711"},
+			"c8y_Temperature": {"T": {"value": 22, "unit": "C"}, "T2": {"value": 1}},
+			"mixed": 5
+		}
+	]
+}`
+
+const sampleManagedObject = `{"id": "4711", "c8y_IsDevice": {}, "mixed": "five"}`
+
+// Compares the generated source with the golden file testdata/<name>.golden
+func TestGenerator_Generate(t *testing.T) {
+	tests := []struct {
+		name     string
+		rootType string
+		samples  []string
+	}{
+		{"thermometer", "Thermometer", []string{sampleMeasurements, sampleManagedObject}},
+		{"position", "", []string{`[{"id": "1", "c8y_Position": {"lat": 50.7, "lng": 7.1}}]`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := NewGenerator(tt.name, tt.rootType)
+			for _, sample := range tt.samples {
+				if err := generator.AddSample([]byte(sample)); err != nil {
+					t.Fatalf("AddSample() - unexpected error %v", err)
+				}
+			}
+
+			src, err := generator.Generate()
+			if err != nil {
+				t.Fatalf("Generate() - unexpected error %v", err)
+			}
+
+			expectedSource, err := ioutil.ReadFile(filepath.Join("testdata", tt.name+".golden"))
+			if err != nil {
+				t.Fatalf("can not read golden file: %v", err)
+			}
+			if string(src) != string(expectedSource) {
+				t.Errorf("Generate()\n source = %s\n want %s", src, expectedSource)
+			}
+		})
+	}
+}
+
+func TestGenerator_WithoutRootType(t *testing.T) {
+	generator := NewGenerator("thermometer", "")
+	_ = generator.AddSample([]byte(`[{"id": "1", "c8y_Position": {"lat": 50.7, "lng": 7.1}}]`))
+
+	src, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() - unexpected error %v", err)
+	}
+
+	if !strings.Contains(string(src), "type C8yPosition struct") || strings.Contains(string(src), "AdditionalFields") {
+		t.Errorf("Generate() - unexpected source %s", src)
+	}
+	if strings.Contains(string(src), `"time"`) {
+		t.Errorf("Generate() - unused time import in %s", src)
+	}
+}
+
+func TestGenerator_Errors(t *testing.T) {
+	generator := NewGenerator("thermometer", "Thermometer")
+
+	if err := generator.AddSample([]byte(`Hello`)); err == nil {
+		t.Errorf("AddSample() - error expected for invalid json")
+	}
+	if err := generator.AddSample([]byte(`[1, 2]`)); err == nil {
+		t.Errorf("AddSample() - error expected for a list of numbers")
+	}
+	if _, err := generator.Generate(); err == nil {
+		t.Errorf("Generate() - error expected without samples")
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"c8y_Temperature": "C8yTemperature",
+		"serialNumber":    "SerialNumber",
+		"T":               "T",
+		"1st-value":       "F1stValue",
+		"_":               "Field",
+	}
+
+	for key, want := range tests {
+		if got := goName(key); got != want {
+			t.Errorf("goName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
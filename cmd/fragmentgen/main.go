@@ -0,0 +1,60 @@
+/*
+fragmentgen generates Go structs for custom fragments from sample json documents, e.g. exported managed
+objects or measurements. The structs carry `json` tags and can be used with `generic.ObjectFromJson`;
+each fragment is registered with `generic.RegisterFragment`.
+
+Usage:
+
+	fragmentgen [-package name] [-type RootType] [-o output.go] sample.json...
+
+Within a package, use it with `go generate`:
+
+	//go:generate go run github.com/tarent/gomulocity/cmd/fragmentgen -type Thermometer -o fragments_gen.go samples/thermometer.json
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+func main() {
+	packageName := flag.String("package", os.Getenv("GOPACKAGE"), "package of the generated code (default $GOPACKAGE)")
+	rootType := flag.String("type", "", "if set, a struct with this name is generated for the samples themselves")
+	output := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+
+	if *packageName == "" {
+		log.Fatal("no package given. Use -package or run with go generate.")
+	}
+	if flag.NArg() == 0 {
+		log.Fatal("no sample json files given.")
+	}
+
+	generator := NewGenerator(*packageName, *rootType)
+	for _, file := range flag.Args() {
+		j, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Fatalf("Error while reading sample %s: %s", file, err.Error())
+		}
+		if err := generator.AddSample(j); err != nil {
+			log.Fatalf("Error while reading sample %s: %s", file, err.Error())
+		}
+	}
+
+	src, err := generator.Generate()
+	if err != nil {
+		log.Fatalf("Error while generating code: %s", err.Error())
+	}
+
+	if *output == "" {
+		fmt.Print(string(src))
+		return
+	}
+	if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+		log.Fatalf("Error while writing %s: %s", *output, err.Error())
+	}
+}
@@ -29,7 +29,7 @@ func buildEventsApi(url string) Events {
 		Username:   "foo",
 		Password:   "bar",
 	}
-	return NewEventsApi(client)
+	return NewEventsApi(&client)
 }
 
 func buildHttpServer(status int, body string) *httptest.Server {
@@ -5,6 +5,12 @@ import (
 	"time"
 )
 
+const (
+	EVENT_API_PATH        = "/event/events"
+	EVENT_TYPE            = "application/vnd.com.nsn.cumulocity.event+json"
+	EVENT_COLLECTION_TYPE = "application/vnd.com.nsn.cumulocity.eventCollection+json"
+)
+
 type Source struct {
 	Id   string `json:"id"`
 	Self string `json:"self,omitempty"`
@@ -37,6 +43,9 @@ type Event struct {
 	AdditionalFields map[string]interface{} `jsonc:"flat"`
 }
 
+// The json fields of an event, which can not be updated
+var eventReadOnlyFields = []string{"id", "self", "creationTime", "type", "time", "source"}
+
 // application/vnd.com.nsn.cumulocity.eventCollection+json
 // ---- EventCollection
 type EventCollection struct {
@@ -0,0 +1,188 @@
+package events
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	EVENT_BINARY_TYPE           = "application/json"
+	DEFAULT_BINARY_CONTENT_TYPE = "application/octet-stream"
+)
+
+// The way a binary is sent to cumulocity.
+type BinaryUploadMode int
+
+const (
+	// The binary is sent as file part of a 'multipart/form-data' request.
+	MultipartUpload BinaryUploadMode = iota
+	// The binary is sent as request body with its own content type.
+	RawUpload
+)
+
+/*
+Binary is an attachment to upload to an event, e.g. a camera snapshot or a log bundle.
+The content is streamed - it is read once while uploading.
+*/
+type Binary struct {
+	Name        string           // The file name of the binary
+	ContentType string           // The media type of the binary. Default: application/octet-stream
+	Content     io.Reader        // The content of the binary
+	Mode        BinaryUploadMode // Default: MultipartUpload
+}
+
+/*
+Represents the meta data of a binary attached to an event.
+See: https://cumulocity.com/guides/reference/events/#event-binary
+*/
+type EventBinary struct {
+	Id      string     `json:"id,omitempty"`
+	Self    string     `json:"self,omitempty"`
+	Name    string     `json:"name,omitempty"`
+	Type    string     `json:"type,omitempty"`
+	Source  string     `json:"source,omitempty"`
+	Length  int64      `json:"length,omitempty"`
+	Created *time.Time `json:"created,omitempty"`
+}
+
+func (e *events) UploadBinary(eventId string, binary *Binary) (*EventBinary, *generic.Error) {
+	return e.sendBinary(http.MethodPost, eventId, binary, "UploadBinary")
+}
+
+func (e *events) ReplaceBinary(eventId string, binary *Binary) (*EventBinary, *generic.Error) {
+	return e.sendBinary(http.MethodPut, eventId, binary, "ReplaceBinary")
+}
+
+func (e *events) DownloadBinary(eventId string, w io.Writer) *generic.Error {
+	body, status, err := e.client.Download(e.binaryPath(eventId), generic.EmptyHeader(), w)
+	if err != nil {
+		return generic.ClientError(fmt.Sprintf("Error while downloading the binary of an event: %s", err.Error()), "DownloadBinary")
+	}
+	if status != http.StatusOK {
+		return generic.CreateErrorFromResponse(body, status)
+	}
+
+	return nil
+}
+
+func (e *events) DeleteBinary(eventId string) *generic.Error {
+	body, status, err := e.client.Delete(e.binaryPath(eventId), generic.EmptyHeader())
+	if err != nil {
+		return generic.ClientError(fmt.Sprintf("Error while deleting the binary of an event: %s", err.Error()), "DeleteBinary")
+	}
+	if status != http.StatusNoContent {
+		return generic.CreateErrorFromResponse(body, status)
+	}
+
+	return nil
+}
+
+// -- internal
+
+func (e *events) binaryPath(eventId string) string {
+	return fmt.Sprintf("%s/%s/binaries", e.basePath, url.QueryEscape(eventId))
+}
+
+func (e *events) sendBinary(method string, eventId string, binary *Binary, operation string) (*EventBinary, *generic.Error) {
+	if binary == nil || binary.Content == nil {
+		return nil, generic.ClientError("No binary content given", operation)
+	}
+
+	contentType := binary.ContentType
+	if len(contentType) == 0 {
+		contentType = DEFAULT_BINARY_CONTENT_TYPE
+	}
+
+	var content io.Reader
+	header := generic.AcceptHeader(EVENT_BINARY_TYPE)
+	switch binary.Mode {
+	case MultipartUpload:
+		form := newMultipartBinary(binary.Name, contentType, binary.Content)
+		// Stops the writer, if the request failed or the response came before the whole form was read
+		defer form.Close()
+		content = form
+		header["Content-Type"] = []string{form.contentType}
+	case RawUpload:
+		content = binary.Content
+		header["Content-Type"] = []string{contentType}
+		if len(binary.Name) > 0 {
+			header["Content-Disposition"] = []string{mime.FormatMediaType("attachment", map[string]string{"filename": binary.Name})}
+		}
+	default:
+		return nil, generic.ClientError(fmt.Sprintf("Unknown upload mode %d", binary.Mode), operation)
+	}
+
+	body, status, err := e.client.Send(method, e.binaryPath(eventId), content, header)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while sending the binary of an event: %s", err.Error()), operation)
+	}
+	if status != http.StatusCreated && status != http.StatusOK {
+		return nil, generic.CreateErrorFromResponse(body, status)
+	}
+
+	var result EventBinary
+	if len(body) > 0 {
+		err = generic.ObjectFromJson(body, &result)
+		if err != nil {
+			return nil, generic.ClientError(fmt.Sprintf("Error while parsing response JSON: %s", err.Error()), operation)
+		}
+	}
+
+	return &result, nil
+}
+
+/*
+multipartBinary streams the content as file part of a multipart form. The form is written by a goroutine,
+which is started on the first read, so nothing is written, if the request is never sent.
+Close stops the writer, if the form was not read completely.
+*/
+type multipartBinary struct {
+	contentType string
+	reader      *io.PipeReader
+	start       sync.Once
+	write       func()
+}
+
+func newMultipartBinary(name string, contentType string, content io.Reader) *multipartBinary {
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	return &multipartBinary{
+		contentType: form.FormDataContentType(),
+		reader:      reader,
+		write: func() {
+			part, err := form.CreatePart(textproto.MIMEHeader{
+				"Content-Disposition": {mime.FormatMediaType("form-data", map[string]string{"name": "file", "filename": name})},
+				"Content-Type":        {contentType},
+			})
+			if err == nil {
+				_, err = io.Copy(part, content)
+			}
+			if err == nil {
+				err = form.Close()
+			}
+			_ = writer.CloseWithError(err)
+		},
+	}
+}
+
+func (m *multipartBinary) Read(p []byte) (int, error) {
+	m.start.Do(func() {
+		go m.write()
+	})
+	return m.reader.Read(p)
+}
+
+func (m *multipartBinary) Close() error {
+	// A form, which has not been read yet, is never written
+	m.start.Do(func() {})
+	return m.reader.Close()
+}
@@ -0,0 +1,265 @@
+package events
+
+import (
+	"bytes"
+	"github.com/tarent/gomulocity/generic"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var binaryResponse = `{
+	"id": "5555",
+	"self": "https://t0815.cumulocity.com/event/events/1337/binaries",
+	"name": "snapshot.jpg",
+	"type": "image/jpeg",
+	"source": "1337",
+	"length": 11
+}`
+
+type capturedBinaryRequest struct {
+	method      string
+	path        string
+	contentType string
+	disposition string
+	body        []byte
+}
+
+func buildBinaryHttpServer(status int, response string, captured *capturedBinaryRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*captured = capturedBinaryRequest{
+			method:      r.Method,
+			path:        r.URL.Path,
+			contentType: r.Header.Get("Content-Type"),
+			disposition: r.Header.Get("Content-Disposition"),
+			body:        body,
+		}
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+}
+
+func TestEvents_UploadBinary_Multipart(t *testing.T) {
+	var captured capturedBinaryRequest
+	ts := buildBinaryHttpServer(http.StatusCreated, binaryResponse, &captured)
+	defer ts.Close()
+
+	api := buildEventsApi(ts.URL)
+
+	binary, err := api.UploadBinary(eventId, &Binary{Name: "snapshot.jpg", ContentType: "image/jpeg", Content: strings.NewReader("hello world")})
+
+	if err != nil {
+		t.Fatalf("UploadBinary() got an unexpected error: %s", err.Error())
+	}
+	if binary.Id != "5555" || binary.Length != 11 || binary.Type != "image/jpeg" {
+		t.Errorf("UploadBinary() binary = %+v", binary)
+	}
+	if captured.method != http.MethodPost || captured.path != "/event/events/"+eventId+"/binaries" {
+		t.Errorf("UploadBinary() request = %s %s", captured.method, captured.path)
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(captured.contentType)
+	if mediaType != "multipart/form-data" {
+		t.Fatalf("UploadBinary() content type = %s, want multipart/form-data", captured.contentType)
+	}
+	part, partErr := multipart.NewReader(bytes.NewReader(captured.body), params["boundary"]).NextPart()
+	if partErr != nil {
+		t.Fatalf("UploadBinary() invalid multipart body: %v", partErr)
+	}
+	content, _ := ioutil.ReadAll(part)
+	if part.FormName() != "file" || part.FileName() != "snapshot.jpg" || part.Header.Get("Content-Type") != "image/jpeg" || string(content) != "hello world" {
+		t.Errorf("UploadBinary() part = %s, %s, %v, %s", part.FormName(), part.FileName(), part.Header, content)
+	}
+}
+
+func TestEvents_UploadBinary_Raw(t *testing.T) {
+	var captured capturedBinaryRequest
+	ts := buildBinaryHttpServer(http.StatusCreated, binaryResponse, &captured)
+	defer ts.Close()
+
+	api := buildEventsApi(ts.URL)
+
+	_, err := api.UploadBinary(eventId, &Binary{Name: "log.txt", Content: strings.NewReader("hello world"), Mode: RawUpload})
+
+	if err != nil {
+		t.Fatalf("UploadBinary() got an unexpected error: %s", err.Error())
+	}
+	if captured.contentType != DEFAULT_BINARY_CONTENT_TYPE || captured.disposition != `attachment; filename=log.txt` || string(captured.body) != "hello world" {
+		t.Errorf("UploadBinary() request = %+v", captured)
+	}
+}
+
+func TestEvents_UploadBinary_WithoutContent(t *testing.T) {
+	api := buildEventsApi("")
+
+	_, err := api.UploadBinary(eventId, &Binary{Name: "empty"})
+
+	if err == nil {
+		t.Errorf("UploadBinary() expected an error without content")
+	}
+}
+
+func TestEvents_UploadBinary_Error(t *testing.T) {
+	var captured capturedBinaryRequest
+	ts := buildBinaryHttpServer(http.StatusConflict, `{"error": "binaries/conflict", "message": "Binary already exists"}`, &captured)
+	defer ts.Close()
+
+	api := buildEventsApi(ts.URL)
+
+	_, err := api.UploadBinary(eventId, &Binary{Content: strings.NewReader("hello world")})
+
+	if err == nil || err.Message != "Binary already exists" {
+		t.Errorf("UploadBinary() expected conflict error, got %v", err)
+	}
+}
+
+// A reader, which counts the reads and never ends
+type endlessReader struct {
+	reads int32
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	atomic.AddInt32(&r.reads, 1)
+	return len(p), nil
+}
+
+func TestEvents_UploadBinary_InvalidRequest(t *testing.T) {
+	goroutines := settledGoroutines()
+	api := buildEventsApi("http://[::1")
+	content := &endlessReader{}
+
+	_, err := api.UploadBinary(eventId, &Binary{Content: content})
+
+	if err == nil || err.ErrorType != "ClientError" {
+		t.Errorf("UploadBinary() expected a client error, got %v", err)
+	}
+	if atomic.LoadInt32(&content.reads) != 0 {
+		t.Errorf("UploadBinary() read the content of a request, which was never sent")
+	}
+	if !goroutinesDone(goroutines) {
+		t.Errorf("UploadBinary() left a goroutine behind: %d, want <= %d", runtime.NumGoroutine(), goroutines)
+	}
+}
+
+func TestEvents_UploadBinary_ResponseBeforeContentIsRead(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		_, _ = w.Write([]byte(`{"error": "binaries/tooLarge", "message": "Binary is too large"}`))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	api := NewEventsApi(&generic.Client{HTTPClient: client, BaseURL: ts.URL, Username: "foo", Password: "bar"})
+	goroutines := settledGoroutines()
+
+	_, err := api.UploadBinary(eventId, &Binary{Content: &endlessReader{}})
+
+	if err == nil || err.Message != "Binary is too large" {
+		t.Errorf("UploadBinary() expected too large error, got %v", err)
+	}
+	if !goroutinesDone(goroutines) {
+		t.Errorf("UploadBinary() left a goroutine behind: %d, want <= %d", runtime.NumGoroutine(), goroutines)
+	}
+}
+
+// Returns the number of goroutines, after the goroutines of connections closed by earlier tests have ended.
+func settledGoroutines() int {
+	http.DefaultClient.CloseIdleConnections()
+	goroutines := runtime.NumGoroutine()
+	for stable := 0; stable < 5; {
+		time.Sleep(10 * time.Millisecond)
+		if current := runtime.NumGoroutine(); current != goroutines {
+			goroutines, stable = current, 0
+		} else {
+			stable++
+		}
+	}
+	return goroutines
+}
+
+// Waits until the number of goroutines is back to the given number.
+func goroutinesDone(goroutines int) bool {
+	for i := 0; i < 100; i++ {
+		if runtime.NumGoroutine() <= goroutines {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestEvents_ReplaceBinary(t *testing.T) {
+	var captured capturedBinaryRequest
+	ts := buildBinaryHttpServer(http.StatusCreated, binaryResponse, &captured)
+	defer ts.Close()
+
+	api := buildEventsApi(ts.URL)
+
+	_, err := api.ReplaceBinary(eventId, &Binary{ContentType: "text/plain", Content: strings.NewReader("new content"), Mode: RawUpload})
+
+	if err != nil {
+		t.Fatalf("ReplaceBinary() got an unexpected error: %s", err.Error())
+	}
+	if captured.method != http.MethodPut || captured.contentType != "text/plain" || string(captured.body) != "new content" {
+		t.Errorf("ReplaceBinary() request = %+v", captured)
+	}
+}
+
+func TestEvents_DownloadBinary(t *testing.T) {
+	var captured capturedBinaryRequest
+	ts := buildBinaryHttpServer(http.StatusOK, "hello world", &captured)
+	defer ts.Close()
+
+	api := buildEventsApi(ts.URL)
+
+	var buf bytes.Buffer
+	err := api.DownloadBinary(eventId, &buf)
+
+	if err != nil {
+		t.Fatalf("DownloadBinary() got an unexpected error: %s", err.Error())
+	}
+	if buf.String() != "hello world" || captured.method != http.MethodGet || captured.path != "/event/events/"+eventId+"/binaries" {
+		t.Errorf("DownloadBinary() content = %s, request = %+v", buf.String(), captured)
+	}
+}
+
+func TestEvents_DownloadBinary_NotFound(t *testing.T) {
+	var captured capturedBinaryRequest
+	ts := buildBinaryHttpServer(http.StatusNotFound, `{"error": "binaries/notFound", "message": "Not found"}`, &captured)
+	defer ts.Close()
+
+	api := buildEventsApi(ts.URL)
+
+	var buf bytes.Buffer
+	err := api.DownloadBinary(eventId, &buf)
+
+	if err == nil || buf.Len() != 0 {
+		t.Errorf("DownloadBinary() expected an error and no content, got %v, %s", err, buf.String())
+	}
+}
+
+func TestEvents_DeleteBinary(t *testing.T) {
+	var captured capturedBinaryRequest
+	ts := buildBinaryHttpServer(http.StatusNoContent, "", &captured)
+	defer ts.Close()
+
+	api := buildEventsApi(ts.URL)
+
+	err := api.DeleteBinary(eventId)
+
+	if err != nil {
+		t.Fatalf("DeleteBinary() got an unexpected error: %s", err.Error())
+	}
+	if captured.method != http.MethodDelete || captured.path != "/event/events/"+eventId+"/binaries" {
+		t.Errorf("DeleteBinary() request = %s %s", captured.method, captured.path)
+	}
+}
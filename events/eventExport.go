@@ -0,0 +1,58 @@
+package events
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"io"
+)
+
+// The default columns of a csv export of events.
+var DefaultCsvColumns = []string{"id", "time", "creationTime", "type", "source.id", "text"}
+
+/*
+Writes all events found by the filter to the export writer, page by page. The writer is flushed at the end.
+Returns the number of exported events.
+*/
+func Export(api Events, filter *EventFilter, pageSize int, writer generic.ExportWriter) (int, *generic.Error) {
+	var collection *EventCollection
+	nextPage := func() ([]generic.ExportObject, *generic.Error) {
+		var err *generic.Error
+		if collection == nil {
+			collection, err = api.Find(filter, pageSize)
+		} else {
+			collection, err = api.NextPage(collection)
+		}
+		if err != nil || collection == nil {
+			return nil, err
+		}
+
+		page := make([]generic.ExportObject, len(collection.Events))
+		for i := range collection.Events {
+			page[i] = generic.ExportObject{Id: collection.Events[i].Id, Object: &collection.Events[i]}
+		}
+		return page, nil
+	}
+
+	return generic.ExportPages(nextPage, pageSize, writer, "event", "ExportEvents")
+}
+
+/*
+Exports all events found by the filter as CSV. If no columns are given, `DefaultCsvColumns` are used.
+Custom fragments can be exported by their name or a dot separated path, e.g. `c8y_Position.lat`.
+*/
+func ExportCsv(api Events, filter *EventFilter, pageSize int, w io.Writer, columns ...string) (int, *generic.Error) {
+	if len(columns) == 0 {
+		columns = DefaultCsvColumns
+	}
+
+	writer, err := generic.NewCsvExportWriter(w, columns)
+	if err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while exporting events: %s", err.Error()), "ExportEvents")
+	}
+	return Export(api, filter, pageSize, writer)
+}
+
+// Exports all events found by the filter as newline delimited json.
+func ExportNdjson(api Events, filter *EventFilter, pageSize int, w io.Writer) (int, *generic.Error) {
+	return Export(api, filter, pageSize, generic.NewNdjsonExportWriter(w))
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// A test server with two pages of events: the first page is full with two events, the second contains one event.
+func buildExportHttpServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := `{"id": "%s", "type": "export-Event", "time": "2020-06-26T10:43:25Z", "source": {"id": "4711"}, "text": "Export %s", "c8y_Door": {"open": true}}`
+
+		switch r.URL.Query().Get("currentPage") {
+		case "":
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"next": "%s/event/events?pageSize=2&currentPage=2", "events": [%s, %s]}`,
+				"http://"+r.Host, fmt.Sprintf(event, "1", "first"), fmt.Sprintf(event, "2", "second"))))
+		default:
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"next": "%s/event/events?pageSize=2&currentPage=3", "events": [%s]}`,
+				"http://"+r.Host, fmt.Sprintf(event, "3", "third"))))
+		}
+	}))
+}
+
+func TestEventExport_Csv(t *testing.T) {
+	ts := buildExportHttpServer()
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	count, err := ExportCsv(buildEventsApi(ts.URL), &EventFilter{}, 2, &buf, "id", "time", "text", "c8y_Door.open")
+
+	if err != nil {
+		t.Fatalf("ExportCsv() got an unexpected error: %s", err.Error())
+	}
+	if count != 3 {
+		t.Errorf("ExportCsv() count = %d, want 3", count)
+	}
+
+	want := "id,time,text,c8y_Door.open\n" +
+		"1,2020-06-26T10:43:25Z,Export first,true\n" +
+		"2,2020-06-26T10:43:25Z,Export second,true\n" +
+		"3,2020-06-26T10:43:25Z,Export third,true\n"
+	if buf.String() != want {
+		t.Errorf("ExportCsv() = \n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestEventExport_Ndjson(t *testing.T) {
+	ts := buildExportHttpServer()
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	count, err := ExportNdjson(buildEventsApi(ts.URL), &EventFilter{}, 2, &buf)
+
+	if err != nil {
+		t.Fatalf("ExportNdjson() got an unexpected error: %s", err.Error())
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if count != 3 || len(lines) != 3 {
+		t.Fatalf("ExportNdjson() count = %d, lines = %d, want 3", count, len(lines))
+	}
+	if !strings.Contains(lines[0], `"c8y_Door":{"open":true}`) {
+		t.Errorf("ExportNdjson() line = %s", lines[0])
+	}
+}
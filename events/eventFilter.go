@@ -0,0 +1,96 @@
+package events
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"net/url"
+	"time"
+)
+
+/*
+See: https://cumulocity.com/guides/reference/events/#event-collection
+*/
+type EventFilter struct {
+	DateFrom        *time.Time // Start date or date and time of the event occurrence.
+	DateTo          *time.Time // End date or date and time of the event occurrence.
+	CreatedFrom     *time.Time // Start date or date and time of the event creation.
+	CreatedTo       *time.Time // End date or date and time of the event creation.
+	LastUpdatedFrom *time.Time // Start date or date and time of the last update of the event.
+	LastUpdatedTo   *time.Time // End date or date and time of the last update of the event.
+	FragmentType    string     // Only events, which contain a fragment with this name.
+	// Only events, whose fragment has this value. When this parameter is provided also FragmentType must be defined.
+	FragmentValue string
+	Type          string // Event type.
+	Source        string // Source device id.
+	// When set to true also events for related source assets will be included.
+	// When this parameter is provided also source must be defined.
+	WithSourceAssets bool
+	// When set to true also events for related source devices will be included.
+	// When this parameter is provided also source must be defined.
+	WithSourceDevices bool
+	// When set to true the events are returned in ascending instead of descending order of time.
+	// It is not a filter and ignored when deleting events.
+	Revert bool
+}
+
+// Appends the filter query parameters to the provided parameter values for a request
+// When provided values is nil an error will be created
+func (eventFilter EventFilter) QueryParams(params *url.Values) error {
+	if params == nil {
+		return fmt.Errorf("The provided parameter values must not be nil!")
+	}
+
+	err := generic.TimeWindowParameters("date", eventFilter.DateFrom, eventFilter.DateTo, params)
+	if err != nil {
+		return err
+	}
+
+	err = generic.TimeWindowParameters("created", eventFilter.CreatedFrom, eventFilter.CreatedTo, params)
+	if err != nil {
+		return err
+	}
+
+	err = generic.TimeWindowParameters("lastUpdated", eventFilter.LastUpdatedFrom, eventFilter.LastUpdatedTo, params)
+	if err != nil {
+		return err
+	}
+
+	if len(eventFilter.FragmentType) > 0 {
+		params.Add("fragmentType", eventFilter.FragmentType)
+	}
+
+	if len(eventFilter.FragmentValue) > 0 {
+		if len(eventFilter.FragmentType) == 0 {
+			return fmt.Errorf("failed to build filter: when 'FragmentValue' parameter is defined also FragmentType must be set.")
+		}
+		params.Add("fragmentValue", eventFilter.FragmentValue)
+	}
+
+	if len(eventFilter.Type) > 0 {
+		params.Add("type", eventFilter.Type)
+	}
+
+	if len(eventFilter.Source) > 0 {
+		params.Add("source", eventFilter.Source)
+	}
+
+	if eventFilter.WithSourceAssets {
+		if len(eventFilter.Source) == 0 {
+			return fmt.Errorf("failed to build filter: when 'WithSourceAssets' parameter is defined also Source must be set.")
+		}
+		params.Add("withSourceAssets", "true")
+	}
+
+	if eventFilter.WithSourceDevices {
+		if len(eventFilter.Source) == 0 {
+			return fmt.Errorf("failed to build filter: when 'WithSourceDevices' parameter is defined also Source must be set.")
+		}
+		params.Add("withSourceDevices", "true")
+	}
+
+	if eventFilter.Revert {
+		params.Add("revert", "true")
+	}
+
+	return nil
+}
@@ -1,21 +1,20 @@
 package events
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/tarent/gomulocity/generic"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"time"
 )
 
-const EVENT_ACCEPT_HEADER = "application/vnd.com.nsn.cumulocity.eventApi+json"
-
 // Creates a new events api object
 // client - Must be a gomulocity client.
 // returns - The `Events`-api object
-func NewEventsApi(client generic.Client) Events {
-	return &events{client, "/event/events"}
+func NewEventsApi(client *generic.Client) Events {
+	return &events{client, EVENT_API_PATH}
 }
 
 type Events interface {
@@ -26,19 +25,46 @@ type Events interface {
 	// Updated an exiting event and returns the updated event entity.
 	UpdateEvent(eventId string, event *UpdateEvent) (*Event, *generic.Error)
 
+	// Compares an event before and after local modifications and updates only the changed fields.
+	// Removed custom fragments are set to null, changes of read-only fields, e.g. the source, are not sent.
+	// If nothing has changed, `before` is returned without a request.
+	UpdateChanges(before *Event, after *Event) (*Event, *generic.Error)
+
 	// Deletes an exiting event. If error is nil, the event was deleted
 	// successfully.
 	DeleteEvent(eventId string) *generic.Error
 
+	// Deletes events by filter. If error is nil, events were deleted successfully.
+	// ATTENTION: at least one filter should be set otherwise an error will be thrown.
+	// Use DeleteAll() (with caution!) instead if you want delete all events!
+	Delete(filter *EventFilter) *generic.Error
+
+	// A special function to delete all events at once to avoid accident deletion using the Delete()-function with filters.
+	// If error is nil, events were deleted successfully.
+	// ATTENTION: use it with caution!
+	DeleteAll() *generic.Error
+
 	// Gets an exiting event by its id. If the id does not exists, nil is returned.
 	Get(eventId string) (*Event, *generic.Error)
 
 	// Gets a event collection by a source (aka managed object id).
 	GetForDevice(source string, pageSize int) (*EventCollection, *generic.Error)
 
-	// Returns an event collection, found by the given event query parameters.
-	// all query parameters are AND concat.
-	Find(query EventQuery) (*EventCollection, *generic.Error)
+	// Returns an event collection, found by the given event filter.
+	// All filter parameters are AND concatenated.
+	Find(filter *EventFilter, pageSize int) (*EventCollection, *generic.Error)
+
+	// Uploads a binary attachment to an existing event. An event can carry one binary only.
+	UploadBinary(eventId string, binary *Binary) (*EventBinary, *generic.Error)
+
+	// Streams the binary attachment of an event to the writer.
+	DownloadBinary(eventId string, w io.Writer) *generic.Error
+
+	// Replaces the binary attachment of an event.
+	ReplaceBinary(eventId string, binary *Binary) (*EventBinary, *generic.Error)
+
+	// Deletes the binary attachment of an event. If error is nil, the binary was deleted successfully.
+	DeleteBinary(eventId string) *generic.Error
 
 	// Gets the next page from an existing event collection.
 	// If there is no next page, nil is returned.
@@ -49,64 +75,70 @@ type Events interface {
 	PreviousPage(c *EventCollection) (*EventCollection, *generic.Error)
 }
 
-type EventQuery struct {
-	DateFrom     *time.Time
-	DateTo       *time.Time
-	FragmentType string
-	Type         string
-	Source       string
-	PageSize     int
+type events struct {
+	client   *generic.Client
+	basePath string
 }
 
-func (q EventQuery) QueryParams() (string, *generic.Error) {
-	if q.PageSize < 0 || q.PageSize > 2000 {
-		return "", generic.ClientError(fmt.Sprintf("The page size must be between 1 and 2000. Was %d", q.PageSize), "QueryParams")
-	}
-
-	params := url.Values{}
+func (e *events) DeleteEvent(eventId string) *generic.Error {
+	body, status, err := e.client.Delete(fmt.Sprintf("%s/%s", e.basePath, url.QueryEscape(eventId)), generic.EmptyHeader())
 
-	if q.DateFrom != nil {
-		params.Add("dateFrom", q.DateFrom.Format(time.RFC3339))
+	if err != nil {
+		return generic.ClientError(fmt.Sprintf("Error while deleting an event: %s", err.Error()), "DeleteEvent")
 	}
 
-	if q.DateTo != nil {
-		params.Add("dateTo", q.DateTo.Format(time.RFC3339))
+	if status != http.StatusNoContent {
+		return generic.CreateErrorFromResponse(body, status)
 	}
 
-	if q.PageSize > 0 {
-		params.Add("pageSize", fmt.Sprintf("%d", q.PageSize))
-	}
+	return nil
+}
 
-	if len(q.FragmentType) > 0 {
-		params.Add("fragmentType", q.FragmentType)
-	}
+/*
+Deletes events by filter.
 
-	if len(q.Type) > 0 {
-		params.Add("type", q.Type)
+See: https://cumulocity.com/guides/reference/events/#delete-delete-an-event-collection
+*/
+func (e *events) Delete(filter *EventFilter) *generic.Error {
+	if filter == nil {
+		return generic.ClientError("No filter set. At least one filter has to be set to avoid accident deletion of all events. Use `DeleteAll()` if you really want to remove them all", "DeleteEvents")
+	}
+	queryParamsValues := &url.Values{}
+	err := filter.QueryParams(queryParamsValues)
+	if err != nil {
+		return generic.ClientError(fmt.Sprintf("Error while building query parameters for deletion of events: %s", err.Error()), "DeleteEvents")
+	}
+	// revert is no filter: it must neither be sent nor protect from deleting all events
+	queryParamsValues.Del("revert")
+	if len(*queryParamsValues) == 0 {
+		return generic.ClientError("No filter set. At least one filter has to be set to avoid accident deletion of all events. Use `DeleteAll()` if you really want to remove them all", "DeleteEvents")
 	}
 
-	if len(q.Source) > 0 {
-		params.Add("source", q.Source)
+	body, status, err := e.client.Delete(fmt.Sprintf("%s?%s", e.basePath, queryParamsValues.Encode()), generic.EmptyHeader())
+	if err != nil {
+		return generic.ClientError(fmt.Sprintf("Error while deleting events: %s", err.Error()), "DeleteEvents")
 	}
 
-	return params.Encode(), nil
-}
+	if status != http.StatusNoContent {
+		return generic.CreateErrorFromResponse(body, status)
+	}
 
-type events struct {
-	client   generic.Client
-	basePath string
+	return nil
 }
 
-func (e *events) DeleteEvent(eventId string) *generic.Error {
-	body, status, err := e.client.Delete(fmt.Sprintf("%s/%s", e.basePath, url.QueryEscape(eventId)), generic.EmptyHeader())
-
+/*
+ATTENTION: This function deletes all events
+*/
+func (e *events) DeleteAll() *generic.Error {
+	body, status, err := e.client.Delete(e.basePath, generic.EmptyHeader())
 	if err != nil {
-		return generic.ClientError(fmt.Sprintf("Error while deleting an event: %s", err.Error()), "DeleteEvent")
+		return generic.ClientError(fmt.Sprintf("Error while deleting events: %s", err.Error()), "DeleteAllEvents")
 	}
 
 	if status != http.StatusNoContent {
 		return generic.CreateErrorFromResponse(body, status)
 	}
+	log.Println("WARNING: all events of the tenant were deleted!")
 
 	return nil
 }
@@ -117,7 +149,7 @@ func (e *events) CreateEvent(event *CreateEvent) (*Event, *generic.Error) {
 		return nil, generic.ClientError(fmt.Sprintf("Error while marshalling the event: %s", err.Error()), "CreateEvent")
 	}
 
-	body, status, err := e.client.Post(e.basePath, bytes, generic.AcceptHeader(EVENT_ACCEPT_HEADER))
+	body, status, err := e.client.Post(e.basePath, bytes, generic.AcceptAndContentTypeHeader(EVENT_TYPE, EVENT_TYPE))
 	if err != nil {
 		return nil, generic.ClientError(fmt.Sprintf("Error while posting a new event: %s", err.Error()), "CreateEvent")
 	}
@@ -134,20 +166,32 @@ func (e *events) UpdateEvent(eventId string, event *UpdateEvent) (*Event, *gener
 		return nil, generic.ClientError(fmt.Sprintf("Error while marshalling the update event: %s", err.Error()), "UpdateEvent")
 	}
 
-	path := fmt.Sprintf("%s/%s", e.basePath, url.QueryEscape(eventId))
-	body, status, err := e.client.Put(path, bytes, generic.AcceptHeader(EVENT_ACCEPT_HEADER))
+	return e.update(eventId, bytes, "UpdateEvent")
+}
+
+func (e *events) UpdateChanges(before *Event, after *Event) (*Event, *generic.Error) {
+	if before == nil || after == nil || len(before.Id) == 0 {
+		return nil, generic.ClientError("Updating changes needs an existing event with id before and after the changes", "UpdateEventChanges")
+	}
+
+	diff, err := generic.UpdateDiff(before, after, eventReadOnlyFields...)
 	if err != nil {
-		return nil, generic.ClientError(fmt.Sprintf("Error while updating an event: %s", err.Error()), "UpdateEvent")
+		return nil, generic.ClientError(fmt.Sprintf("Error while comparing the events: %s", err.Error()), "UpdateEventChanges")
 	}
-	if status != http.StatusOK {
-		return nil, generic.CreateErrorFromResponse(body, status)
+	if len(diff) == 0 {
+		return before, nil
 	}
 
-	return parseEventResponse(body)
+	bytes, err := json.Marshal(diff)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while marshalling the event changes: %s", err.Error()), "UpdateEventChanges")
+	}
+
+	return e.update(before.Id, bytes, "UpdateEventChanges")
 }
 
 func (e *events) Get(eventId string) (*Event, *generic.Error) {
-	body, status, err := e.client.Get(fmt.Sprintf("%s/%s", e.basePath, url.QueryEscape(eventId)), generic.EmptyHeader())
+	body, status, err := e.client.Get(fmt.Sprintf("%s/%s", e.basePath, url.QueryEscape(eventId)), generic.AcceptHeader(EVENT_TYPE))
 
 	if err != nil {
 		return nil, generic.ClientError(fmt.Sprintf("Error while getting an event: %s", err.Error()), "Get")
@@ -160,16 +204,24 @@ func (e *events) Get(eventId string) (*Event, *generic.Error) {
 }
 
 func (e *events) GetForDevice(source string, pageSize int) (*EventCollection, *generic.Error) {
-	return e.Find(EventQuery{Source: source, PageSize: pageSize})
+	return e.Find(&EventFilter{Source: source}, pageSize)
 }
 
-func (e *events) Find(query EventQuery) (*EventCollection, *generic.Error) {
-	queryParams, err := query.QueryParams()
+func (e *events) Find(filter *EventFilter, pageSize int) (*EventCollection, *generic.Error) {
+	queryParamsValues := &url.Values{}
+	if filter != nil {
+		err := filter.QueryParams(queryParamsValues)
+		if err != nil {
+			return nil, generic.ClientError(fmt.Sprintf("Error while building query parameters to search for events: %s", err.Error()), "FindEvents")
+		}
+	}
+
+	err := generic.PageSizeParameter(pageSize, queryParamsValues)
 	if err != nil {
-		return nil, err
+		return nil, generic.ClientError(fmt.Sprintf("Error while building pageSize parameter to fetch events: %s", err.Error()), "FindEvents")
 	}
 
-	return e.getCommon(fmt.Sprintf("%s?%s", e.basePath, queryParams))
+	return e.getCommon(fmt.Sprintf("%s?%s", e.basePath, queryParamsValues.Encode()))
 }
 
 func (e *events) NextPage(c *EventCollection) (*EventCollection, *generic.Error) {
@@ -182,6 +234,19 @@ func (e *events) PreviousPage(c *EventCollection) (*EventCollection, *generic.Er
 
 // -- internal
 
+func (e *events) update(eventId string, bytes []byte, operation string) (*Event, *generic.Error) {
+	path := fmt.Sprintf("%s/%s", e.basePath, url.QueryEscape(eventId))
+	body, status, err := e.client.Put(path, bytes, generic.AcceptAndContentTypeHeader(EVENT_TYPE, EVENT_TYPE))
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while updating an event: %s", err.Error()), operation)
+	}
+	if status != http.StatusOK {
+		return nil, generic.CreateErrorFromResponse(body, status)
+	}
+
+	return parseEventResponse(body)
+}
+
 func parseEventResponse(body []byte) (*Event, *generic.Error) {
 	var result Event
 	if len(body) > 0 {
@@ -221,7 +286,7 @@ func (e *events) getPage(reference string) (*EventCollection, *generic.Error) {
 }
 
 func (e *events) getCommon(path string) (*EventCollection, *generic.Error) {
-	body, status, err := e.client.Get(path, generic.EmptyHeader())
+	body, status, err := e.client.Get(path, generic.AcceptHeader(EVENT_COLLECTION_TYPE))
 
 	if status != http.StatusOK {
 		return nil, generic.CreateErrorFromResponse(body, status)
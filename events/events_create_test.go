@@ -38,7 +38,7 @@ func TestEvents_Create_Event_Success_SendsData(t *testing.T) {
 	}
 
 	header := requestCapture.Header.Get("Accept")
-	want := "application/vnd.com.nsn.cumulocity.eventApi+json"
+	want := "application/vnd.com.nsn.cumulocity.event+json"
 	if header != want {
 		t.Errorf("CreateEvent() accent header = %v, want %v", header, want)
 	}
@@ -0,0 +1,87 @@
+package events
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEvents_Delete_Many_Success(t *testing.T) {
+	var capturedUrl string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUrl = r.URL.String()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// given: A test server
+	defer ts.Close()
+
+	// and: the api as system under test
+	api := buildEventsApi(ts.URL)
+
+	err := api.Delete(&EventFilter{Source: deviceId, Type: "TestEvent"})
+
+	if err != nil {
+		t.Fatalf("Delete() got an unexpected error: %s", err.Error())
+	}
+
+	expectedUrl := "/event/events?source=" + deviceId + "&type=TestEvent"
+	if capturedUrl != expectedUrl {
+		t.Errorf("Delete() target URL = %s - expected %s", capturedUrl, expectedUrl)
+	}
+}
+
+func TestEvents_Delete_Many_WithoutFilter(t *testing.T) {
+	// given: the api as system under test
+	api := buildEventsApi("")
+
+	for _, filter := range []*EventFilter{nil, {}, {Revert: true}} {
+		err := api.Delete(filter)
+
+		if err == nil || !strings.Contains(err.Message, "No filter set") {
+			t.Errorf("Delete() expected error with appropriate message. Got: %v", err)
+		}
+	}
+}
+
+func TestEvents_Delete_All_Success(t *testing.T) {
+	var capturedUrl string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUrl = r.URL.String()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// given: A test server
+	defer ts.Close()
+
+	// and: the api as system under test
+	api := buildEventsApi(ts.URL)
+
+	err := api.DeleteAll()
+
+	if err != nil {
+		t.Fatalf("DeleteAll() got an unexpected error: %s", err.Error())
+	}
+
+	if capturedUrl != "/event/events" {
+		t.Errorf("DeleteAll(): Wrong target URL: %s - expected %s", capturedUrl, "/event/events")
+	}
+}
+
+func TestEvents_Delete_All_BadRequest(t *testing.T) {
+	// given: A test server
+	ts := buildHttpServer(http.StatusBadRequest, "")
+	defer ts.Close()
+
+	// and: the api as system under test
+	api := buildEventsApi(ts.URL)
+
+	err := api.DeleteAll()
+
+	if err == nil || !strings.Contains(err.ErrorType, "400") {
+		t.Errorf("DeleteAll() expected error on 400 - bad request. Got: %v", err)
+	}
+}
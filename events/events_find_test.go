@@ -24,83 +24,108 @@ func TestEvents_FindWithFilter(t *testing.T) {
 
 	tests := []struct {
 		name          string
-		query         EventQuery
+		query         EventFilter
 		expectedQuery string
 	}{
 		{
 			"All",
-			EventQuery{},
-			"",
+			EventFilter{},
+			"pageSize=1",
 		},
 		{
 			"ForDateAndFragmentType",
-			EventQuery{DateFrom: &dateFrom, DateTo: &dateTo, FragmentType: "FragmentType_1"},
-			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&fragmentType=FragmentType_1",
+			EventFilter{DateFrom: &dateFrom, DateTo: &dateTo, FragmentType: "FragmentType_1"},
+			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&fragmentType=FragmentType_1&pageSize=1",
 		},
 		{
 			"ForFragmentTypeAndType",
-			EventQuery{FragmentType: "FragmentType_1", Type: "Type_1"},
-			"fragmentType=FragmentType_1&type=Type_1",
+			EventFilter{FragmentType: "FragmentType_1", Type: "Type_1"},
+			"fragmentType=FragmentType_1&pageSize=1&type=Type_1",
 		},
 		{
 			"ForSourceAndType",
-			EventQuery{Source: "4711", Type: "Type_1"},
-			"source=4711&type=Type_1",
+			EventFilter{Source: "4711", Type: "Type_1"},
+			"pageSize=1&source=4711&type=Type_1",
 		},
 		{
 			"ForTimeAndType",
-			EventQuery{DateFrom: &dateFrom, DateTo: &dateTo, Type: "Type_1"},
-			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&type=Type_1",
+			EventFilter{DateFrom: &dateFrom, DateTo: &dateTo, Type: "Type_1"},
+			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&pageSize=1&type=Type_1",
 		},
 		{
 			"ForDateAndFragmentTypeAndType",
-			EventQuery{DateFrom: &dateFrom, DateTo: &dateTo, FragmentType: "FragmentType_1", Type: "Type_1"},
-			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&fragmentType=FragmentType_1&type=Type_1",
+			EventFilter{DateFrom: &dateFrom, DateTo: &dateTo, FragmentType: "FragmentType_1", Type: "Type_1"},
+			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&fragmentType=FragmentType_1&pageSize=1&type=Type_1",
 		},
 		{
 			"ForFragmentType",
-			EventQuery{FragmentType: "FragmentType_1"},
-			"fragmentType=FragmentType_1",
+			EventFilter{FragmentType: "FragmentType_1"},
+			"fragmentType=FragmentType_1&pageSize=1",
 		},
 		{
 			"ForSource",
-			EventQuery{Source: "4711"},
-			"source=4711",
+			EventFilter{Source: "4711"},
+			"pageSize=1&source=4711",
 		},
 		{
 			"ForSourceAndTimeAndType",
-			EventQuery{Source: "4711", DateFrom: &dateFrom, DateTo: &dateTo, Type: "Type_1"},
-			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&source=4711&type=Type_1",
+			EventFilter{Source: "4711", DateFrom: &dateFrom, DateTo: &dateTo, Type: "Type_1"},
+			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&pageSize=1&source=4711&type=Type_1",
 		},
 		{
 			"ForTime",
-			EventQuery{DateFrom: &dateFrom, DateTo: &dateTo},
-			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z",
+			EventFilter{DateFrom: &dateFrom, DateTo: &dateTo},
+			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&pageSize=1",
 		},
 		{
 			"ForSourceAndDateAndFragmentTypeAndType",
-			EventQuery{Source: "4711", DateFrom: &dateFrom, DateTo: &dateTo, FragmentType: "FragmentType_1", Type: "Type_1"},
-			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&fragmentType=FragmentType_1&source=4711&type=Type_1",
+			EventFilter{Source: "4711", DateFrom: &dateFrom, DateTo: &dateTo, FragmentType: "FragmentType_1", Type: "Type_1"},
+			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&fragmentType=FragmentType_1&pageSize=1&source=4711&type=Type_1",
 		},
 		{
 			"ForSourceAndFragmentTypeAndType",
-			EventQuery{Source: "4711", FragmentType: "FragmentType_1", Type: "Type_1"},
-			"fragmentType=FragmentType_1&source=4711&type=Type_1",
+			EventFilter{Source: "4711", FragmentType: "FragmentType_1", Type: "Type_1"},
+			"fragmentType=FragmentType_1&pageSize=1&source=4711&type=Type_1",
 		},
 		{
 			"ForType",
-			EventQuery{Type: "Type_1"},
-			"type=Type_1",
+			EventFilter{Type: "Type_1"},
+			"pageSize=1&type=Type_1",
 		},
 		{
 			"ForSourceAndFragmentType",
-			EventQuery{Source: "4711", FragmentType: "FragmentType_1"},
-			"fragmentType=FragmentType_1&source=4711",
+			EventFilter{Source: "4711", FragmentType: "FragmentType_1"},
+			"fragmentType=FragmentType_1&pageSize=1&source=4711",
 		},
 		{
 			"ForSourceAndTime",
-			EventQuery{Source: "4711", DateFrom: &dateFrom, DateTo: &dateTo},
-			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&source=4711",
+			EventFilter{Source: "4711", DateFrom: &dateFrom, DateTo: &dateTo},
+			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&pageSize=1&source=4711",
+		},
+		{
+			"ForCreationTime",
+			EventFilter{CreatedFrom: &dateFrom, CreatedTo: &dateTo},
+			"createdFrom=2020-06-01T01%3A00%3A00Z&createdTo=2020-06-30T01%3A00%3A00Z&pageSize=1",
+		},
+		{
+			"ForLastUpdated",
+			EventFilter{LastUpdatedFrom: &dateFrom, LastUpdatedTo: &dateTo},
+			"lastUpdatedFrom=2020-06-01T01%3A00%3A00Z&lastUpdatedTo=2020-06-30T01%3A00%3A00Z&pageSize=1",
+		},
+		{
+			"ForFragmentValue",
+			EventFilter{FragmentType: "c8y_Door", FragmentValue: "open"},
+			"fragmentType=c8y_Door&fragmentValue=open&pageSize=1",
+		},
+		{
+			"ForSourceHierarchy",
+			EventFilter{Source: "4711", WithSourceAssets: true, WithSourceDevices: true},
+			"pageSize=1&source=4711&withSourceAssets=true&withSourceDevices=true",
+		},
+		{
+			"Reverted",
+			EventFilter{Source: "4711", Revert: true},
+			"pageSize=1&revert=true&source=4711",
 		},
 	}
 
@@ -108,7 +133,7 @@ func TestEvents_FindWithFilter(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			api.Find(tt.query)
+			api.Find(&tt.query, 1)
 			cUrl, err := url.Parse(capturedUrl)
 
 			if err != nil {
@@ -122,6 +147,60 @@ func TestEvents_FindWithFilter(t *testing.T) {
 	}
 }
 
+func TestEvents_Find_WithInvalidFilter(t *testing.T) {
+	dateFrom, _ := time.Parse(time.RFC3339, "2020-06-01T01:00:00.00Z")
+	dateTo, _ := time.Parse(time.RFC3339, "2020-06-30T01:00:00.00Z")
+
+	tests := []struct {
+		name          string
+		query         EventFilter
+		expectedError string
+	}{
+		{
+			"FragmentValue",
+			EventFilter{FragmentValue: "open"},
+			"when 'FragmentValue' parameter is defined also FragmentType must be set",
+		},
+		{
+			"WithSourceAssets",
+			EventFilter{WithSourceAssets: true},
+			"when 'WithSourceAssets' parameter is defined also Source must be set",
+		},
+		{
+			"WithSourceDevices",
+			EventFilter{WithSourceDevices: true},
+			"when 'WithSourceDevices' parameter is defined also Source must be set",
+		},
+		{
+			"DateWindow",
+			EventFilter{DateFrom: &dateTo, DateTo: &dateFrom},
+			"'dateFrom' must not be after 'dateTo'",
+		},
+		{
+			"CreationTimeWindow",
+			EventFilter{CreatedFrom: &dateTo, CreatedTo: &dateFrom},
+			"'createdFrom' must not be after 'createdTo'",
+		},
+		{
+			"LastUpdatedWindow",
+			EventFilter{LastUpdatedFrom: &dateTo, LastUpdatedTo: &dateFrom},
+			"'lastUpdatedFrom' must not be after 'lastUpdatedTo'",
+		},
+	}
+
+	api := buildEventsApi("test.url")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := api.Find(&tt.query, 1)
+
+			if err == nil || !strings.Contains(err.Message, tt.expectedError) {
+				t.Errorf("Error in Find(): [%v], expected: [%v]", err, tt.expectedError)
+			}
+		})
+	}
+}
+
 func TestEvents_Find_HandlesPageSize(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -129,7 +208,7 @@ func TestEvents_Find_HandlesPageSize(t *testing.T) {
 		errExpected bool
 	}{
 		{"Negative", -1, true},
-		{"Zero", 0, false},
+		{"Zero", 0, true},
 		{"Max", 2000, false},
 		{"too large", 2001, true},
 		{"in range", 10, false},
@@ -147,11 +226,10 @@ func TestEvents_Find_HandlesPageSize(t *testing.T) {
 	api := buildEventsApi(ts.URL)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			query := EventQuery{
-				Source:   deviceId,
-				PageSize: tt.pageSize,
+			query := EventFilter{
+				Source: deviceId,
 			}
-			_, err := api.Find(query)
+			_, err := api.Find(&query, tt.pageSize)
 
 			if tt.errExpected {
 				if err == nil {
@@ -182,7 +260,7 @@ func TestEvents_ReturnsCollection(t *testing.T) {
 
 	api := buildEventsApi(ts.URL)
 
-	collection, err := api.Find(EventQuery{})
+	collection, err := api.Find(&EventFilter{}, 5)
 
 	if err != nil {
 		t.Fatalf("Find() - Error given but no expected")
@@ -206,7 +284,7 @@ func TestEvents_ReturnsCollection_CustomElements(t *testing.T) {
 
 	api := buildEventsApi(ts.URL)
 
-	collection, err := api.Find(EventQuery{})
+	collection, err := api.Find(&EventFilter{}, 5)
 
 	if err != nil {
 		t.Fatalf("Find() - Error given but no expected")
@@ -251,7 +329,7 @@ func TestEvents_FindReturnsError(t *testing.T) {
 
 	api := buildEventsApi(ts.URL)
 
-	_, err := api.Find(EventQuery{})
+	_, err := api.Find(&EventFilter{}, 5)
 
 	if err == nil {
 		t.Fatalf("Find() - Error expected")
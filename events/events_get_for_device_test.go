@@ -85,7 +85,7 @@ func TestEvents_GetForDevice_HandlesPageSize(t *testing.T) {
 		errExpected bool
 	}{
 		{"Negative", -1, true},
-		{"Zero", 0, false},
+		{"Zero", 0, true},
 		{"Max", 2000, false},
 		{"too large", 2001, true},
 		{"in range", 10, false},
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEvents_UpdateChanges_SendsOnlyChanges(t *testing.T) {
+	// given: A test server
+	ts := updateEventHttpServer(200)
+	defer ts.Close()
+
+	// and: the api as system under test
+	api := buildEventsApi(ts.URL)
+
+	// and: An event with a changed text and a removed fragment
+	before := &Event{Id: eventId, Type: "TestEvent", Text: "Hello", Time: eventTime,
+		AdditionalFields: map[string]interface{}{"custom1": "foo", "custom2": "bar"}}
+	after := &Event{Id: eventId, Type: "TestEvent", Text: "World", Time: eventTime,
+		AdditionalFields: map[string]interface{}{"custom1": "foo"}}
+
+	_, err := api.UpdateChanges(before, after)
+
+	if err != nil {
+		t.Fatalf("UpdateChanges() got an unexpected error: %s", err.Error())
+	}
+
+	if requestCapture.URL.Path != "/event/events/"+eventId {
+		t.Errorf("UpdateChanges() url = %s, want /event/events/%s", requestCapture.URL.Path, eventId)
+	}
+
+	want := `{"custom2":null,"text":"World"}`
+	if string(*bodyCapture) != want {
+		t.Errorf("UpdateChanges() body = %s, want %s", *bodyCapture, want)
+	}
+}
+
+func TestEvents_UpdateChanges_WithoutReadOnlyFields(t *testing.T) {
+	ts := updateEventHttpServer(200)
+	defer ts.Close()
+
+	api := buildEventsApi(ts.URL)
+
+	// and: An event with changed read-only fields and a new fragment
+	before := &Event{Id: eventId, Type: "TestEvent", Text: "Hello", Time: eventTime, Source: Source{Id: "1"}}
+	after := &Event{Id: "4711", Type: "OtherEvent", Text: "Hello", Time: eventTime.Add(time.Hour), Source: Source{Id: "2"},
+		AdditionalFields: map[string]interface{}{"custom1": "foo"}}
+
+	_, err := api.UpdateChanges(before, after)
+
+	if err != nil {
+		t.Fatalf("UpdateChanges() got an unexpected error: %s", err.Error())
+	}
+	want := `{"custom1":"foo"}`
+	if string(*bodyCapture) != want {
+		t.Errorf("UpdateChanges() body = %s, want %s", *bodyCapture, want)
+	}
+}
+
+func TestEvents_UpdateChanges_NoChanges(t *testing.T) {
+	// given: A test server, which must not be called
+	called := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		called = true
+	}))
+	defer ts.Close()
+
+	api := buildEventsApi(ts.URL)
+	before := &Event{Id: eventId, Text: "Hello"}
+
+	event, err := api.UpdateChanges(before, &Event{Id: eventId, Text: "Hello"})
+
+	if err != nil {
+		t.Fatalf("UpdateChanges() got an unexpected error: %s", err.Error())
+	}
+	if called {
+		t.Errorf("UpdateChanges() sent a request without changes")
+	}
+	if event != before {
+		t.Errorf("UpdateChanges() event = %v, want %v", event, before)
+	}
+}
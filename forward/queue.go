@@ -0,0 +1,658 @@
+/*
+Package forward provides a disk-backed store-and-forward queue for devices with an unreliable connection.
+Measurements, events and alarms are queued durably on local disk and forwarded to cumulocity in the order of
+queuing, once the connection is available again. The queue survives restarts of the process.
+
+The queue is stored in a directory as append-only segment files. Each record of a segment is prefixed by its
+length and a CRC-32 checksum, so a record partly written on a crash is detected and truncated on `Open`.
+A corrupt record is truncated with all records after it and reported as dropped. A record, which is found corrupt
+while forwarding, drops the rest of its segment, so forwarding does not stall.
+The sequence number of the last forwarded record is stored in a cursor file. Segments, which are forwarded
+completely, are deleted.
+*/
+package forward
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	QueueClosedErr = errors.New("queue is closed")
+	QueueFullErr   = errors.New("queue is full")
+
+	corruptRecordErr = errors.New("corrupt queue record")
+)
+
+// The kind of a queued entry.
+type Kind string
+
+const (
+	MEASUREMENT Kind = "MEASUREMENT"
+	EVENT       Kind = "EVENT"
+	ALARM       Kind = "ALARM"
+)
+
+// When the queue syncs appended records to disk.
+type SyncPolicy int
+
+const (
+	// Every record is synced before `Enqueue` returns. Safest, but slowest.
+	SyncAlways SyncPolicy = iota
+	// Records are synced, when the last sync is older than the sync interval, and on `Close`.
+	SyncInterval
+	// Syncing is left to the operating system.
+	SyncNever
+)
+
+// What happens, when a record would exceed the max disk usage.
+type DropPolicy int
+
+const (
+	// The oldest segments are dropped until the record fits.
+	DropOldest DropPolicy = iota
+	// The new record is rejected with `QueueFullErr`.
+	RejectNew
+)
+
+const (
+	segmentSuffix = ".seg"
+	cursorFile    = "cursor"
+	headerSize    = 8
+)
+
+/*
+Options configures a Queue. Zero values are replaced by the defaults.
+*/
+type Options struct {
+	SegmentSize  int64         // The size of a segment file, before a new one is started. Default: 8 MiB
+	MaxDiskBytes int64         // The max disk usage of all segments. 0 means unlimited
+	DropPolicy   DropPolicy    // Default: DropOldest
+	SyncPolicy   SyncPolicy    // Default: SyncAlways
+	SyncInterval time.Duration // The interval for SyncInterval. Default: 1s
+	// Is called with the number of records dropped by the drop policy, as undeliverable while forwarding or as
+	// corrupt.
+	OnDrop func(count int, reason string)
+}
+
+/*
+Entry is a queued measurement, event or alarm. The payload is the JSON of the entity to create.
+*/
+type Entry struct {
+	Sequence uint64          `json:"seq"`
+	Kind     Kind            `json:"kind"`
+	Time     time.Time       `json:"time"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+/*
+Queue is a durable FIFO queue of entries to forward. A Queue is safe for concurrent use, but a directory must
+only be opened by one Queue at a time.
+*/
+type Queue struct {
+	dir     string
+	options Options
+
+	mutex    sync.Mutex
+	segments []*segment // ascending, the last one is the active segment
+	active   *os.File
+	nextSeq  uint64
+	acked    uint64 // the sequence of the last forwarded or dropped entry
+	read     readPosition
+	lastSync time.Time
+	closed   bool
+
+	forwardMutex sync.Mutex // only one forwarding at a time
+}
+
+type segment struct {
+	path  string
+	first uint64
+	last  uint64 // 0, if the segment is empty
+	size  int64
+}
+
+type readPosition struct {
+	segment *segment
+	offset  int64
+}
+
+// Opens the queue in the directory, which is created if necessary. Records partly written on a crash are truncated.
+func Open(dir string, options Options) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %s", err.Error())
+	}
+
+	queue := &Queue{dir: dir, options: optionsWithDefaults(options), lastSync: time.Now()}
+	if err := queue.load(); err != nil {
+		return nil, err
+	}
+	return queue, nil
+}
+
+// Appends an entry of the kind with the JSON payload to the queue.
+func (queue *Queue) Enqueue(kind Kind, payload []byte) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.closed {
+		return QueueClosedErr
+	}
+
+	record, err := encodeRecord(Entry{Sequence: queue.nextSeq, Kind: kind, Time: time.Now(), Payload: payload})
+	if err != nil {
+		return err
+	}
+
+	if err := queue.ensureCapacity(int64(len(record))); err != nil {
+		return err
+	}
+	if err := queue.ensureActiveSegment(int64(len(record))); err != nil {
+		return err
+	}
+
+	if _, err := queue.active.Write(record); err != nil {
+		return fmt.Errorf("failed to append to queue: %s", err.Error())
+	}
+	current := queue.segments[len(queue.segments)-1]
+	if current.first == 0 {
+		current.first = queue.nextSeq
+	}
+	current.last = queue.nextSeq
+	current.size += int64(len(record))
+	queue.nextSeq++
+
+	return queue.syncIfRequired()
+}
+
+// Returns the number of entries waiting to be forwarded.
+func (queue *Queue) Len() int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	// Counted by segment, because corrupt records leave gaps in the sequence
+	count := 0
+	for _, seg := range queue.segments {
+		first := seg.first
+		if first <= queue.acked {
+			first = queue.acked + 1
+		}
+		if seg.last >= first {
+			count += int(seg.last - first + 1)
+		}
+	}
+	return count
+}
+
+// Returns the disk usage of all segments in bytes. It contains forwarded entries of segments not yet deleted.
+func (queue *Queue) Size() int64 {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	return queue.totalSize()
+}
+
+/*
+Forwards the queued entries in order by passing them to the sender, until the queue is empty or sending fails.
+An entry is removed from the queue after it is sent successfully. If the sender returns a `Permanent` error,
+the entry is dropped as undeliverable and forwarding continues. On any other error forwarding stops and the
+entry is kept to be forwarded again.
+Returns the number of forwarded entries and the error that stopped forwarding.
+*/
+func (queue *Queue) Forward(send Sender) (int, error) {
+	queue.forwardMutex.Lock()
+	defer queue.forwardMutex.Unlock()
+
+	forwarded := 0
+	for {
+		entry, next, err := queue.peek()
+		if err != nil || entry == nil {
+			return forwarded, err
+		}
+
+		sendErr := send(*entry)
+		var permanent *permanentError
+		if sendErr != nil && !errors.As(sendErr, &permanent) {
+			return forwarded, sendErr
+		}
+
+		if err := queue.ack(entry.Sequence, next); err != nil {
+			return forwarded, err
+		}
+		if sendErr != nil {
+			queue.dropped(1, fmt.Sprintf("undeliverable: %s", sendErr.Error()))
+		} else {
+			forwarded++
+		}
+	}
+}
+
+// Syncs and closes the queue. Further calls to Enqueue fail.
+func (queue *Queue) Close() error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.closed {
+		return nil
+	}
+	queue.closed = true
+
+	if queue.active == nil {
+		return nil
+	}
+	if err := queue.active.Sync(); err != nil {
+		_ = queue.active.Close()
+		return fmt.Errorf("failed to sync queue: %s", err.Error())
+	}
+	return queue.active.Close()
+}
+
+// -- internal
+
+func optionsWithDefaults(options Options) Options {
+	if options.SegmentSize <= 0 {
+		options.SegmentSize = 8 * 1024 * 1024
+	}
+	if options.SyncInterval <= 0 {
+		options.SyncInterval = time.Second
+	}
+	return options
+}
+
+// Reads the cursor and all segments. Truncates incomplete and corrupt records and removes completely forwarded segments.
+func (queue *Queue) load() error {
+	cursor, err := ioutil.ReadFile(filepath.Join(queue.dir, cursorFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read queue cursor: %s", err.Error())
+	}
+	if len(cursor) > 0 {
+		queue.acked, err = strconv.ParseUint(strings.TrimSpace(string(cursor)), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid queue cursor: %s", err.Error())
+		}
+	}
+	queue.nextSeq = queue.acked + 1
+
+	paths, err := filepath.Glob(filepath.Join(queue.dir, "*"+segmentSuffix))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		seg, dropped, err := scanSegment(path, queue.acked)
+		if err != nil {
+			return err
+		}
+		queue.dropped(dropped, fmt.Sprintf("corrupt record in %s", filepath.Base(path)))
+		if seg.last == 0 || seg.last <= queue.acked {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove queue segment: %s", err.Error())
+			}
+			continue
+		}
+		queue.segments = append(queue.segments, seg)
+		queue.nextSeq = seg.last + 1
+	}
+
+	if len(queue.segments) > 0 {
+		path := queue.segments[len(queue.segments)-1].path
+		queue.active, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open queue segment: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+/*
+Reads all records of a segment file and truncates it after the last valid record. A torn header at the end is
+the result of a crash while appending and is truncated silently. A corrupt record, e.g. with a checksum mismatch
+or a length exceeding the file, drops itself and all records after it; the number of dropped, not yet forwarded
+records is returned.
+*/
+func scanSegment(path string, acked uint64) (*segment, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open queue segment: %s", err.Error())
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	seg := &segment{path: path}
+	dropped := 0
+	reader := bufio.NewReader(file)
+	for {
+		entry, size, err := readRecord(reader, info.Size()-seg.size)
+		if err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				dropped = countDropped(reader, size, info.Size()-seg.size, seg.last, acked)
+			}
+			break
+		}
+		if seg.first == 0 {
+			seg.first = entry.Sequence
+		}
+		seg.last = entry.Sequence
+		seg.size += size
+	}
+
+	if info.Size() > seg.size {
+		if err := os.Truncate(path, seg.size); err != nil {
+			return nil, 0, fmt.Errorf("failed to truncate incomplete queue record: %s", err.Error())
+		}
+	}
+	return seg, dropped, nil
+}
+
+/*
+Counts the corrupt record, which was just read, and the records after it, whose sequence is after `acked`.
+`size` is the size of the corrupt record or 0, if its length is corrupt and the next record can not be found.
+`remaining` is the number of bytes from the start of the corrupt record to the end of the file.
+The sequence of an unreadable record is assumed to follow the previous one.
+*/
+func countDropped(reader io.Reader, size int64, remaining int64, previous uint64, acked uint64) int {
+	count := 0
+	for sequence := previous + 1; ; sequence++ {
+		if sequence > acked {
+			count++
+		}
+		if size == 0 {
+			return count
+		}
+
+		remaining -= size
+		entry, next, err := readRecord(reader, remaining)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return count
+		}
+		size = next
+		if err == nil {
+			sequence = entry.Sequence - 1
+		}
+	}
+}
+
+func (queue *Queue) totalSize() int64 {
+	var size int64
+	for _, seg := range queue.segments {
+		size += seg.size
+	}
+	return size
+}
+
+// Applies the drop policy, if the record would exceed the max disk usage.
+func (queue *Queue) ensureCapacity(recordSize int64) error {
+	max := queue.options.MaxDiskBytes
+	if max <= 0 || queue.totalSize()+recordSize <= max {
+		return nil
+	}
+	if recordSize > max || queue.options.DropPolicy == RejectNew {
+		return QueueFullErr
+	}
+
+	for len(queue.segments) > 0 && queue.totalSize()+recordSize > max {
+		if len(queue.segments) == 1 {
+			if err := queue.rotate(); err != nil {
+				return err
+			}
+		}
+		if err := queue.dropOldestSegment("max disk usage exceeded"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (queue *Queue) dropOldestSegment(reason string) error {
+	oldest := queue.segments[0]
+	if err := os.Remove(oldest.path); err != nil {
+		return fmt.Errorf("failed to remove queue segment: %s", err.Error())
+	}
+	queue.segments = queue.segments[1:]
+
+	if oldest.last > queue.acked {
+		first := oldest.first
+		if first <= queue.acked {
+			first = queue.acked + 1
+		}
+		count := int(oldest.last - first + 1)
+		if err := queue.writeCursor(oldest.last); err != nil {
+			return err
+		}
+		queue.dropped(count, reason)
+	}
+	return nil
+}
+
+// Starts a new segment, if there is none or the record does not fit into the active one.
+func (queue *Queue) ensureActiveSegment(recordSize int64) error {
+	if len(queue.segments) > 0 {
+		current := queue.segments[len(queue.segments)-1]
+		if current.size == 0 || current.size+recordSize <= queue.options.SegmentSize {
+			return nil
+		}
+	}
+	return queue.rotate()
+}
+
+// Closes the active segment and starts a new one, named by the next sequence.
+func (queue *Queue) rotate() error {
+	if queue.active != nil {
+		if err := queue.active.Sync(); err != nil {
+			return fmt.Errorf("failed to sync queue segment: %s", err.Error())
+		}
+		if err := queue.active.Close(); err != nil {
+			return err
+		}
+		queue.active = nil
+	}
+
+	path := filepath.Join(queue.dir, fmt.Sprintf("%020d%s", queue.nextSeq, segmentSuffix))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create queue segment: %s", err.Error())
+	}
+	queue.active = file
+	queue.segments = append(queue.segments, &segment{path: path})
+	return nil
+}
+
+func (queue *Queue) syncIfRequired() error {
+	switch queue.options.SyncPolicy {
+	case SyncAlways:
+	case SyncInterval:
+		if time.Since(queue.lastSync) < queue.options.SyncInterval {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	if err := queue.active.Sync(); err != nil {
+		return fmt.Errorf("failed to sync queue: %s", err.Error())
+	}
+	queue.lastSync = time.Now()
+	return nil
+}
+
+/*
+Returns the oldest entry not yet forwarded and the read offset behind it or nil, if the queue is empty.
+Segments read completely are deleted.
+*/
+func (queue *Queue) peek() (*Entry, int64, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	for len(queue.segments) > 0 {
+		seg := queue.segments[0]
+		if queue.read.segment != seg {
+			queue.read = readPosition{segment: seg}
+		}
+
+		entry, next, err := queue.readFrom(seg, queue.read.offset)
+		if errors.Is(err, corruptRecordErr) {
+			// The rest of the segment can not be read. It is dropped, so forwarding does not stall.
+			if len(queue.segments) == 1 {
+				if err := queue.rotate(); err != nil {
+					return nil, 0, err
+				}
+			}
+			if err := queue.dropOldestSegment(fmt.Sprintf("corrupt record in %s", filepath.Base(seg.path))); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if entry == nil {
+			if len(queue.segments) == 1 {
+				return nil, 0, nil
+			}
+			if err := os.Remove(seg.path); err != nil {
+				return nil, 0, fmt.Errorf("failed to remove queue segment: %s", err.Error())
+			}
+			queue.segments = queue.segments[1:]
+			continue
+		}
+		if entry.Sequence <= queue.acked {
+			queue.read.offset = next
+			continue
+		}
+		return entry, next, nil
+	}
+	return nil, 0, nil
+}
+
+// Reads the record at the offset. Returns nil, if there is no complete record at the offset.
+func (queue *Queue) readFrom(seg *segment, offset int64) (*Entry, int64, error) {
+	if offset >= seg.size {
+		return nil, offset, nil
+	}
+
+	file, err := os.Open(seg.path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open queue segment: %s", err.Error())
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	entry, size, err := readRecord(bufio.NewReader(file), seg.size-offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %s at %d: %s", corruptRecordErr, seg.path, offset, err.Error())
+	}
+	return entry, offset + size, nil
+}
+
+// Marks the entry as forwarded.
+func (queue *Queue) ack(sequence uint64, next int64) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if len(queue.segments) > 0 && queue.read.segment == queue.segments[0] {
+		queue.read.offset = next
+	}
+	if sequence <= queue.acked {
+		return nil
+	}
+	return queue.writeCursor(sequence)
+}
+
+// Stores the sequence of the last forwarded or dropped entry atomically.
+func (queue *Queue) writeCursor(sequence uint64) error {
+	path := filepath.Join(queue.dir, cursorFile)
+	tmp := path + ".tmp"
+
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write queue cursor: %s", err.Error())
+	}
+	_, err = file.WriteString(strconv.FormatUint(sequence, 10))
+	if err == nil && queue.options.SyncPolicy == SyncAlways {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write queue cursor: %s", err.Error())
+	}
+
+	queue.acked = sequence
+	return nil
+}
+
+func (queue *Queue) dropped(count int, reason string) {
+	if queue.options.OnDrop != nil && count > 0 {
+		queue.options.OnDrop(count, reason)
+	}
+}
+
+// A record is the length and CRC-32 of the entry JSON followed by the JSON itself.
+func encodeRecord(entry Entry) ([]byte, error) {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode queue entry: %s", err.Error())
+	}
+
+	record := make([]byte, headerSize+len(body))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(body))
+	copy(record[headerSize:], body)
+	return record, nil
+}
+
+/*
+Reads a record and returns its entry and size. Returns an error on incomplete or corrupt records. The size is also
+returned for records with a corrupt body, so the next record can be read. A length exceeding the `remaining` bytes
+of the segment is corrupt and nothing is allocated for it.
+*/
+func readRecord(reader io.Reader, remaining int64) (*Entry, int64, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, 0, err
+	}
+
+	length := int64(binary.BigEndian.Uint32(header[0:4]))
+	if length > remaining-headerSize {
+		return nil, 0, errors.New("record length exceeds the segment")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, 0, err
+	}
+	size := headerSize + length
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, size, errors.New("checksum mismatch")
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return nil, size, err
+	}
+	return &entry, size, nil
+}
@@ -0,0 +1,368 @@
+package forward
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempQueueDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gomulocity-forward")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	return dir
+}
+
+func openQueue(t *testing.T, dir string, options Options) *Queue {
+	queue, err := Open(dir, options)
+	if err != nil {
+		t.Fatalf("Open() got an unexpected error: %s", err.Error())
+	}
+	return queue
+}
+
+func enqueue(t *testing.T, queue *Queue, payloads ...string) {
+	for _, payload := range payloads {
+		if err := queue.Enqueue(EVENT, []byte(fmt.Sprintf(`"%s"`, payload))); err != nil {
+			t.Fatalf("Enqueue() got an unexpected error: %s", err.Error())
+		}
+	}
+}
+
+// Returns a sender collecting the payloads. It fails with `failWith` after `max` entries.
+func collectingSender(payloads *[]string, max int, failWith error) Sender {
+	return func(entry Entry) error {
+		if len(*payloads) >= max {
+			return failWith
+		}
+		*payloads = append(*payloads, string(entry.Payload))
+		return nil
+	}
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	return files
+}
+
+func TestQueue_ForwardsInOrder(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	queue := openQueue(t, dir, Options{SegmentSize: 100})
+	defer queue.Close()
+
+	enqueue(t, queue, "a", "b", "c", "d")
+	if queue.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", queue.Len())
+	}
+	if len(segmentFiles(t, dir)) < 2 {
+		t.Errorf("Expected the queue to rotate segments, got %v", segmentFiles(t, dir))
+	}
+
+	var payloads []string
+	count, err := queue.Forward(collectingSender(&payloads, 100, nil))
+
+	if err != nil || count != 4 {
+		t.Fatalf("Forward() = %d, %v - want 4 entries", count, err)
+	}
+	if fmt.Sprint(payloads) != `["a" "b" "c" "d"]` {
+		t.Errorf("Forward() payloads = %v, want a, b, c, d in order", payloads)
+	}
+	if queue.Len() != 0 || len(segmentFiles(t, dir)) != 1 {
+		t.Errorf("Forward() left %d entries and segments %v, want only the active segment", queue.Len(), segmentFiles(t, dir))
+	}
+
+	count, err = queue.Forward(collectingSender(&payloads, 100, nil))
+	if err != nil || count != 0 {
+		t.Errorf("Forward() of an empty queue = %d, %v", count, err)
+	}
+}
+
+func TestQueue_SurvivesRestart(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	queue := openQueue(t, dir, Options{SyncPolicy: SyncInterval})
+	enqueue(t, queue, "a", "b", "c")
+
+	offline := errors.New("offline")
+	var payloads []string
+	count, err := queue.Forward(collectingSender(&payloads, 1, offline))
+	if count != 1 || err != offline {
+		t.Fatalf("Forward() = %d, %v - want 1 entry and the sender error", count, err)
+	}
+	_ = queue.Close()
+
+	if err := queue.Enqueue(EVENT, []byte(`"x"`)); err != QueueClosedErr {
+		t.Errorf("Enqueue() after close = %v, want %v", err, QueueClosedErr)
+	}
+
+	queue = openQueue(t, dir, Options{})
+	defer queue.Close()
+
+	if queue.Len() != 2 {
+		t.Errorf("Len() after restart = %d, want 2", queue.Len())
+	}
+	enqueue(t, queue, "d")
+
+	var sequences []uint64
+	_, err = queue.Forward(func(entry Entry) error {
+		sequences = append(sequences, entry.Sequence)
+		payloads = append(payloads, string(entry.Payload))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Forward() got an unexpected error: %s", err.Error())
+	}
+	if fmt.Sprint(payloads) != `["a" "b" "c" "d"]` || fmt.Sprint(sequences) != "[2 3 4]" {
+		t.Errorf("Forward() after restart = %v %v, want b, c, d with sequences 2, 3, 4", payloads, sequences)
+	}
+}
+
+func TestQueue_TruncatesIncompleteRecords(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	queue := openQueue(t, dir, Options{})
+	enqueue(t, queue, "a", "b")
+	_ = queue.Close()
+
+	// A crash while appending a record
+	segment := segmentFiles(t, dir)[0]
+	file, _ := os.OpenFile(segment, os.O_WRONLY|os.O_APPEND, 0600)
+	_, _ = file.Write([]byte{0, 0, 0, 42, 1, 2})
+	_ = file.Close()
+
+	queue = openQueue(t, dir, Options{})
+	defer queue.Close()
+	enqueue(t, queue, "c")
+
+	var payloads []string
+	count, err := queue.Forward(collectingSender(&payloads, 100, nil))
+	if err != nil || count != 3 || fmt.Sprint(payloads) != `["a" "b" "c"]` {
+		t.Errorf("Forward() = %d, %v, %v - want a, b, c", count, payloads, err)
+	}
+}
+
+// Returns the offsets of the records of a segment file.
+func recordOffsets(t *testing.T, segment string) []int {
+	content, err := ioutil.ReadFile(segment)
+	if err != nil {
+		t.Fatalf("Failed to read segment: %s", err.Error())
+	}
+
+	var offsets []int
+	for offset := 0; offset < len(content); offset += headerSize + int(binary.BigEndian.Uint32(content[offset:offset+4])) {
+		offsets = append(offsets, offset)
+	}
+	return offsets
+}
+
+// Applies the change to the content of the record at the index in the segment file.
+func corruptRecord(t *testing.T, segment string, index int, change func(record []byte)) {
+	content, _ := ioutil.ReadFile(segment)
+	change(content[recordOffsets(t, segment)[index]:])
+	if err := ioutil.WriteFile(segment, content, 0600); err != nil {
+		t.Fatalf("Failed to write segment: %s", err.Error())
+	}
+}
+
+func flipPayloadBit(record []byte) {
+	record[headerSize+1] ^= 1
+}
+
+func TestQueue_DropsCorruptRecords(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	queue := openQueue(t, dir, Options{})
+	enqueue(t, queue, "a", "b", "c", "d")
+	var payloads []string
+	if _, err := queue.Forward(collectingSender(&payloads, 1, errors.New("offline"))); err == nil {
+		t.Fatalf("Forward() expected the sender error")
+	}
+	_ = queue.Close()
+
+	segment := segmentFiles(t, dir)[0]
+	corruptRecord(t, segment, 1, flipPayloadBit)
+
+	var drops []string
+	queue = openQueue(t, dir, Options{OnDrop: func(count int, reason string) {
+		drops = append(drops, fmt.Sprintf("%d %s", count, reason))
+	}})
+	defer queue.Close()
+
+	// then: b, c and d are dropped and reported, a was forwarded already
+	if want := fmt.Sprintf("[3 corrupt record in %s]", filepath.Base(segment)); fmt.Sprint(drops) != want {
+		t.Errorf("OnDrop() = %v, want %s", drops, want)
+	}
+	if queue.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", queue.Len())
+	}
+}
+
+func TestQueue_DropsRecordsWithCorruptLength(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	queue := openQueue(t, dir, Options{})
+	enqueue(t, queue, "a", "b", "c")
+	_ = queue.Close()
+
+	// A length of almost 4 GiB, which must not be allocated
+	segment := segmentFiles(t, dir)[0]
+	corruptRecord(t, segment, 1, func(record []byte) {
+		binary.BigEndian.PutUint32(record[0:4], 0xfffffff0)
+	})
+
+	dropped := 0
+	queue = openQueue(t, dir, Options{OnDrop: func(count int, reason string) { dropped += count }})
+	defer queue.Close()
+
+	// then: The following records can not be found anymore, only the corrupt one is counted
+	if dropped != 1 || queue.Len() != 1 {
+		t.Errorf("Open() dropped %d records and left %d, want 1 dropped and 1 left", dropped, queue.Len())
+	}
+	var payloads []string
+	if count, err := queue.Forward(collectingSender(&payloads, 100, nil)); err != nil || count != 1 {
+		t.Errorf("Forward() = %d, %v - want a", count, err)
+	}
+}
+
+func TestQueue_LenWithSequenceGap(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	queue := openQueue(t, dir, Options{})
+	enqueue(t, queue, "a", "b")
+	_ = queue.Close()
+
+	// A second segment starts after b
+	queue = openQueue(t, dir, Options{SegmentSize: 1})
+	enqueue(t, queue, "c", "d")
+	_ = queue.Close()
+
+	corruptRecord(t, segmentFiles(t, dir)[0], 1, flipPayloadBit)
+
+	queue = openQueue(t, dir, Options{})
+	defer queue.Close()
+
+	if queue.Len() != 3 {
+		t.Errorf("Len() = %d, want 3 for a, c and d", queue.Len())
+	}
+	var payloads []string
+	count, err := queue.Forward(collectingSender(&payloads, 100, nil))
+	if err != nil || count != 3 || fmt.Sprint(payloads) != `["a" "c" "d"]` || queue.Len() != 0 {
+		t.Errorf("Forward() = %d, %v, %v - want a, c, d", count, payloads, err)
+	}
+}
+
+func TestQueue_ForwardSkipsCorruptSegments(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	var drops []string
+	queue := openQueue(t, dir, Options{OnDrop: func(count int, reason string) {
+		drops = append(drops, fmt.Sprintf("%d %s", count, reason))
+	}})
+	defer queue.Close()
+
+	enqueue(t, queue, "a", "b", "c")
+	segment := segmentFiles(t, dir)[0]
+	corruptRecord(t, segment, 1, flipPayloadBit)
+
+	var payloads []string
+	count, err := queue.Forward(collectingSender(&payloads, 100, nil))
+
+	// then: The rest of the segment is dropped and forwarding continues with new entries
+	if err != nil || count != 1 || fmt.Sprint(payloads) != `["a"]` {
+		t.Errorf("Forward() = %d, %v, %v - want a", count, payloads, err)
+	}
+	if want := fmt.Sprintf("[2 corrupt record in %s]", filepath.Base(segment)); fmt.Sprint(drops) != want {
+		t.Errorf("OnDrop() = %v, want %s", drops, want)
+	}
+
+	enqueue(t, queue, "d")
+	count, err = queue.Forward(collectingSender(&payloads, 100, nil))
+	if err != nil || count != 1 || fmt.Sprint(payloads) != `["a" "d"]` || queue.Len() != 0 {
+		t.Errorf("Forward() after the corrupt segment = %d, %v, %v - want d", count, payloads, err)
+	}
+}
+
+func TestQueue_DropOldest(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	record, _ := encodeRecord(Entry{Sequence: 1, Kind: EVENT, Payload: []byte(`"a"`)})
+	recordSize := int64(len(record)) + 10
+
+	dropped := 0
+	queue := openQueue(t, dir, Options{
+		SegmentSize:  recordSize,
+		MaxDiskBytes: 3 * recordSize,
+		OnDrop:       func(count int, reason string) { dropped += count },
+	})
+	defer queue.Close()
+
+	enqueue(t, queue, "a", "b", "c", "d", "e")
+
+	if dropped != 2 || queue.Len() != 3 || queue.Size() > 3*recordSize {
+		t.Errorf("Queue dropped %d, has %d entries and %d bytes - want 2 dropped and 3 entries", dropped, queue.Len(), queue.Size())
+	}
+
+	var payloads []string
+	_, _ = queue.Forward(collectingSender(&payloads, 100, nil))
+	if fmt.Sprint(payloads) != `["c" "d" "e"]` {
+		t.Errorf("Forward() payloads = %v, want c, d, e", payloads)
+	}
+}
+
+func TestQueue_RejectNew(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	record, _ := encodeRecord(Entry{Sequence: 1, Kind: EVENT, Payload: []byte(`"a"`)})
+	queue := openQueue(t, dir, Options{MaxDiskBytes: 2*int64(len(record)) + 20, DropPolicy: RejectNew})
+	defer queue.Close()
+
+	enqueue(t, queue, "a", "b")
+	if err := queue.Enqueue(EVENT, []byte(`"c"`)); err != QueueFullErr {
+		t.Errorf("Enqueue() on a full queue = %v, want %v", err, QueueFullErr)
+	}
+	if queue.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", queue.Len())
+	}
+}
+
+func TestQueue_DropsUndeliverableEntries(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	var reasons []string
+	queue := openQueue(t, dir, Options{OnDrop: func(count int, reason string) { reasons = append(reasons, reason) }})
+	defer queue.Close()
+
+	enqueue(t, queue, "a", "invalid", "c")
+
+	var payloads []string
+	count, err := queue.Forward(func(entry Entry) error {
+		if string(entry.Payload) == `"invalid"` {
+			return Permanent(errors.New("rejected"))
+		}
+		payloads = append(payloads, string(entry.Payload))
+		return nil
+	})
+
+	if err != nil || count != 2 || fmt.Sprint(payloads) != `["a" "c"]` {
+		t.Errorf("Forward() = %d, %v, %v - want a and c", count, payloads, err)
+	}
+	if len(reasons) != 1 || reasons[0] != "undeliverable: rejected" {
+		t.Errorf("OnDrop() reasons = %v, want one undeliverable entry", reasons)
+	}
+}
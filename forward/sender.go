@@ -0,0 +1,108 @@
+package forward
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/events"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/measurement"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Sends a queued entry. Returns a `Permanent` error, if the entry can never be delivered.
+type Sender func(entry Entry) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Marks an error as permanent: the entry is dropped instead of being forwarded again.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Queues a measurement to create.
+func (queue *Queue) EnqueueMeasurement(m *measurement.NewMeasurement) error {
+	return queue.enqueueObject(MEASUREMENT, m)
+}
+
+// Queues an event to create.
+func (queue *Queue) EnqueueEvent(event *events.CreateEvent) error {
+	return queue.enqueueObject(EVENT, event)
+}
+
+// Queues an alarm to create.
+func (queue *Queue) EnqueueAlarm(newAlarm *alarm.NewAlarm) error {
+	return queue.enqueueObject(ALARM, newAlarm)
+}
+
+/*
+Returns a sender, which creates the queued entries with the apis. Entries rejected by cumulocity as invalid
+(status 400, 413 or 422) are reported as permanent errors. An api may be nil, if no entries of its
+kind are queued.
+*/
+func NewApiSender(measurementApi measurement.MeasurementApi, eventsApi events.Events, alarmApi alarm.AlarmApi) Sender {
+	return func(entry Entry) error {
+		var err *generic.Error
+
+		switch {
+		case entry.Kind == MEASUREMENT && measurementApi != nil:
+			var m measurement.NewMeasurement
+			if parseErr := generic.ObjectFromJson(entry.Payload, &m); parseErr != nil {
+				return Permanent(parseErr)
+			}
+			_, err = measurementApi.Create(&m)
+		case entry.Kind == EVENT && eventsApi != nil:
+			var event events.CreateEvent
+			if parseErr := generic.ObjectFromJson(entry.Payload, &event); parseErr != nil {
+				return Permanent(parseErr)
+			}
+			_, err = eventsApi.CreateEvent(&event)
+		case entry.Kind == ALARM && alarmApi != nil:
+			var newAlarm alarm.NewAlarm
+			if parseErr := generic.ObjectFromJson(entry.Payload, &newAlarm); parseErr != nil {
+				return Permanent(parseErr)
+			}
+			_, err = alarmApi.Create(&newAlarm)
+		default:
+			return Permanent(fmt.Errorf("no api to send entries of kind '%s'", entry.Kind))
+		}
+
+		if err != nil {
+			if rejected(err) {
+				return Permanent(err)
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// -- internal
+
+func (queue *Queue) enqueueObject(kind Kind, object interface{}) error {
+	payload, err := generic.JsonFromObject(object)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %s", strings.ToLower(string(kind)), err.Error())
+	}
+	return queue.Enqueue(kind, payload)
+}
+
+// True, if cumulocity rejected the entry itself - not e.g. because of missing permissions or rate limiting.
+func rejected(err *generic.Error) bool {
+	status, parseErr := strconv.Atoi(strings.SplitN(err.ErrorType, ":", 2)[0])
+	if parseErr != nil {
+		return false
+	}
+	return status == http.StatusBadRequest || status == http.StatusRequestEntityTooLarge || status == http.StatusUnprocessableEntity
+}
@@ -0,0 +1,73 @@
+package forward
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/events"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/measurement"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApiSender_CreatesQueuedEntries(t *testing.T) {
+	var requests []string
+	available := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case !available:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error": "unavailable", "message": "offline"}`))
+		case strings.Contains(string(body), "invalid"):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"error": "invalid", "message": "invalid entry"}`))
+		default:
+			requests = append(requests, fmt.Sprintf("%s %s", r.URL.Path, body))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		}
+	}))
+	defer ts.Close()
+
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+	sender := NewApiSender(measurement.NewMeasurementApi(client), events.NewEventsApi(client), alarm.NewAlarmApi(client))
+
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+	queue := openQueue(t, dir, Options{})
+	defer queue.Close()
+
+	at, _ := time.Parse(time.RFC3339, "2020-06-30T10:00:00Z")
+	for _, err := range []error{
+		queue.EnqueueMeasurement(measurement.New("4711", "c8y_Temperature").At(at).Value("c8y_Temperature", "T", 21.5, "C").Build()),
+		queue.EnqueueEvent(&events.CreateEvent{Type: "invalid", Time: at, Text: "invalid", Source: events.Source{Id: "4711"}}),
+		queue.EnqueueAlarm(&alarm.NewAlarm{Type: "c8y_Overheat", Time: at, Text: "too hot", Severity: alarm.MAJOR, Source: alarm.Source{Id: "4711"}}),
+	} {
+		if err != nil {
+			t.Fatalf("Enqueue() got an unexpected error: %s", err.Error())
+		}
+	}
+
+	count, err := queue.Forward(sender)
+	if count != 0 || err == nil || queue.Len() != 3 {
+		t.Fatalf("Forward() while offline = %d, %v - want an error and all entries kept", count, err)
+	}
+
+	available = true
+	count, err = queue.Forward(sender)
+	if err != nil || count != 2 || queue.Len() != 0 {
+		t.Fatalf("Forward() = %d, %v - want 2 created entries and the invalid one dropped", count, err)
+	}
+
+	if len(requests) != 2 ||
+		!strings.HasPrefix(requests[0], `/measurement/measurements {`) || !strings.Contains(requests[0], `"c8y_Temperature":{"T":{"unit":"C","value":21.5}}`) ||
+		!strings.HasPrefix(requests[1], `/alarm/alarms {`) || !strings.Contains(requests[1], `"severity":"MAJOR"`) {
+		t.Errorf("Forward() requests = %v", requests)
+	}
+}
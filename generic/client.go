@@ -2,6 +2,7 @@ package generic
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -48,14 +49,59 @@ func (client *Client) Get(path string, header map[string][]string) ([]byte, int,
 	return client.request(http.MethodGet, path, []byte{}, header)
 }
 
+// Sends a request with a streamed body, e.g. a file upload, and returns the response body and status.
+func (client *Client) Send(method, path string, body io.Reader, header map[string][]string) ([]byte, int, error) {
+	resp, err := client.do(method, path, body, header)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	return readResponse(resp)
+}
+
+/*
+Sends a GET request and streams the response body to the writer, if the status is 200 - OK.
+Otherwise the response body is returned, e.g. to create an error from it.
+*/
+func (client *Client) Download(path string, header map[string][]string, w io.Writer) ([]byte, int, error) {
+	resp, err := client.do(http.MethodGet, path, nil, header)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return readResponse(resp)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		log.Printf("Error while reading from stream: %s", err.Error())
+		return nil, resp.StatusCode, err
+	}
+
+	return nil, resp.StatusCode, nil
+}
+
 func (client *Client) request(method, path string, body []byte, header map[string][]string) ([]byte, int, error) {
+	resp, err := client.do(method, path, bytes.NewBuffer(body), header)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	return readResponse(resp)
+}
+
+func (client *Client) do(method, path string, body io.Reader, header map[string][]string) (*http.Response, error) {
 	url := client.BaseURL + path
 	log.Printf("HTTP %s on URL %s", method, url)
 
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		log.Printf("Error while creating a request: %s", err.Error())
-		return nil, 0, err
+		return nil, err
 	}
 
 	req.SetBasicAuth(client.Username, client.Password)
@@ -68,11 +114,14 @@ func (client *Client) request(method, path string, body []byte, header map[strin
 	resp, err := client.HTTPClient.Do(req)
 	if err != nil {
 		log.Printf("An error occured: %s", err.Error())
-		return nil, 0, err
+		return nil, err
 	}
 	log.Printf("Got status %d", resp.StatusCode)
-	defer resp.Body.Close()
 
+	return resp, nil
+}
+
+func readResponse(resp *http.Response) ([]byte, int, error) {
 	result, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		log.Printf("Error while reading from stream: %s", err.Error())
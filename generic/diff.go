@@ -0,0 +1,85 @@
+package generic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+/*
+Compares two pointers of struct of the same type and returns the minimal update body:
+all top level json fields (incl. the fragments of a `jsonc:"flat"` map), which were added or changed
+in `after`. Fields which were removed in `after` are contained with a nil value - and so are sent as
+explicit `null`, which removes e.g. a fragment from a cumulocity object.
+
+Both objects are compared in their json representation, see `JsonFromObject`. A changed fragment is
+contained as a whole, as cumulocity replaces fragments on update.
+Returns an empty map, if nothing has changed.
+*/
+func Diff(before interface{}, after interface{}) (map[string]interface{}, error) {
+	if reflect.TypeOf(before) != reflect.TypeOf(after) {
+		return nil, errors.New(fmt.Sprintf("can not compare objects of different types %T and %T", before, after))
+	}
+
+	beforeMap, err := jsonMapFromObject(before)
+	if err != nil {
+		return nil, err
+	}
+	afterMap, err := jsonMapFromObject(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := map[string]interface{}{}
+	for key, afterValue := range afterMap {
+		beforeValue, ok := beforeMap[key]
+		if !ok || !reflect.DeepEqual(beforeValue, afterValue) {
+			diff[key] = afterValue
+		}
+	}
+	for key := range beforeMap {
+		if _, ok := afterMap[key]; !ok {
+			diff[key] = nil
+		}
+	}
+
+	return diff, nil
+}
+
+/*
+Same as `Diff`, but without the given read-only fields, e.g. `id` or `creationTime`. Cumulocity rejects an update,
+which contains read-only fields, so local changes of them are dropped.
+*/
+func UpdateDiff(before interface{}, after interface{}, readOnly ...string) (map[string]interface{}, error) {
+	diff, err := Diff(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range readOnly {
+		delete(diff, key)
+	}
+	return diff, nil
+}
+
+// Same as `Diff`, but returns the update body as json.
+func JsonFromDiff(before interface{}, after interface{}) ([]byte, error) {
+	diff, err := Diff(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(diff)
+}
+
+func jsonMapFromObject(o interface{}) (map[string]interface{}, error) {
+	j, err := JsonFromObject(o)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	err = json.Unmarshal(j, &result)
+	return result, err
+}
@@ -0,0 +1,115 @@
+package generic
+
+import (
+	"reflect"
+	"testing"
+)
+
+type diffObject struct {
+	Id        string                 `json:"id"`
+	Text      string                 `json:"text,omitempty"`
+	Count     int                    `json:"count"`
+	Fragments map[string]interface{} `jsonc:"flat"`
+}
+
+func TestDiff(t *testing.T) {
+	before := &diffObject{
+		Id:    "4711",
+		Text:  "Hello",
+		Count: 1,
+		Fragments: map[string]interface{}{
+			"c8y_Unchanged": map[string]interface{}{"a": 1},
+			"c8y_Changed":   map[string]interface{}{"a": 1, "b": 2},
+			"c8y_Removed":   "foo",
+		},
+	}
+
+	tests := []struct {
+		name  string
+		after *diffObject
+		want  map[string]interface{}
+	}{
+		{
+			"Unchanged",
+			&diffObject{Id: "4711", Text: "Hello", Count: 1, Fragments: before.Fragments},
+			map[string]interface{}{},
+		},
+		{
+			"ChangedFields",
+			&diffObject{Id: "4711", Text: "World", Count: 2, Fragments: before.Fragments},
+			map[string]interface{}{"text": "World", "count": float64(2)},
+		},
+		{
+			"RemovedField",
+			&diffObject{Id: "4711", Count: 1, Fragments: before.Fragments},
+			map[string]interface{}{"text": nil},
+		},
+		{
+			"Fragments",
+			&diffObject{Id: "4711", Text: "Hello", Count: 1, Fragments: map[string]interface{}{
+				"c8y_Unchanged": map[string]interface{}{"a": 1},
+				"c8y_Changed":   map[string]interface{}{"a": 1, "b": 3},
+				"c8y_Added":     true,
+			}},
+			map[string]interface{}{
+				"c8y_Changed": map[string]interface{}{"a": float64(1), "b": float64(3)},
+				"c8y_Added":   true,
+				"c8y_Removed": nil,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := Diff(before, tt.after)
+			if err != nil {
+				t.Fatalf("Diff() - unexpected error %v", err)
+			}
+
+			if !reflect.DeepEqual(diff, tt.want) {
+				t.Errorf("Diff()\n diff = %v\n want %v", diff, tt.want)
+			}
+		})
+	}
+}
+
+func TestJsonFromDiff_ExplicitNull(t *testing.T) {
+	before := &diffObject{Id: "4711", Fragments: map[string]interface{}{"c8y_Removed": "foo"}}
+	after := &diffObject{Id: "4711"}
+
+	j, err := JsonFromDiff(before, after)
+	if err != nil {
+		t.Fatalf("JsonFromDiff() - unexpected error %v", err)
+	}
+
+	want := `{"c8y_Removed":null}`
+	if string(j) != want {
+		t.Errorf("JsonFromDiff() - json = %s, want %s", j, want)
+	}
+}
+
+func TestDiff_ErrorOnDifferentTypes(t *testing.T) {
+	_, err := Diff(&diffObject{}, &A{})
+	if err == nil {
+		t.Errorf("Diff() - error expected for different types")
+	}
+
+	_, err = Diff(diffObject{}, diffObject{})
+	if err == nil {
+		t.Errorf("Diff() - error expected for plain structs")
+	}
+}
+
+func TestUpdateDiff_WithoutReadOnlyFields(t *testing.T) {
+	before := &diffObject{Id: "1", Text: "before"}
+	after := &diffObject{Id: "2", Text: "after"}
+
+	diff, err := UpdateDiff(before, after, "id")
+
+	if err != nil {
+		t.Fatalf("UpdateDiff() - unexpected error %v", err)
+	}
+	if want := map[string]interface{}{"text": "after"}; !reflect.DeepEqual(diff, want) {
+		t.Errorf("UpdateDiff() = %v, want %v", diff, want)
+	}
+}
@@ -0,0 +1,204 @@
+package generic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+ExportWriter writes cumulocity objects (e.g. alarms or events) one by one to an export format.
+Objects are written in their json representation, see `JsonFromObject`.
+*/
+type ExportWriter interface {
+	// Writes a single object.
+	Write(object interface{}) error
+
+	// Flushes all buffered data to the underlying writer.
+	Flush() error
+}
+
+// ExportObject is an object of an exported page. The id names the object in errors.
+type ExportObject struct {
+	Id     string
+	Object interface{}
+}
+
+/*
+Writes all objects of the pages returned by `nextPage` to the export writer. Each call of `nextPage` returns the
+next page, a page smaller than `pageSize` is the last one. The writer is flushed at the end.
+`kind` names the objects in errors, e.g. "alarm", and `operation` is the operation of the errors.
+Returns the number of exported objects.
+*/
+func ExportPages(nextPage func() ([]ExportObject, *Error), pageSize int, writer ExportWriter, kind string, operation string) (int, *Error) {
+	exported := 0
+
+	for {
+		page, err := nextPage()
+		if err != nil {
+			return exported, err
+		}
+		for _, object := range page {
+			if writeErr := writer.Write(object.Object); writeErr != nil {
+				return exported, ClientError(fmt.Sprintf("Error while exporting %s %s: %s", kind, object.Id, writeErr.Error()), operation)
+			}
+			exported++
+		}
+		if len(page) < pageSize {
+			break
+		}
+	}
+
+	if flushErr := writer.Flush(); flushErr != nil {
+		return exported, ClientError(fmt.Sprintf("Error while exporting %ss: %s", kind, flushErr.Error()), operation)
+	}
+	return exported, nil
+}
+
+/*
+Creates an ExportWriter for CSV. The first line contains the column names.
+
+A column is the name of a top level json field, e.g. `type`, or a dot separated path into nested objects,
+e.g. `source.id` or `c8y_Position.lat`. Custom fragments of a `jsonc:"flat"` map are top level fields as
+well. Missing fields result in empty cells, objects and arrays are written as json.
+*/
+func NewCsvExportWriter(w io.Writer, columns []string) (ExportWriter, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("at least one column is needed for a csv export")
+	}
+
+	return &csvExportWriter{writer: csv.NewWriter(w), columns: columns}, nil
+}
+
+/*
+Creates an ExportWriter for newline delimited json (NDJSON): each object is written as json in a single line.
+*/
+func NewNdjsonExportWriter(w io.Writer) ExportWriter {
+	return &ndjsonExportWriter{writer: bufio.NewWriter(w)}
+}
+
+// -- internal
+
+type csvExportWriter struct {
+	writer        *csv.Writer
+	columns       []string
+	headerWritten bool
+}
+
+func (c *csvExportWriter) Write(object interface{}) error {
+	if !c.headerWritten {
+		if err := c.writer.Write(c.columns); err != nil {
+			return err
+		}
+		c.headerWritten = true
+	}
+
+	fields, err := exportFieldsFromObject(object)
+	if err != nil {
+		return err
+	}
+
+	record := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		record[i], err = exportCell(lookupExportField(fields, column))
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error while writing column %s: %v", column, err))
+		}
+	}
+
+	return c.writer.Write(record)
+}
+
+// Writes the header, if no object has been written, and flushes.
+func (c *csvExportWriter) Flush() error {
+	if !c.headerWritten {
+		if err := c.writer.Write(c.columns); err != nil {
+			return err
+		}
+		c.headerWritten = true
+	}
+
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type ndjsonExportWriter struct {
+	writer *bufio.Writer
+}
+
+func (n *ndjsonExportWriter) Write(object interface{}) error {
+	j, err := JsonFromObject(object)
+	if err != nil {
+		return err
+	}
+
+	// The codec writes compact json without line breaks
+	if _, err := n.writer.Write(j); err != nil {
+		return err
+	}
+	return n.writer.WriteByte('\n')
+}
+
+func (n *ndjsonExportWriter) Flush() error {
+	return n.writer.Flush()
+}
+
+func exportFieldsFromObject(object interface{}) (map[string]interface{}, error) {
+	j, err := JsonFromObject(object)
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers are kept as written, e.g. integers are not converted to floats
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.UseNumber()
+
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error while reading exported object: %v", err))
+	}
+	return fields, nil
+}
+
+// A top level field with the exact column name wins over a path, so fragment names may contain dots.
+func lookupExportField(fields map[string]interface{}, column string) interface{} {
+	if value, ok := fields[column]; ok {
+		return value
+	}
+
+	var value interface{} = fields
+	for _, key := range strings.Split(column, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+func exportCell(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		return fmt.Sprintf("%t", value), nil
+	default:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(buf.String(), "\n"), nil
+	}
+}
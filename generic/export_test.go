@@ -0,0 +1,162 @@
+package generic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type exportTestSource struct {
+	Id string `json:"id"`
+}
+
+type exportTestObject struct {
+	Id               string                 `json:"id"`
+	Time             *time.Time             `json:"time,omitempty"`
+	Source           exportTestSource       `json:"source"`
+	Count            int                    `json:"count"`
+	AdditionalFields map[string]interface{} `jsonc:"flat"`
+}
+
+var exportTime, _ = time.Parse(time.RFC3339, "2020-06-26T10:43:25Z")
+
+var exportObjects = []exportTestObject{
+	{
+		Id:     "1",
+		Time:   &exportTime,
+		Source: exportTestSource{Id: "4711"},
+		Count:  3,
+		AdditionalFields: map[string]interface{}{
+			"c8y_Position": map[string]interface{}{"lat": 52.5, "lng": 13.4},
+			"c8y.Dotted":   true,
+			"tags":         []interface{}{"a", "b"},
+		},
+	},
+	{Id: "2", Source: exportTestSource{Id: "4712"}},
+}
+
+func TestExport_Csv(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewCsvExportWriter(&buf, []string{"id", "time", "source.id", "count", "c8y_Position.lat", "c8y.Dotted", "tags", "missing.field"})
+	if err != nil {
+		t.Fatalf("NewCsvExportWriter() - unexpected error %v", err)
+	}
+
+	for i := range exportObjects {
+		if err := writer.Write(&exportObjects[i]); err != nil {
+			t.Fatalf("Write() - unexpected error %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush() - unexpected error %v", err)
+	}
+
+	want := "id,time,source.id,count,c8y_Position.lat,c8y.Dotted,tags,missing.field\n" +
+		"1,2020-06-26T10:43:25Z,4711,3,52.5,true,\"[\"\"a\"\",\"\"b\"\"]\",\n" +
+		"2,,4712,0,,,,\n"
+	if buf.String() != want {
+		t.Errorf("csv export = \n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestExport_CsvHeaderOnly(t *testing.T) {
+	var buf bytes.Buffer
+	writer, _ := NewCsvExportWriter(&buf, []string{"id", "type"})
+
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush() - unexpected error %v", err)
+	}
+	if buf.String() != "id,type\n" {
+		t.Errorf("csv export = %q, want header only", buf.String())
+	}
+}
+
+func TestExport_CsvWithoutColumns(t *testing.T) {
+	_, err := NewCsvExportWriter(&bytes.Buffer{}, nil)
+	if err == nil {
+		t.Errorf("NewCsvExportWriter() - error expected without columns")
+	}
+}
+
+func TestExport_Ndjson(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewNdjsonExportWriter(&buf)
+
+	for i := range exportObjects {
+		if err := writer.Write(&exportObjects[i]); err != nil {
+			t.Fatalf("Write() - unexpected error %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush() - unexpected error %v", err)
+	}
+
+	want := `{"c8y.Dotted":true,"c8y_Position":{"lat":52.5,"lng":13.4},"count":3,"id":"1","source":{"id":"4711"},"tags":["a","b"],"time":"2020-06-26T10:43:25Z"}` + "\n" +
+		`{"count":0,"id":"2","source":{"id":"4712"}}` + "\n"
+	if buf.String() != want {
+		t.Errorf("ndjson export = \n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+// Returns the export objects in pages of the given size, the last call fails with `failWith`, if it is set.
+func exportTestPages(pageSize int, failWith *Error) (func() ([]ExportObject, *Error), *int) {
+	calls := 0
+	return func() ([]ExportObject, *Error) {
+		calls++
+		start := (calls - 1) * pageSize
+		if start >= len(exportObjects) && failWith != nil {
+			return nil, failWith
+		}
+
+		var page []ExportObject
+		for i := start; i < start+pageSize && i < len(exportObjects); i++ {
+			page = append(page, ExportObject{Id: exportObjects[i].Id, Object: &exportObjects[i]})
+		}
+		return page, nil
+	}, &calls
+}
+
+func TestExportPages(t *testing.T) {
+	var buf bytes.Buffer
+	nextPage, calls := exportTestPages(1, nil)
+
+	exported, err := ExportPages(nextPage, 1, NewNdjsonExportWriter(&buf), "object", "ExportObjects")
+
+	// then: Pages are fetched until a page is smaller than the page size
+	if err != nil || exported != 2 || *calls != 3 || strings.Count(buf.String(), "\n") != 2 {
+		t.Errorf("ExportPages() = %d, %v after %d pages - want 2 objects after 3 pages", exported, err, *calls)
+	}
+
+	// A full last page ends the export only with the next, empty page
+	nextPage, calls = exportTestPages(2, nil)
+	if exported, err := ExportPages(nextPage, 2, NewNdjsonExportWriter(&buf), "object", "ExportObjects"); err != nil || exported != 2 || *calls != 2 {
+		t.Errorf("ExportPages() = %d, %v after %d pages - want 2 objects after 2 pages", exported, err, *calls)
+	}
+}
+
+func TestExportPages_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	nextPage, _ := exportTestPages(1, ClientError("offline", "Test"))
+
+	exported, err := ExportPages(nextPage, 1, NewNdjsonExportWriter(&buf), "object", "ExportObjects")
+	if err == nil || err.Message != "offline" || exported != 2 {
+		t.Errorf("ExportPages() = %d, %v - want 2 objects and the page error", exported, err)
+	}
+
+	// The writer fails on the second object, which can not be encoded as json
+	writer, _ := NewCsvExportWriter(&buf, []string{"id"})
+	nextPage, _ = exportTestPages(1, nil)
+	broken := func() ([]ExportObject, *Error) {
+		page, err := nextPage()
+		if len(page) > 0 && page[0].Id == "2" {
+			page[0].Object = make(chan int)
+		}
+		return page, err
+	}
+
+	exported, err = ExportPages(broken, 1, writer, "object", "ExportObjects")
+	if err == nil || !strings.Contains(err.Message, "Error while exporting object 2") || err.Info != "ExportObjects" || exported != 1 {
+		t.Errorf("ExportPages() = %d, %v - want 1 object and an error naming object 2", exported, err)
+	}
+}
@@ -0,0 +1,95 @@
+package generic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+/*
+Custom fragments are the parts of a cumulocity object, which are not known by gomulocity's structs,
+e.g. `c8y_Temperature` of a measurement or `c8y_Hardware` of a managed object. They are collected in
+the `jsonc:"flat"` map (`AdditionalFields`) as generic values.
+
+By registering a Go type for a fragment name, such generic values can be converted into typed values.
+Registrations are usually generated, see `cmd/fragmentgen`.
+*/
+var fragmentTypes sync.Map // fragment name -> reflect.Type
+
+// Registers the Go type of the given prototype value for a custom fragment name.
+// The prototype may be a struct or a pointer of struct. A registration with the same name is replaced.
+// Returns an error and registers nothing, if the prototype is nil.
+func RegisterFragment(name string, prototype interface{}) error {
+	fragmentType := reflect.TypeOf(prototype)
+	if fragmentType == nil {
+		return errors.New(fmt.Sprintf("error: Can not register fragment %s without a prototype", name))
+	}
+	if fragmentType.Kind() == reflect.Ptr {
+		fragmentType = fragmentType.Elem()
+	}
+
+	fragmentTypes.Store(name, fragmentType)
+	return nil
+}
+
+// Returns the registered Go type of a custom fragment.
+func FragmentType(name string) (reflect.Type, bool) {
+	fragmentType, ok := fragmentTypes.Load(name)
+	if !ok {
+		return nil, false
+	}
+
+	return fragmentType.(reflect.Type), true
+}
+
+/*
+Converts a generic fragment value, as found in a `jsonc:"flat"` map, into a new value of the registered type.
+Returns a pointer of the registered type or an error, if no type is registered for the fragment name.
+*/
+func DecodeFragment(name string, value interface{}) (interface{}, error) {
+	fragmentType, ok := FragmentType(name)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("no type registered for fragment %s", name))
+	}
+
+	j, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error: Can not marshal fragment %s: %s", name, err.Error()))
+	}
+
+	target := reflect.New(fragmentType).Interface()
+	if fragmentType.Kind() == reflect.Struct {
+		err = ObjectFromJson(j, target)
+	} else {
+		err = json.Unmarshal(j, target)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error: Can not decode fragment %s: %s", name, err.Error()))
+	}
+
+	return target, nil
+}
+
+/*
+Returns a copy of the given `jsonc:"flat"` map, in which all registered fragments are converted into
+typed values (pointers of the registered types). Unregistered fragments are copied as they are.
+*/
+func TypedFragments(fields map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		if _, ok := FragmentType(name); !ok {
+			result[name] = value
+			continue
+		}
+
+		fragment, err := DecodeFragment(name, value)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = fragment
+	}
+
+	return result, nil
+}
@@ -0,0 +1,76 @@
+package generic
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testHardwareFragment struct {
+	Model        string `json:"model,omitempty"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+}
+
+func TestFragments_DecodeRegisteredFragment(t *testing.T) {
+	RegisterFragment("test_Hardware", testHardwareFragment{})
+
+	fragment, err := DecodeFragment("test_Hardware", map[string]interface{}{"model": "X1", "serialNumber": "4711"})
+	if err != nil {
+		t.Fatalf("DecodeFragment() - unexpected error %v", err)
+	}
+
+	want := &testHardwareFragment{Model: "X1", SerialNumber: "4711"}
+	if !reflect.DeepEqual(fragment, want) {
+		t.Errorf("DecodeFragment() = %#v, want %#v", fragment, want)
+	}
+}
+
+func TestFragments_DecodeUnregisteredFragment(t *testing.T) {
+	_, err := DecodeFragment("test_Unknown", map[string]interface{}{})
+	if err == nil {
+		t.Errorf("DecodeFragment() - error expected for unregistered fragment")
+	}
+}
+
+func TestFragments_TypedFragments(t *testing.T) {
+	RegisterFragment("test_Hardware", &testHardwareFragment{})
+
+	fields := map[string]interface{}{
+		"test_Hardware": map[string]interface{}{"model": "X1"},
+		"custom":        "foo",
+	}
+
+	typed, err := TypedFragments(fields)
+	if err != nil {
+		t.Fatalf("TypedFragments() - unexpected error %v", err)
+	}
+
+	want := map[string]interface{}{
+		"test_Hardware": &testHardwareFragment{Model: "X1"},
+		"custom":        "foo",
+	}
+	if !reflect.DeepEqual(typed, want) {
+		t.Errorf("TypedFragments() = %#v, want %#v", typed, want)
+	}
+}
+
+func TestFragments_TypedFragments_ErrorOnMismatch(t *testing.T) {
+	RegisterFragment("test_Hardware", testHardwareFragment{})
+
+	_, err := TypedFragments(map[string]interface{}{"test_Hardware": map[string]interface{}{"model": 4711}})
+	if err == nil {
+		t.Errorf("TypedFragments() - error expected for mismatching fragment")
+	}
+}
+
+func TestFragments_RegisterNilPrototype(t *testing.T) {
+	if err := RegisterFragment("test_Nil", nil); err == nil {
+		t.Errorf("RegisterFragment() - error expected for a nil prototype")
+	}
+
+	if _, ok := FragmentType("test_Nil"); ok {
+		t.Errorf("FragmentType() - the nil prototype was registered")
+	}
+	if _, err := DecodeFragment("test_Nil", map[string]interface{}{}); err == nil {
+		t.Errorf("DecodeFragment() - error expected for the nil prototype")
+	}
+}
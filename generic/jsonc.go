@@ -1,8 +1,13 @@
 package generic
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 )
 
 type Tag struct {
@@ -44,3 +49,234 @@ func getJsonTag(fieldType *reflect.StructField, tagName string) *Tag {
 		return &Tag{tagName, fieldType.Name, tagValues[0], false}
 	}
 }
+
+/*
+structInfo holds the reflection metadata of a struct type, which is needed to encode and decode it
+with respect to `json:...` and `jsonc:...` tags. It is computed once per type and cached.
+
+Like `encoding/json`, the fields of embedded structs without json name are promoted, as if they were
+declared in the outer struct. A field of a shallower struct hides promoted fields of the same name.
+*/
+type structInfo struct {
+	// All (un-ignored and not hidden) fields in declaration order
+	fields []*fieldInfo
+
+	// Fields by their json name. If several fields of the same depth share a name, the last one wins.
+	byName map[string]*fieldInfo
+
+	// Fields by their json name, sorted and without duplicates. Used for encoding.
+	sorted []*fieldInfo
+
+	// The field tagged with `jsonc:"flat"` or nil
+	flat *fieldInfo
+
+	// Go names of all fields. Json keys matching one of them are never collected into the flat map.
+	goNames map[string]bool
+}
+
+type fieldInfo struct {
+	name      string
+	quoted    []byte // the json encoded name, ready to be written as object key
+	index     []int  // the index sequence of the field, see `reflect.Value.FieldByIndex`
+	typ       reflect.Type
+	omitEmpty bool
+
+	// `jsonc:"collection"` - `typ` is a slice of structs
+	collection bool
+}
+
+var structInfoCache sync.Map // reflect.Type -> *structInfo
+
+/*
+Returns the cached metadata of the given struct type. On first use the metadata is built
+and the `jsonc` tags are validated.
+*/
+func cachedStructInfo(structType reflect.Type) (*structInfo, error) {
+	if info, ok := structInfoCache.Load(structType); ok {
+		return info.(*structInfo), nil
+	}
+
+	info, err := buildStructInfo(structType)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, _ := structInfoCache.LoadOrStore(structType, info)
+	return actual.(*structInfo), nil
+}
+
+func buildStructInfo(structType reflect.Type) (*structInfo, error) {
+	info := &structInfo{byName: map[string]*fieldInfo{}, goNames: map[string]bool{}}
+
+	var all []*fieldInfo
+	err := info.addFields(structType, nil, map[reflect.Type]bool{structType: true}, &all)
+	if err != nil {
+		return nil, err
+	}
+
+	// A shallower field hides the promoted fields of the same name
+	for _, field := range all {
+		if current, ok := info.byName[field.name]; !ok || len(field.index) <= len(current.index) {
+			info.byName[field.name] = field
+		}
+	}
+	for _, field := range all {
+		if len(field.index) == len(info.byName[field.name].index) {
+			info.fields = append(info.fields, field)
+		}
+	}
+
+	for _, field := range info.byName {
+		info.sorted = append(info.sorted, field)
+	}
+	sort.Slice(info.sorted, func(i, j int) bool {
+		return info.sorted[i].name < info.sorted[j].name
+	})
+
+	return info, nil
+}
+
+/*
+Collects the fields of the struct type in declaration order and descends into embedded structs.
+`index` is the index sequence of the struct within the outer struct, `visited` the embedded struct types
+on the way, to stop on cycles.
+*/
+func (info *structInfo) addFields(structType reflect.Type, index []int, visited map[reflect.Type]bool, all *[]*fieldInfo) error {
+	for i := 0; i < structType.NumField(); i++ {
+		fieldType := structType.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		jsonCTag := getJsonTag(&fieldType, "jsonc")
+		jsonTag := getJsonTag(&fieldType, "json")
+
+		if embedded := embeddedStruct(&fieldType, jsonTag, jsonCTag); embedded != nil {
+			info.goNames[fieldType.Name] = true
+			if !visited[embedded] {
+				visited[embedded] = true
+				if err := info.addFields(embedded, fieldIndex, visited, all); err != nil {
+					return err
+				}
+				delete(visited, embedded)
+			}
+			continue
+		}
+
+		if fieldType.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		info.goNames[fieldType.Name] = true
+
+		if jsonCTag != nil && jsonCTag.Name == "flat" {
+			if fieldType.Type.Kind() != reflect.Map || fieldType.Type.Key().Kind() != reflect.String {
+				return errors.New(fmt.Sprintf("error: Field %s is not a map! Can not deflat it.", fieldType.Name))
+			}
+
+			if info.flat == nil || len(fieldIndex) <= len(info.flat.index) {
+				info.flat = &fieldInfo{name: fieldType.Name, index: fieldIndex, typ: fieldType.Type}
+			}
+			continue
+		}
+
+		if jsonTag != nil && jsonTag.Name == "-" {
+			continue
+		}
+
+		field := &fieldInfo{name: fieldType.Name, index: fieldIndex, typ: fieldType.Type}
+		if jsonTag != nil {
+			if jsonTag.Name != "" {
+				field.name = jsonTag.Name
+			}
+			field.omitEmpty = jsonTag.OmitEmpty
+		}
+
+		if jsonCTag != nil && jsonCTag.Name == "collection" {
+			if fieldType.Type.Kind() != reflect.Slice || fieldType.Type.Elem().Kind() != reflect.Struct {
+				return errors.New(fmt.Sprintf("error: Field %s ist not a slice of structs! Can not use it as collection", fieldType.Name))
+			}
+			field.collection = true
+		}
+
+		quoted, err := json.Marshal(field.name)
+		if err != nil {
+			return err
+		}
+		field.quoted = quoted
+
+		*all = append(*all, field)
+	}
+	return nil
+}
+
+/*
+Returns the struct type of an embedded struct, whose fields are promoted, or nil. Like `encoding/json`,
+embedded structs with a json name are regular fields. Embedded pointers of unexported types are ignored,
+because they can not be allocated, and so are embedded structs of unexported types with a json name.
+*/
+func embeddedStruct(fieldType *reflect.StructField, jsonTag *Tag, jsonCTag *Tag) reflect.Type {
+	if !fieldType.Anonymous || (jsonTag != nil && jsonTag.Name != "") || jsonCTag != nil {
+		return nil
+	}
+
+	typ := fieldType.Type
+	if typ.Kind() == reflect.Ptr {
+		if fieldType.PkgPath != "" {
+			return nil
+		}
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	return typ
+}
+
+/*
+Returns the field of the given struct value. Nil pointers of embedded structs on the way are allocated,
+if `allocate` is set. Otherwise the invalid zero Value is returned for them.
+*/
+func (field *fieldInfo) value(structValue reflect.Value, allocate bool) reflect.Value {
+	value := structValue
+	for i, index := range field.index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				if !allocate {
+					return reflect.Value{}
+				}
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(index)
+	}
+	return value
+}
+
+// Returns true, if the field is declared before the other one in the struct including all embedded structs.
+func (field *fieldInfo) before(other *fieldInfo) bool {
+	for i := 0; i < len(field.index) && i < len(other.index); i++ {
+		if field.index[i] != other.index[i] {
+			return field.index[i] < other.index[i]
+		}
+	}
+	return len(field.index) < len(other.index)
+}
+
+/*
+Looks up the field for a json object key. Like `encoding/json`, an exact match is preferred,
+otherwise the key is matched case-insensitively.
+*/
+func (info *structInfo) field(key string) *fieldInfo {
+	if field, ok := info.byName[key]; ok {
+		return field
+	}
+
+	for _, field := range info.fields {
+		if strings.EqualFold(field.name, key) {
+			return field
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package generic_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/events"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/measurement"
+)
+
+const benchmarkCollectionSize = 100
+
+const benchmarkAlarm = `{
+	"id": "%d",
+	"self": "https://t0815.cumulocity.com/alarm/alarms/%d",
+	"creationTime": "2020-06-30T08:32:04.413Z",
+	"type": "test-gomulocity-Alarm",
+	"time": "2020-06-30T08:32:04.261Z",
+	"text": "Test creation of an alarm",
+	"source": {"id": "1111111", "name": "testGomulocityDevice"},
+	"status": "ACTIVE",
+	"severity": "MINOR",
+	"count": 1,
+	"firstOccurrenceTime": "2020-06-30T08:32:04Z",
+	"custom1": "Hello",
+	"custom2": ["Foo", "Bar"]
+}`
+
+const benchmarkEvent = `{
+	"id": "%d",
+	"self": "https://t0815.cumulocity.com/event/events/%d",
+	"creationTime": "2020-01-01T01:00:10.000Z",
+	"source": {"self": "https://t0815.cumulocity.com/inventory/managedObjects/1111111", "id": "1111111"},
+	"type": "threshold",
+	"time": "2020-01-01T01:00:00.000Z",
+	"text": "over 21°C",
+	"custom1": "Hello",
+	"custom2": ["Foo", "Bar"]
+}`
+
+const benchmarkMeasurement = `{
+	"id": "%d",
+	"self": "https://t0815.cumulocity.com/measurement/measurements/%d",
+	"type": "test-gomulocity-Measurement",
+	"time": "2020-06-30T08:32:04.261Z",
+	"source": {"id": "1111111"},
+	"AirPressure": {"value": 1011.2, "unit": "hPa"},
+	"Humidity": {"value": 51, "unit": "%%RH"},
+	"c8y_Temperature": {"T": {"value": 23.45, "unit": "C"}}
+}`
+
+func collectionJson(name string, template string) []byte {
+	elements := make([]string, benchmarkCollectionSize)
+	for i := range elements {
+		elements[i] = fmt.Sprintf(template, i, i)
+	}
+
+	return []byte(fmt.Sprintf(`{"self": "https://t0815.cumulocity.com/%s", "%s": [%s], "statistics": {"currentPage": 1, "pageSize": %d}}`,
+		name, name, strings.Join(elements, ","), benchmarkCollectionSize))
+}
+
+var benchmarkCollections = []struct {
+	name      string
+	json      []byte
+	newTarget func() interface{}
+	fragment  string // A custom fragment as written by JsonFromObject
+}{
+	{"AlarmCollection", collectionJson("alarms", benchmarkAlarm), func() interface{} { return &alarm.AlarmCollection{} }, `"custom2":["Foo","Bar"]`},
+	{"EventCollection", collectionJson("events", benchmarkEvent), func() interface{} { return &events.EventCollection{} }, `"custom2":["Foo","Bar"]`},
+	{"MeasurementCollection", collectionJson("measurements", benchmarkMeasurement), func() interface{} { return &measurement.MeasurementCollection{} }, `"c8y_Temperature":{"T":{"unit":"C","value":23.45}}`},
+}
+
+// The collections keep their custom fragments, when they are written and read again.
+func TestJsonc_BenchmarkCollectionsRoundTrip(t *testing.T) {
+	for _, c := range benchmarkCollections {
+		t.Run(c.name, func(t *testing.T) {
+			object := c.newTarget()
+			if err := generic.ObjectFromJson(c.json, object); err != nil {
+				t.Fatalf("ObjectFromJson - unexpected error %v", err)
+			}
+			j, err := generic.JsonFromObject(object)
+			if err != nil {
+				t.Fatalf("JsonFromObject - unexpected error %v", err)
+			}
+
+			again := c.newTarget()
+			if err := generic.ObjectFromJson(j, again); err != nil {
+				t.Fatalf("ObjectFromJson - unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(object, again) {
+				t.Errorf("ObjectFromJson(JsonFromObject())\n object = %v\n want %v", again, object)
+			}
+			if c.fragment != "" && !strings.Contains(string(j), c.fragment) {
+				t.Errorf("JsonFromObject lost the custom fragment %s: %s", c.fragment, j)
+			}
+		})
+	}
+}
+
+// The single pass codec gives the same results as the two pass codec, it replaced.
+func TestJsonc_SameResultAsLegacyCodec(t *testing.T) {
+	for _, c := range benchmarkCollections {
+		t.Run(c.name, func(t *testing.T) {
+			legacy := c.newTarget()
+			current := c.newTarget()
+
+			if err := generic.LegacyObjectFromJson(c.json, legacy); err != nil {
+				t.Fatalf("LegacyObjectFromJson - unexpected error %v", err)
+			}
+			if err := generic.ObjectFromJson(c.json, current); err != nil {
+				t.Fatalf("ObjectFromJson - unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(legacy, current) {
+				t.Errorf("ObjectFromJson\n object = %v\n want %v", current, legacy)
+			}
+
+			legacyJson, err := generic.LegacyJsonFromObject(legacy)
+			if err != nil {
+				t.Fatalf("LegacyJsonFromObject - unexpected error %v", err)
+			}
+			currentJson, err := generic.JsonFromObject(current)
+			if err != nil {
+				t.Fatalf("JsonFromObject - unexpected error %v", err)
+			}
+			if string(legacyJson) != string(currentJson) {
+				t.Errorf("JsonFromObject\n json = %s\n want %s", currentJson, legacyJson)
+			}
+		})
+	}
+}
+
+/*
+Compares the single pass codec with the two pass codec it replaced ("legacy"). The plain `encoding/json` codec
+without any `jsonc` handling, e.g. of custom fragments, is the lower bound.
+*/
+func BenchmarkObjectFromJson(b *testing.B) {
+	codecs := []struct {
+		name      string
+		unmarshal func([]byte, interface{}) error
+	}{
+		{"encoding_json", json.Unmarshal},
+		{"legacy", generic.LegacyObjectFromJson},
+		{"jsonc", generic.ObjectFromJson},
+	}
+
+	for _, c := range benchmarkCollections {
+		for _, codec := range codecs {
+			b.Run(fmt.Sprintf("%s/%s", c.name, codec.name), func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(c.json)))
+				for i := 0; i < b.N; i++ {
+					if err := codec.unmarshal(c.json, c.newTarget()); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkJsonFromObject(b *testing.B) {
+	codecs := []struct {
+		name    string
+		marshal func(interface{}) ([]byte, error)
+	}{
+		{"encoding_json", json.Marshal},
+		{"legacy", generic.LegacyJsonFromObject},
+		{"jsonc", generic.JsonFromObject},
+	}
+
+	for _, c := range benchmarkCollections {
+		object := c.newTarget()
+		if err := generic.ObjectFromJson(c.json, object); err != nil {
+			b.Fatal(err)
+		}
+
+		for _, codec := range codecs {
+			b.Run(fmt.Sprintf("%s/%s", c.name, codec.name), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := codec.marshal(object); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package generic
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+DecodeIssue describes a part of a json document, which does not match the target struct.
+*/
+type DecodeIssue struct {
+	Path     string // JSON path of the value, e.g. `measurements[3].c8y_Temp.T.value`
+	Expected string // The expected Go type. Empty, if the field is unknown.
+	Actual   string // The json type of the value: object, array, string, number, boolean or null
+	Message  string // Optional details, e.g. the error of a custom unmarshaller
+}
+
+// Returns true, if the field is neither a struct field nor can be collected by a `jsonc:"flat"` map.
+func (issue DecodeIssue) Unknown() bool {
+	return issue.Expected == ""
+}
+
+func (issue DecodeIssue) String() string {
+	if issue.Unknown() {
+		return fmt.Sprintf("%s: unknown field", issue.Path)
+	}
+
+	s := fmt.Sprintf("%s: expected %s, got %s", issue.Path, issue.Expected, issue.Actual)
+	if issue.Message != "" {
+		s = fmt.Sprintf("%s (%s)", s, issue.Message)
+	}
+	return s
+}
+
+/*
+DecodeError is returned by `ObjectFromJsonStrict` and lists all issues of the json document.
+*/
+type DecodeError struct {
+	Issues []DecodeIssue
+}
+
+func (e *DecodeError) Error() string {
+	issues := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		issues[i] = issue.String()
+	}
+
+	return fmt.Sprintf("Error while unmarshaling json: %d issue(s): %s", len(e.Issues), strings.Join(issues, "; "))
+}
+
+/*
+Works like `ObjectFromJson`, but reports every value with a mismatching type and every unknown
+field with its JSON path. A field is unknown, if it is neither a struct field nor can be collected
+by a `jsonc:"flat"` map.
+
+Returns a *DecodeError listing all issues. The `targetStruct` is filled as far as possible anyway.
+*/
+func ObjectFromJsonStrict(j []byte, targetStruct interface{}) error {
+	state, err := objectFromJson(j, targetStruct, decodeStrict)
+	if err != nil {
+		return err
+	}
+	if len(state.issues) > 0 {
+		return &DecodeError{Issues: state.issues}
+	}
+
+	return nil
+}
+
+/*
+Works like `ObjectFromJson`, but skips every element of a `jsonc:"collection"` which has a value with
+a mismatching type.
+
+Returns the skipped elements' issues and all other issues (incl. unknown fields) as warnings.
+An error is only returned, if the json is invalid or the `targetStruct` is not usable.
+*/
+func ObjectFromJsonLenient(j []byte, targetStruct interface{}) ([]DecodeIssue, error) {
+	state, err := objectFromJson(j, targetStruct, decodeLenient)
+	if err != nil {
+		return nil, err
+	}
+
+	return state.issues, nil
+}
+
+// -- internal
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+/*
+Decodes the next json value of the stream into the `jsonc:"collection"` field. Each element is
+decoded separately, so bad elements can be reported and, in lenient mode, skipped.
+*/
+func (state *decodeState) diagnoseCollection(elementInfo *structInfo, field *fieldInfo, fieldValue reflect.Value, path string) error {
+	var raw json.RawMessage
+	err := state.decoder.Decode(&raw)
+	if err != nil {
+		return err
+	}
+
+	kind := jsonKind(raw)
+	if kind == "null" {
+		fieldValue.Set(reflect.Zero(field.typ))
+		return nil
+	}
+	if kind != "array" {
+		state.issues = append(state.issues, DecodeIssue{Path: path, Expected: field.typ.String(), Actual: kind})
+		return nil
+	}
+
+	var elements []json.RawMessage
+	err = json.Unmarshal(raw, &elements)
+	if err != nil {
+		return err
+	}
+
+	slice := reflect.MakeSlice(field.typ, 0, len(elements))
+	for i, rawElement := range elements {
+		element := reflect.New(field.typ.Elem()).Elem()
+		elementPath := fmt.Sprintf("%s[%d]", path, i)
+
+		elementKind := jsonKind(rawElement)
+		if elementKind != "object" && elementKind != "null" {
+			state.issues = append(state.issues, DecodeIssue{Path: elementPath, Expected: field.typ.Elem().String(), Actual: elementKind})
+			if state.mode != decodeLenient {
+				slice = reflect.Append(slice, element)
+			}
+			continue
+		}
+
+		elementState := &decodeState{decoder: json.NewDecoder(bytes.NewReader(rawElement)), mode: state.mode}
+		err := elementState.decodeStruct(elementInfo, element, elementPath)
+		if err != nil {
+			return err
+		}
+		state.issues = append(state.issues, elementState.issues...)
+
+		if state.mode == decodeLenient && hasMismatch(elementState.issues) {
+			continue
+		}
+		slice = reflect.Append(slice, element)
+	}
+	fieldValue.Set(slice)
+
+	return nil
+}
+
+// Decodes the next json value of the stream into `target` and reports all mismatches.
+func (state *decodeState) diagnoseValue(target interface{}, path string) error {
+	var raw json.RawMessage
+	err := state.decoder.Decode(&raw)
+	if err != nil {
+		return err
+	}
+
+	targetType := reflect.TypeOf(target).Elem()
+	issues := validateJson(raw, targetType, path)
+	state.issues = append(state.issues, issues...)
+
+	// Fill the target as far as possible
+	err = json.Unmarshal(raw, target)
+	if err != nil && len(issues) == 0 {
+		state.issues = append(state.issues, DecodeIssue{Path: path, Expected: targetType.String(), Actual: jsonKind(raw), Message: err.Error()})
+	}
+
+	return nil
+}
+
+func validateJson(raw []byte, typ reflect.Type, path string) []DecodeIssue {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return []DecodeIssue{{Path: path, Expected: typ.String(), Actual: jsonKind(raw), Message: err.Error()}}
+	}
+
+	return validateValue(value, typ, path, nil)
+}
+
+/*
+Checks a generic json value (as decoded into an interface{} with `UseNumber`) against the given type
+the same way `encoding/json` would decode it. Appends all found issues to `issues`.
+*/
+func validateValue(value interface{}, typ reflect.Type, path string, issues []DecodeIssue) []DecodeIssue {
+	if value == nil {
+		// null is accepted for every type
+		return issues
+	}
+
+	mismatch := func(message string) []DecodeIssue {
+		return append(issues, DecodeIssue{Path: path, Expected: typ.String(), Actual: jsonValueKind(value), Message: message})
+	}
+
+	if typ.Kind() == reflect.Ptr {
+		return validateValue(value, typ.Elem(), path, issues)
+	}
+
+	// Types with a custom unmarshaller (e.g. time.Time) are checked by trying it
+	pointerType := reflect.PtrTo(typ)
+	if pointerType.Implements(jsonUnmarshalerType) || pointerType.Implements(textUnmarshalerType) {
+		j, _ := json.Marshal(value)
+		err := json.Unmarshal(j, reflect.New(typ).Interface())
+		if err != nil {
+			return mismatch(err.Error())
+		}
+		return issues
+	}
+
+	switch typ.Kind() {
+	case reflect.Interface:
+		if typ.NumMethod() > 0 {
+			return mismatch("")
+		}
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch("")
+		}
+		info, err := cachedStructInfo(typ)
+		if err != nil {
+			return mismatch(err.Error())
+		}
+
+		for _, key := range sortedKeys(object) {
+			keyPath := joinPath(path, key)
+			field := info.field(key)
+			if field != nil {
+				issues = validateValue(object[key], field.typ, keyPath, issues)
+			} else if info.flat != nil && !info.goNames[key] {
+				// Collected by the `jsonc:"flat"` map, like the decoder does
+				issues = validateValue(object[key], info.flat.typ.Elem(), keyPath, issues)
+			} else if !info.goNames[key] {
+				issues = append(issues, DecodeIssue{Path: keyPath, Actual: jsonValueKind(object[key])})
+			}
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch("")
+		}
+		for _, key := range sortedKeys(object) {
+			issues = validateValue(object[key], typ.Elem(), joinPath(path, key), issues)
+		}
+	case reflect.Slice, reflect.Array:
+		if typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 {
+			s, ok := value.(string)
+			if !ok {
+				return mismatch("")
+			}
+			if _, err := base64.StdEncoding.DecodeString(s); err != nil {
+				return mismatch(err.Error())
+			}
+			return issues
+		}
+
+		array, ok := value.([]interface{})
+		if !ok {
+			return mismatch("")
+		}
+		for i, element := range array {
+			issues = validateValue(element, typ.Elem(), fmt.Sprintf("%s[%d]", path, i), issues)
+		}
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			return mismatch("")
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return mismatch("")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, ok := value.(json.Number)
+		if !ok {
+			return mismatch("")
+		}
+		n, err := strconv.ParseInt(string(number), 10, 64)
+		if err != nil || reflect.Zero(typ).OverflowInt(n) {
+			return mismatch(fmt.Sprintf("number %s", number))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		number, ok := value.(json.Number)
+		if !ok {
+			return mismatch("")
+		}
+		n, err := strconv.ParseUint(string(number), 10, 64)
+		if err != nil || reflect.Zero(typ).OverflowUint(n) {
+			return mismatch(fmt.Sprintf("number %s", number))
+		}
+	case reflect.Float32, reflect.Float64:
+		number, ok := value.(json.Number)
+		if !ok {
+			return mismatch("")
+		}
+		n, err := number.Float64()
+		if err != nil || reflect.Zero(typ).OverflowFloat(n) {
+			return mismatch(fmt.Sprintf("number %s", number))
+		}
+	}
+
+	return issues
+}
+
+func hasMismatch(issues []DecodeIssue) bool {
+	for _, issue := range issues {
+		if !issue.Unknown() {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Returns the json type of a raw json value.
+func jsonKind(raw []byte) string {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return ""
+	}
+
+	switch trimmed[0] {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	default:
+		return "number"
+	}
+}
+
+// Returns the json type of a generic json value.
+func jsonValueKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	default:
+		return "number"
+	}
+}
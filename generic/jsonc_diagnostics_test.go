@@ -0,0 +1,137 @@
+package generic
+
+import (
+	"reflect"
+	"testing"
+)
+
+type diagnosticsValue struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+type diagnosticsMeasurement struct {
+	Id        string                                 `json:"id"`
+	Count     int                                    `json:"count"`
+	Fragments map[string]map[string]diagnosticsValue `jsonc:"flat"`
+}
+
+type diagnosticsCollection struct {
+	Self         string                   `json:"self"`
+	Measurements []diagnosticsMeasurement `json:"measurements" jsonc:"collection"`
+}
+
+const diagnosticsJson = `{
+	"self": 4711,
+	"foo": "bar",
+	"measurements": [
+		{"id": "1", "c8y_Temp": {"T": {"value": 21.5, "unit": "C"}}},
+		{"id": "2", "count": "three", "c8y_Temp": {"T": {"value": "hot", "unit": "C", "extra": true}}},
+		7
+	]
+}`
+
+var diagnosticsIssues = []DecodeIssue{
+	{Path: "self", Expected: "string", Actual: "number"},
+	{Path: "foo", Actual: "string"},
+	{Path: "measurements[1].count", Expected: "int", Actual: "string"},
+	{Path: "measurements[1].c8y_Temp.T.extra", Actual: "boolean"},
+	{Path: "measurements[1].c8y_Temp.T.value", Expected: "float64", Actual: "string"},
+	{Path: "measurements[2]", Expected: "generic.diagnosticsMeasurement", Actual: "number"},
+}
+
+func TestJsonc_ObjectFromJsonStrict_ReportsAllIssues(t *testing.T) {
+	c := &diagnosticsCollection{}
+	err := ObjectFromJsonStrict([]byte(diagnosticsJson), c)
+
+	decodeErr, ok := err.(*DecodeError)
+	if !ok {
+		t.Fatalf("ObjectFromJsonStrict - error = %v, want *DecodeError", err)
+	}
+
+	if !reflect.DeepEqual(decodeErr.Issues, diagnosticsIssues) {
+		t.Errorf("ObjectFromJsonStrict\n issues = %v\n want %v", decodeErr.Issues, diagnosticsIssues)
+	}
+
+	// and: The valid parts are decoded anyway
+	if len(c.Measurements) != 3 || c.Measurements[0].Fragments["c8y_Temp"]["T"].Value != 21.5 {
+		t.Errorf("ObjectFromJsonStrict - measurements = %v", c.Measurements)
+	}
+}
+
+func TestJsonc_ObjectFromJsonStrict_NoIssues(t *testing.T) {
+	a := &A{}
+	err := ObjectFromJsonStrict([]byte(testJson), a)
+
+	if err != nil {
+		t.Errorf("ObjectFromJsonStrict - unexpected error %v", err)
+	}
+
+	if len(a.Bs) != 2 {
+		t.Fatalf("ObjectFromJsonStrict - collection size = %d, want = 2", len(a.Bs))
+	}
+	assertB(a.Bs[0], "Hello", 1, t)
+	assertB(a.Bs[1], "Hello2", 2, t)
+}
+
+func TestJsonc_ObjectFromJsonLenient_SkipsBadElements(t *testing.T) {
+	c := &diagnosticsCollection{}
+	warnings, err := ObjectFromJsonLenient([]byte(diagnosticsJson), c)
+
+	if err != nil {
+		t.Fatalf("ObjectFromJsonLenient - unexpected error %v", err)
+	}
+
+	if !reflect.DeepEqual(warnings, diagnosticsIssues) {
+		t.Errorf("ObjectFromJsonLenient\n warnings = %v\n want %v", warnings, diagnosticsIssues)
+	}
+
+	if len(c.Measurements) != 1 || c.Measurements[0].Id != "1" {
+		t.Errorf("ObjectFromJsonLenient - measurements = %v, want only the element with id 1", c.Measurements)
+	}
+}
+
+func TestJsonc_ObjectFromJsonLenient_ErrorOnInvalidJson(t *testing.T) {
+	_, err := ObjectFromJsonLenient([]byte(`{"measurements": [{"id": "1"`), &diagnosticsCollection{})
+
+	if err == nil {
+		t.Errorf("ObjectFromJsonLenient - error expected for invalid json")
+	}
+}
+
+func TestJsonc_DecodeIssue_String(t *testing.T) {
+	tests := []struct {
+		issue DecodeIssue
+		want  string
+	}{
+		{DecodeIssue{Path: "a.b", Actual: "string"}, "a.b: unknown field"},
+		{DecodeIssue{Path: "a[1]", Expected: "int", Actual: "string"}, "a[1]: expected int, got string"},
+		{DecodeIssue{Path: "time", Expected: "time.Time", Actual: "string", Message: "bad time"}, "time: expected time.Time, got string (bad time)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.issue.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+type diagnosticsReference struct {
+	Measurement diagnosticsMeasurement `json:"measurement"`
+}
+
+func TestJsonc_ObjectFromJsonStrict_FlatFragmentsOfNestedStruct(t *testing.T) {
+	j := `{"measurement": {"id": "1", "c8y_Temp": {"T": {"value": 21.5, "unit": "C"}}, "c8y_Hum": {"H": {"value": "wet"}}}}`
+
+	err := ObjectFromJsonStrict([]byte(j), &diagnosticsReference{})
+
+	// then: The fragments are no unknown fields, but their values are checked
+	decodeErr, ok := err.(*DecodeError)
+	if !ok {
+		t.Fatalf("ObjectFromJsonStrict - error = %v, want *DecodeError", err)
+	}
+	want := []DecodeIssue{{Path: "measurement.c8y_Hum.H.value", Expected: "float64", Actual: "string"}}
+	if !reflect.DeepEqual(decodeErr.Issues, want) {
+		t.Errorf("ObjectFromJsonStrict\n issues = %v\n want %v", decodeErr.Issues, want)
+	}
+}
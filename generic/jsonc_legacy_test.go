@@ -0,0 +1,136 @@
+package generic
+
+// The previous, two pass implementation of the jsonc codec: objects are converted via an intermediate map.
+// It is only kept as baseline for the benchmarks of the single pass codec - see jsonc_benchmark_test.go.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Exported for the external benchmark package.
+var (
+	LegacyJsonFromObject = legacyJsonFromObject
+	LegacyObjectFromJson = legacyObjectFromJson
+)
+
+func legacyJsonFromObject(o interface{}) ([]byte, error) {
+	structValue, ok := pointerOfStruct(&o)
+	if !ok {
+		return nil, errors.New("input is not a pointer of struct")
+	}
+
+	m, err := legacyMapFromStruct(*structValue)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
+
+// Converts a struct into a map with respect to the `json` tags, `jsonc:"flat"` and `jsonc:"collection"`.
+func legacyMapFromStruct(structValue reflect.Value) (map[string]interface{}, error) {
+	target := map[string]interface{}{}
+	structType := structValue.Type()
+
+	for i := 0; i < structValue.NumField(); i++ {
+		fieldType := structType.Field(i)
+		fieldValue := structValue.Field(i)
+
+		switch tag := getJsonTag(&fieldType, "jsonc"); {
+		case tag == nil:
+			legacyInsertTaggedField(target, &fieldType, fieldValue)
+		case tag.Name == "flat":
+			if fieldValue.Kind() != reflect.Map {
+				return nil, fmt.Errorf("error: on flat field %s: is not a map", fieldType.Name)
+			}
+			for iter := fieldValue.MapRange(); iter.Next(); {
+				target[iter.Key().String()] = iter.Value().Interface()
+			}
+		case tag.Name == "collection":
+			if fieldValue.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("error: on collection %s: is not a slice", fieldType.Name)
+			}
+			items := make([]map[string]interface{}, fieldValue.Len())
+			for j := range items {
+				item, err := legacyMapFromStruct(fieldValue.Index(j))
+				if err != nil {
+					return nil, fmt.Errorf("error: on collection %s: can not convert item %d: %s", fieldType.Name, j, err.Error())
+				}
+				items[j] = item
+			}
+			legacyInsertTaggedField(target, &fieldType, reflect.ValueOf(items))
+		}
+	}
+	return target, nil
+}
+
+func legacyInsertTaggedField(target map[string]interface{}, fieldType *reflect.StructField, fieldValue reflect.Value) {
+	tag := getJsonTag(fieldType, "json")
+	switch {
+	case tag == nil:
+		target[fieldType.Name] = fieldValue.Interface()
+	case tag.Name == "-" || (tag.OmitEmpty && isEmptyValue(&fieldValue)):
+	default:
+		target[tag.Name] = fieldValue.Interface()
+	}
+}
+
+// Unmarshals the json into the struct and a second time into a map, whose unknown fields are merged into the struct.
+func legacyObjectFromJson(j []byte, targetStruct interface{}) error {
+	structValue, ok := pointerOfStruct(&targetStruct)
+	if !ok {
+		return errors.New("input is not a pointer of struct")
+	}
+
+	if err := json.Unmarshal(j, targetStruct); err != nil {
+		return fmt.Errorf("Error while unmarshaling json: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(j, &fields); err != nil {
+		return fmt.Errorf("Error while unmarshaling json: %v", err)
+	}
+	return legacyMergeMapWithStruct(fields, *structValue)
+}
+
+func legacyMergeMapWithStruct(fields map[string]interface{}, structValue reflect.Value) error {
+	structType := structValue.Type()
+	flatField := ""
+
+	for i := 0; i < structType.NumField(); i++ {
+		fieldType := structType.Field(i)
+		fieldValue := structValue.Field(i)
+		jsonTag := getJsonTag(&fieldType, "json")
+
+		name := fieldType.Name
+		if jsonTag != nil {
+			name = jsonTag.Name
+		}
+
+		switch tag := getJsonTag(&fieldType, "jsonc"); {
+		case tag != nil && tag.Name == "flat":
+			flatField = fieldType.Name
+		case tag != nil && tag.Name == "collection":
+			collection, _ := fields[name].([]interface{})
+			for j := 0; j < fieldValue.Len() && j < len(collection); j++ {
+				element, ok := collection[j].(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("error: Element of jsonc:collection field %s is not a map!", fieldType.Name)
+				}
+				if err := legacyMergeMapWithStruct(element, fieldValue.Index(j)); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Only the unknown fields remain in the map
+		delete(fields, name)
+		delete(fields, fieldType.Name)
+	}
+
+	if flatField != "" {
+		structValue.FieldByName(flatField).Set(reflect.ValueOf(fields))
+	}
+	return nil
+}
@@ -117,3 +117,26 @@ func assertB(b B, foo string, bar int, t *testing.T) {
 		t.Errorf("ObjectFromJson - Sub B -> custom3 = [%v, %v], want = [Hallo Welt]", custom3[0], custom3[1])
 	}
 }
+
+func TestJsonc_Unmarshal_MissingOrNullList(t *testing.T) {
+	for _, j := range []string{`{"c":4711}`, `{"bList":null,"c":4711}`} {
+		a := &A{Bs: []B{{Foo: "Old"}}}
+		err := ObjectFromJson([]byte(j), a)
+
+		if err != nil {
+			t.Errorf("ObjectFromJson - unexpected error %v", err)
+		}
+
+		if a.C != 4711 {
+			t.Errorf("ObjectFromJson - basic elements = {C: %d}, want = {C: 4711}", a.C)
+		}
+	}
+}
+
+func TestJsonc_Unmarshal_ErrorOnTrailingData(t *testing.T) {
+	err := ObjectFromJson([]byte(`{"c":4711} {"c":4712}`), &A{})
+
+	if err == nil {
+		t.Errorf("ObjectFromJson - error expected for data after the top-level object")
+	}
+}
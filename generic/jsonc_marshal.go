@@ -1,12 +1,19 @@
 package generic
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 )
 
+/*
+Marshals a pointer of struct to json.
+Handles `json:...` tags and `jsonc:...` tags for flattening. The object keys are written in sorted order.
+The struct is written in a single pass directly to the output, no intermediate map is built.
+*/
 func JsonFromObject(o interface{}) ([]byte, error) {
 	// is it a pointer of struct?
 	structValue, ok := pointerOfStruct(&o)
@@ -14,130 +21,159 @@ func JsonFromObject(o interface{}) ([]byte, error) {
 		return nil, errors.New("input is not a pointer of struct")
 	}
 
-	// Convert the struct to a map
-	mapValue, err := mapFromStruct(structValue)
+	info, err := cachedStructInfo(structValue.Type())
 	if err != nil {
 		return nil, err
 	}
 
-	// Marshal the map to a json string
-	j, err := json.Marshal(mapValue)
+	buf := &bytes.Buffer{}
+	state := &encodeState{buf: buf, encoder: json.NewEncoder(buf)}
+	err = state.encodeStruct(info, *structValue)
 	if err != nil {
 		return nil, err
 	}
 
-	return j, nil
+	return buf.Bytes(), nil
 }
 
 /*
-Maps a given struct to a map.
-Handles `json:...` tags and `jsonc:...` tags for flattening.
-Returns the map or an error
+Writes a given struct as json object to `buf`.
+The known fields and the entries of the `jsonc:"flat"` map are merged in sorted key order. On a name
+clash the field declared later wins - a flat entry wins over fields declared before the flat field.
 */
-func mapFromStruct(structValue *reflect.Value) (*map[string]interface{}, error) {
-	targetMap := make(map[string]interface{})
-	structType := structValue.Type()
-
-	// Iterate over the struct fields
-	for i := 0; i < structValue.NumField(); i++ {
-		fieldType := structType.Field(i)
-		fieldValue := structValue.Field(i)
-		jsonCTag := getJsonTag(&fieldType, "jsonc")
-
-		// If no `jsonc` tag: Just add the field into the map - maybe has `json`-Tags
-		if jsonCTag == nil {
-			insertTaggedFieldIntoMap(&targetMap, &fieldType, &fieldValue)
+func (state *encodeState) encodeStruct(info *structInfo, structValue reflect.Value) error {
+	buf := state.buf
+	buf.WriteByte('{')
+
+	var flatKeys []reflect.Value
+	var flatMap reflect.Value
+	if info.flat != nil {
+		flatMap = info.flat.value(structValue, false)
+		if flatMap.IsValid() {
+			flatKeys = flatMap.MapKeys()
+		}
+		sort.Slice(flatKeys, func(i, j int) bool {
+			return flatKeys[i].String() < flatKeys[j].String()
+		})
+	}
+
+	first := true
+	fields := info.sorted
+	for len(fields) > 0 || len(flatKeys) > 0 {
+		var field *fieldInfo
+		var flatKey reflect.Value
+
+		switch {
+		case len(flatKeys) == 0:
+			field, fields = fields[0], fields[1:]
+		case len(fields) == 0:
+			flatKey, flatKeys = flatKeys[0], flatKeys[1:]
+		case fields[0].name < flatKeys[0].String():
+			field, fields = fields[0], fields[1:]
+		case fields[0].name > flatKeys[0].String():
+			flatKey, flatKeys = flatKeys[0], flatKeys[1:]
+		default:
+			// same name
+			if info.flat.before(fields[0]) {
+				field = fields[0]
+			} else {
+				flatKey = flatKeys[0]
+			}
+			fields, flatKeys = fields[1:], flatKeys[1:]
+		}
+
+		if field != nil {
+			fieldValue := field.value(structValue, false)
+			if !fieldValue.IsValid() {
+				// within a nil embedded struct
+				continue
+			}
+			if field.omitEmpty && isEmptyValue(&fieldValue) {
+				continue
+			}
+
+			writeSeparator(buf, &first)
+			buf.Write(field.quoted)
+			buf.WriteByte(':')
+
+			var err error
+			if field.collection {
+				err = state.encodeCollection(field, fieldValue)
+			} else {
+				err = state.encodeValue(fieldValue)
+			}
+			if err != nil {
+				return errors.New(fmt.Sprintf("error: on field %s: %s", field.name, err.Error()))
+			}
 		} else {
-			switch jsonCTag.Name {
-			// `jsonc:"flat"` -> Must be a map. Flatten it to the target Map.
-			case "flat":
-				err := insertReflectMapIntoMap(&targetMap, &fieldValue)
-				if err != nil {
-					return nil, errors.New(fmt.Sprintf("error: on collection %s: %s", fieldType.Name, err.Error()))
-				}
-				break
-			// `jsonc:"collection"` -> Must be a slice. Handle each element recursive with `mapFromStruct`
-			case "collection":
-				err := handleCollection(&targetMap, &fieldType, &fieldValue)
-				if err != nil {
-					return nil, errors.New(fmt.Sprintf("error: on collection %s: %s", fieldType.Name, err.Error()))
-				}
-				break
+			writeSeparator(buf, &first)
+			err := state.encodeValue(flatKey)
+			if err != nil {
+				return errors.New(fmt.Sprintf("error: on flat key %s: %s", flatKey.String(), err.Error()))
+			}
+			buf.WriteByte(':')
+
+			err = state.encodeValue(flatMap.MapIndex(flatKey))
+			if err != nil {
+				return errors.New(fmt.Sprintf("error: on flat key %s: %s", flatKey.String(), err.Error()))
 			}
 		}
 	}
 
-	return &targetMap, nil
+	buf.WriteByte('}')
+	return nil
 }
 
 /*
- * Handles `json:"collection"`
- * Must be a slice. Then handle every slice element as a struct and call it with `mapFromStruct`. Then insert this new
- * map into the `targetMap`.
+ * Handles `jsonc:"collection"`
+ * Writes every slice element as a struct with `encodeStruct`. A nil slice is written as empty list.
  */
-func handleCollection(targetMapPtr *map[string]interface{}, fieldType *reflect.StructField, fieldValue *reflect.Value) error {
-	if fieldValue.Kind() != reflect.Slice {
-		return errors.New("is not a slice")
+func (state *encodeState) encodeCollection(field *fieldInfo, fieldValue reflect.Value) error {
+	buf := state.buf
+	elementInfo, err := cachedStructInfo(field.typ.Elem())
+	if err != nil {
+		return err
 	}
 
-	slice := make([]map[string]interface{}, fieldValue.Len())
+	buf.WriteByte('[')
 	for i := 0; i < fieldValue.Len(); i++ {
-		structItem := fieldValue.Index(i)
-		mapItem, err := mapFromStruct(&structItem)
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		err := state.encodeStruct(elementInfo, fieldValue.Index(i))
 		if err != nil {
 			return errors.New(fmt.Sprintf("error: Can not convert item %d: %s", i, err.Error()))
 		}
-
-		slice[i] = *mapItem
 	}
-
-	v := reflect.ValueOf(slice)
-	insertTaggedFieldIntoMap(targetMapPtr, fieldType, &v)
+	buf.WriteByte(']')
 
 	return nil
 }
 
-// `fieldValue` must be a Map. Then insert every element into the target map.
-func insertReflectMapIntoMap(targetMapPtr *map[string]interface{}, fieldValue *reflect.Value) error {
-	targetMap := *targetMapPtr
-	if fieldValue.Kind() != reflect.Map {
-		return errors.New("is not a map")
-	}
+// encodeState is the state of a single `JsonFromObject` call.
+type encodeState struct {
+	buf     *bytes.Buffer
+	encoder *json.Encoder // writes to buf
+}
 
-	// flat process
-	iter := fieldValue.MapRange()
-	for iter.Next() {
-		targetMap[iter.Key().String()] = iter.Value().Interface()
+// Writes a plain value with the standard `encoding/json` marshaller.
+func (state *encodeState) encodeValue(value reflect.Value) error {
+	err := state.encoder.Encode(value.Interface())
+	if err != nil {
+		return err
 	}
 
+	// The encoder terminates each value with a newline
+	state.buf.Truncate(state.buf.Len() - 1)
 	return nil
 }
 
-/*
- * Handles a field and its value based on the given tags and insert it into the given map.
- * eg: A field "A -> "foo" without any tag, will result in map["A"] -> "foo"
-       A field "A -> "foo" `json:customA` will result in map["customA"] -> "foo"
-*/
-func insertTaggedFieldIntoMap(targetMapPtr *map[string]interface{}, fieldType *reflect.StructField, fieldValue *reflect.Value) {
-	tag := getJsonTag(fieldType, "json")
-	targetMap := *targetMapPtr
-
-	// no tag -> original name
-	if tag == nil {
-		targetMap[fieldType.Name] = fieldValue.Interface()
-		return
-	}
-
-	// - -> omit value
-	if tag.Name == "-" {
-		return
-	}
-
-	// OmitEmpty and is empty -> omit value
-	if tag.OmitEmpty && isEmptyValue(fieldValue) {
-		return
+func writeSeparator(buf *bytes.Buffer, first *bool) {
+	if *first {
+		*first = false
 	} else {
-		targetMap[tag.Name] = fieldValue.Interface()
+		buf.WriteByte(',')
 	}
 }
 
@@ -197,3 +197,59 @@ func TestJsonc_JsonFromObject_WrongTags(t *testing.T) {
 		t.Errorf("JsonFromObject - no error, want error for wrong use of jsonc:collection.")
 	}
 }
+
+type embeddedBase struct {
+	Id   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// Exported, because embedded pointers and tagged embedded structs of unexported types are ignored
+type EmbeddedPosition struct {
+	Lat float64 `json:"lat"`
+}
+
+type EmbeddedRef struct {
+	Id string `json:"id"`
+}
+
+type embeddedFragments struct {
+	AdditionalFields map[string]interface{} `jsonc:"flat"`
+}
+
+// Embeds structs like `encoding/json`: untagged ones are promoted, tagged ones are nested objects.
+type embeddingObject struct {
+	embeddedBase
+	*EmbeddedPosition
+	EmbeddedRef `json:"ref"`
+	Type        string `json:"type"` // hides embeddedBase.Type
+	embeddedFragments
+}
+
+func TestJsonc_PromotesEmbeddedStructs(t *testing.T) {
+	a := &embeddingObject{
+		embeddedBase:      embeddedBase{Id: "1", Type: "hidden"},
+		EmbeddedRef:       EmbeddedRef{Id: "2"},
+		Type:              "t",
+		embeddedFragments: embeddedFragments{AdditionalFields: map[string]interface{}{"custom": 1}},
+	}
+
+	j, err := JsonFromObject(a)
+
+	if err != nil {
+		t.Errorf("JsonFromObject - unexpected error %v", err)
+	}
+
+	// then: The nil embedded pointer is left out
+	want := `{"custom":1,"id":"1","ref":{"id":"2"},"type":"t"}`
+	if string(j) != want {
+		t.Errorf("JsonFromObject\n json = %v\n want %v", string(j), want)
+	}
+
+	a.EmbeddedPosition = &EmbeddedPosition{Lat: 52.5}
+	a.AdditionalFields = nil
+	j, _ = JsonFromObject(a)
+	want = `{"id":"1","lat":52.5,"ref":{"id":"2"},"type":"t"}`
+	if string(j) != want {
+		t.Errorf("JsonFromObject\n json = %v\n want %v", string(j), want)
+	}
+}
@@ -1,9 +1,11 @@
 package generic
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 )
 
@@ -11,115 +13,196 @@ import (
 	Takes a json as []byte and a pointer of the target struct
 	Returns an error, otherwise fills the `targetStruct` reference
 	with values.
+
+	The json is read in a single pass: known fields are decoded directly into the struct,
+	all other fields are collected in the `jsonc:"flat"` map (if any).
 */
 func ObjectFromJson(j []byte, targetStruct interface{}) error {
+	state, err := objectFromJson(j, targetStruct, decodeDefault)
+	if err != nil {
+		return err
+	}
+	if state.typeErr != nil {
+		return errors.New(fmt.Sprintf("Error while unmarshaling json: %v", state.typeErr))
+	}
+
+	return nil
+}
+
+type decodeMode int
+
+const (
+	// The first type mismatch is reported, unknown fields are ignored. Like `json.Unmarshal`.
+	decodeDefault decodeMode = iota
+	// Every mismatch and unknown field is reported.
+	decodeStrict
+	// Like strict, but elements of a `jsonc:"collection"` with mismatches are skipped.
+	decodeLenient
+)
+
+func objectFromJson(j []byte, targetStruct interface{}, mode decodeMode) (*decodeState, error) {
 	// is it a pointer of struct?
 	structValue, ok := pointerOfStruct(&targetStruct)
 	if ok == false {
-		return errors.New("input is not a pointer of struct")
+		return nil, errors.New("input is not a pointer of struct")
 	}
 
-	// First - let json unmarshal to the target struct as far as it gets
-	err := json.Unmarshal(j, targetStruct)
+	info, err := cachedStructInfo(structValue.Type())
 	if err != nil {
-		return errors.New(fmt.Sprintf("Error while unmarshaling json: %v", err))
+		return nil, err
 	}
 
-	// Second - Unmarshal json to a generic map: string -> interface
-	// to have all data as raw fields as working structure.
-	var fieldsMap map[string]interface{}
-	err = json.Unmarshal(j, &fieldsMap)
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	state := &decodeState{decoder: decoder, mode: mode}
+
+	err = state.decodeStruct(info, *structValue, "")
+	if err == nil {
+		if _, tokenErr := decoder.Token(); tokenErr != io.EOF {
+			err = errors.New("invalid data after top-level value")
+		}
+	}
 	if err != nil {
-		return errors.New(fmt.Sprintf("Error while unmarshaling json: %v", err))
+		return nil, errors.New(fmt.Sprintf("Error while unmarshaling json: %v", err))
 	}
 
-	return mergeMapWithStruct(&fieldsMap, structValue)
+	return state, nil
 }
 
 /*
-	Merges the given object map into the struct.
-	`structMapPtr` is a pointer of the object map
-	`structValue` is the reflection Value of the struct object
+decodeState is the state of a single `ObjectFromJson` call.
+Like `json.Unmarshal`, a value with a mismatching type does not abort the decoding. The first
+such error is kept in `typeErr`. In strict and lenient mode all mismatches are collected in `issues`.
+*/
+type decodeState struct {
+	decoder *json.Decoder
+	mode    decodeMode
+	typeErr error
+	issues  []DecodeIssue
+}
 
-	Returns an error, otherwise fills the `structValue` reference
-	with values
+/*
+	Decodes the next json object of the stream into the given struct.
+	`info` is the metadata of the struct
+	`structValue` is the reflection Value of the struct object
+	`path` is the JSON path of the object, used for diagnostics
 */
-func mergeMapWithStruct(structMapPtr *map[string]interface{}, structValue *reflect.Value) error {
-	structMap := *structMapPtr
-	structType := structValue.Type()
-
-	// Found field for "jsonc:"flat"" inside the struct
-	var flatFieldName string
-
-	// Iterate over all fields of the struct
-	for i := 0; i < structType.NumField(); i++ {
-		// Represents a single fields type and value
-		fieldType := structType.Field(i)
-		fieldValue := structValue.Field(i)
-
-		// Get tag values of the field
-		jsonCTag := getJsonTag(&fieldType, "jsonc")
-		jsonTag := getJsonTag(&fieldType, "json")
-
-		if jsonCTag != nil {
-			switch jsonCTag.Name {
-			// The field is tagged with `jsonc:"flat"` -> set `flatFieldName`
-			case "flat":
-				if fieldValue.Kind() != reflect.Map {
-					return errors.New(fmt.Sprintf("error: Field %s is not a map! Can not deflat it.", fieldType.Name))
-				}
-
-				flatFieldName = fieldType.Name
-				break
-			case "collection":
-				// The field is tagged with `jsonc:"collection"`. Handle all elements as an flatted struct
-				if fieldValue.Kind() != reflect.Slice {
-					return errors.New(fmt.Sprintf("error: Field %s ist not a slice! Can not use it as collection", fieldType.Name))
-				}
-
-				// What is the json name of the collection in the `structMap`?
-				var jsonFieldName string
-				if jsonTag != nil {
-					jsonFieldName = jsonTag.Name
-				} else {
-					jsonFieldName = fieldType.Name
-				}
-
-				// Get the collection from the `structMap`
-				collection := structMap[jsonFieldName].([]interface{})
-
-				// Iterate over each collection element
-				for i := 0; i < fieldValue.Len(); i++ {
-					// The collection element from the struct value and the struct map collection
-					structElement := fieldValue.Index(i)
-					mapElement, ok := collection[i].(map[string]interface{})
-					if !ok {
-						return errors.New(fmt.Sprintf("error: Element of jsonc:collection field %s is not a map!", fieldType.Name))
-					}
-
-					// Call this function recursively with the collection element.
-					err := mergeMapWithStruct(&mapElement, &structElement)
-					if err != nil {
-						return errors.New(fmt.Sprintf("error: Can not unmarshaling jsonc:collection field %s", fieldType.Name))
-					}
-				}
-				break
+func (state *decodeState) decodeStruct(info *structInfo, structValue reflect.Value, path string) error {
+	token, err := state.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		// null -> leave the struct untouched
+		return nil
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("cannot unmarshal %v into Go value of type %s", token, structValue.Type())
+	}
+
+	// The map of the `jsonc:"flat"` field collects all unknown fields
+	var flatMap reflect.Value
+	if info.flat != nil {
+		flatMap = reflect.MakeMap(info.flat.typ)
+		info.flat.value(structValue, true).Set(flatMap)
+	}
+
+	for state.decoder.More() {
+		token, err := state.decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		keyPath := joinPath(path, key)
+
+		field := info.field(key)
+		switch {
+		case field != nil && field.collection:
+			err = state.decodeCollection(field, field.value(structValue, true), keyPath)
+		case field != nil:
+			err = state.decodeValue(field.value(structValue, true).Addr().Interface(), keyPath)
+		case info.flat != nil && !info.goNames[key]:
+			value := reflect.New(info.flat.typ.Elem())
+			err = state.decodeValue(value.Interface(), keyPath)
+			flatMap.SetMapIndex(reflect.ValueOf(key).Convert(info.flat.typ.Key()), value.Elem())
+		default:
+			var skipped json.RawMessage
+			err = state.decoder.Decode(&skipped)
+			if err == nil && state.mode != decodeDefault && !info.goNames[key] {
+				state.issues = append(state.issues, DecodeIssue{Path: keyPath, Actual: jsonKind(skipped)})
 			}
 		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// closing '}'
+	_, err = state.decoder.Token()
+	return err
+}
+
+/*
+	Decodes the next json array of the stream into the `jsonc:"collection"` field.
+	Each element is handled as a flatted struct.
+*/
+func (state *decodeState) decodeCollection(field *fieldInfo, fieldValue reflect.Value, path string) error {
+	elementInfo, err := cachedStructInfo(field.typ.Elem())
+	if err != nil {
+		return err
+	}
+
+	if state.mode != decodeDefault {
+		return state.diagnoseCollection(elementInfo, field, fieldValue, path)
+	}
+
+	token, err := state.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		fieldValue.Set(reflect.Zero(field.typ))
+		return nil
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("cannot unmarshal %v into jsonc:collection field %s", token, field.name)
+	}
 
-		// At the end, `structMap` must contain only the "non struct" fields.
-		// Therefore, delete all "known" struct fields from the `structMap`
-		if jsonTag != nil {
-			delete(structMap, jsonTag.Name)
+	slice := reflect.MakeSlice(field.typ, 0, 0)
+	for i := 0; state.decoder.More(); i++ {
+		element := reflect.New(field.typ.Elem()).Elem()
+		err := state.decodeStruct(elementInfo, element, fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return errors.New(fmt.Sprintf("error: Can not unmarshal element %d of jsonc:collection field %s: %s", i, field.name, err.Error()))
 		}
-		delete(structMap, structType.Field(i).Name)
+		slice = reflect.Append(slice, element)
 	}
+	fieldValue.Set(slice)
 
-	// Add the structMap as value of the struct field `flatFieldName` or `jsonc:"flat"`
-	if flatFieldName != "" {
-		field := structValue.FieldByName(flatFieldName)
-		field.Set(reflect.ValueOf(structMap))
+	// closing ']'
+	_, err = state.decoder.Token()
+	return err
+}
+
+// Decodes the next json value of the stream with the standard `encoding/json` unmarshaller.
+func (state *decodeState) decodeValue(target interface{}, path string) error {
+	if state.mode != decodeDefault {
+		return state.diagnoseValue(target, path)
 	}
 
-	return nil
+	err := state.decoder.Decode(target)
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+		if state.typeErr == nil {
+			state.typeErr = typeErr
+		}
+		return nil
+	}
+
+	return err
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
 }
@@ -146,3 +146,25 @@ func TestJsonc_ObjectFromJson_WrongTags(t *testing.T) {
 		t.Errorf("JsonFromObject - no error, want error for wrong use of jsonc:collection.")
 	}
 }
+
+func TestJsonc_ObjectFromJson_PromotesEmbeddedStructs(t *testing.T) {
+	a := &embeddingObject{}
+
+	err := ObjectFromJson([]byte(`{"id": "1", "type": "t", "lat": 52.5, "ref": {"id": "2"}, "custom": 1}`), a)
+
+	if err != nil {
+		t.Errorf("ObjectFromJson - unexpected error %v", err)
+	}
+
+	// then: The embedded pointer is allocated and unknown fields are collected in the embedded flat map
+	want := &embeddingObject{
+		embeddedBase:      embeddedBase{Id: "1"},
+		EmbeddedPosition:  &EmbeddedPosition{Lat: 52.5},
+		EmbeddedRef:       EmbeddedRef{Id: "2"},
+		Type:              "t",
+		embeddedFragments: embeddedFragments{AdditionalFields: map[string]interface{}{"custom": float64(1)}},
+	}
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("ObjectFromJson - object = %+v, want %+v", a, want)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"time"
 )
 
 /*
@@ -13,13 +14,15 @@ See: https://cumulocity.com/guides/reference/rest-implementation/#pagingstatisti
 type PagingStatistics struct {
 	TotalRecords int `json:"totalRecords,omitempty"`
 	TotalPages   int `json:"totalPages,omitempty"`
-	PageSize     int `json:"pageSize"`
-	CurrentPage  int `json:"currentPage"`
+	// Only set, when the request asked for it, e.g. with 'withTotalElements=true'
+	TotalElements int `json:"totalElements,omitempty"`
+	PageSize      int `json:"pageSize"`
+	CurrentPage   int `json:"currentPage"`
 }
 
 // Appends the query param 'pageSize' to the provided parameter values for a request.
 // When provided values is nil an error will be created
-func PageSizeParameter(pageSize int, params *url.Values) (error) {
+func PageSizeParameter(pageSize int, params *url.Values) error {
 	if pageSize < 1 || pageSize > 2000 {
 		return fmt.Errorf("The page size must be between 1 and 2000. Was %d", pageSize)
 	}
@@ -32,3 +35,19 @@ func PageSizeParameter(pageSize int, params *url.Values) (error) {
 	return nil
 }
 
+// Appends the query params '<name>From' and '<name>To' of a time window, e.g. 'dateFrom' and 'dateTo', for the
+// times, which are set. An error is returned, if the window is reverted.
+func TimeWindowParameters(name string, from *time.Time, to *time.Time, params *url.Values) error {
+	if from != nil && to != nil && from.After(*to) {
+		return fmt.Errorf("failed to build filter: '%sFrom' must not be after '%sTo'.", name, name)
+	}
+
+	if from != nil {
+		params.Add(name+"From", from.Format(time.RFC3339))
+	}
+	if to != nil {
+		params.Add(name+"To", to.Format(time.RFC3339))
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package generic
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTimeWindowParameters(t *testing.T) {
+	from := time.Date(2020, 6, 30, 10, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	params := url.Values{}
+	if err := TimeWindowParameters("date", &from, &to, &params); err != nil {
+		t.Fatalf("TimeWindowParameters() got an unexpected error: %s", err.Error())
+	}
+	if want := "dateFrom=2020-06-30T10%3A00%3A00Z&dateTo=2020-06-30T11%3A00%3A00Z"; params.Encode() != want {
+		t.Errorf("TimeWindowParameters() = %s, want %s", params.Encode(), want)
+	}
+
+	params = url.Values{}
+	if err := TimeWindowParameters("created", nil, &to, &params); err != nil || params.Encode() != "createdTo=2020-06-30T11%3A00%3A00Z" {
+		t.Errorf("TimeWindowParameters() = %s, %v - want only createdTo", params.Encode(), err)
+	}
+
+	if err := TimeWindowParameters("lastUpdated", &to, &from, &url.Values{}); err == nil {
+		t.Errorf("TimeWindowParameters() expected an error for a reverted window")
+	}
+}
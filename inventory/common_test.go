@@ -79,8 +79,9 @@ var expectedManagedObject = &ManagedObject{
 		} "json:\"managedObject,omitempty\""
 		Self string "json:\"self,omitempty\""
 	}{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/childAdditions"},
-	ChildAssets:  ChildAssets{References: []interface{}{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/childAssets"},
-	ChildDevices: ChildDevices{References: []interface{}{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/childDevices"},
+	ChildAssets:      ChildAssets{References: []interface{}{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/childAssets"},
+	ChildDevices:     ChildDevices{References: []interface{}{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/childDevices"},
+	AdditionalFields: map[string]interface{}{},
 }
 
 var givenResponseBody = `{
@@ -183,8 +184,9 @@ var managedObjectCollectionTemplate = `{
 }`
 
 var childManagedObject = &ManagedObject{
-	Id:           "4711",
-	Self:         "https://t0815.cumulocity.com/inventory/managedObjects/4711",
+	Id:               "4711",
+	Self:             "https://t0815.cumulocity.com/inventory/managedObjects/4711",
+	AdditionalFields: map[string]interface{}{},
 }
 
 var expectedManagedObjectReference = &ManagedObjectReference{
@@ -25,6 +25,11 @@ type InventoryApi interface {
 
 	Update(managedObjectId string, managedObject *ManagedObjectUpdate) (*ManagedObject, *generic.Error)
 
+	// Compares a managed object before and after local modifications and updates only the changed fields.
+	// Removed fragments are set to null. Changes of read-only fields, e.g. the owner or the references, are not sent.
+	// If nothing has changed, `before` is returned without a request.
+	UpdateChanges(before *ManagedObject, after *ManagedObject) (*ManagedObject, *generic.Error)
+
 	// Deletion by managedObject id. If error is nil, managed object was deleted successfully.
 	Delete(managedObjectId string) *generic.Error
 
@@ -64,7 +69,7 @@ Creates a new managed object based on the given variables.
 See: https://cumulocity.com/guides/reference/inventory/#post-create-a-new-managedobject
 */
 func (inventoryApi *inventoryApi) Create(newManagedObject *NewManagedObject) (*ManagedObject, *generic.Error) {
-	bytes, err := json.Marshal(newManagedObject)
+	bytes, err := generic.JsonFromObject(newManagedObject)
 	if err != nil {
 		return nil, generic.ClientError(fmt.Sprintf("Error while marshalling the managedObject: %s", err.Error()), "CreateManagedObject")
 	}
@@ -117,23 +122,40 @@ func (inventoryApi *inventoryApi) Update(managedObjectId string, managedObject *
 	if len(managedObjectId) == 0 {
 		return nil, generic.ClientError("Updating managedObject without an id is not allowed", "UpdateManagedObject")
 	}
-	bytes, err := json.Marshal(managedObject)
+	if managedObject == nil {
+		managedObject = &ManagedObjectUpdate{}
+	}
+	bytes, err := generic.JsonFromObject(managedObject)
 	if err != nil {
 		return nil, generic.ClientError(fmt.Sprintf("Error while marshalling the update managedObject: %s", err.Error()), "UpdateManagedObject")
 	}
 
-	path := fmt.Sprintf("%s/%s", inventoryApi.basePath, url.QueryEscape(managedObjectId))
-	headers := generic.AcceptAndContentTypeHeader(MANAGED_OBJECT_TYPE, MANAGED_OBJECT_TYPE)
+	return inventoryApi.update(managedObjectId, bytes, "UpdateManagedObject")
+}
 
-	body, status, err := inventoryApi.client.Put(path, bytes, headers)
+/*
+Updates the changed fields and fragments of a managedObject. `before` is the managedObject as received
+from cumulocity, `after` the locally modified copy.
+*/
+func (inventoryApi *inventoryApi) UpdateChanges(before *ManagedObject, after *ManagedObject) (*ManagedObject, *generic.Error) {
+	if before == nil || after == nil || len(before.Id) == 0 {
+		return nil, generic.ClientError("Updating changes needs an existing managedObject with id before and after the changes", "UpdateManagedObjectChanges")
+	}
+
+	diff, err := generic.UpdateDiff(before, after, managedObjectReadOnlyFields...)
 	if err != nil {
-		return nil, generic.ClientError(fmt.Sprintf("Error while updating a managedObject: %s", err.Error()), "UpdateManagedObject")
+		return nil, generic.ClientError(fmt.Sprintf("Error while comparing the managedObjects: %s", err.Error()), "UpdateManagedObjectChanges")
 	}
-	if status != http.StatusOK {
-		return nil, generic.CreateErrorFromResponse(body, status)
+	if len(diff) == 0 {
+		return before, nil
 	}
 
-	return parseManagedObjectResponse(body)
+	bytes, err := json.Marshal(diff)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while marshalling the managedObject changes: %s", err.Error()), "UpdateManagedObjectChanges")
+	}
+
+	return inventoryApi.update(before.Id, bytes, "UpdateManagedObjectChanges")
 }
 
 /*
@@ -203,6 +225,21 @@ func (inventoryApi *inventoryApi) PreviousPage(c *ManagedObjectCollection) (*Man
 
 // -- internal
 
+func (inventoryApi *inventoryApi) update(managedObjectId string, bytes []byte, operation string) (*ManagedObject, *generic.Error) {
+	path := fmt.Sprintf("%s/%s", inventoryApi.basePath, url.QueryEscape(managedObjectId))
+	headers := generic.AcceptAndContentTypeHeader(MANAGED_OBJECT_TYPE, MANAGED_OBJECT_TYPE)
+
+	body, status, err := inventoryApi.client.Put(path, bytes, headers)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while updating a managedObject: %s", err.Error()), operation)
+	}
+	if status != http.StatusOK {
+		return nil, generic.CreateErrorFromResponse(body, status)
+	}
+
+	return parseManagedObjectResponse(body)
+}
+
 func (inventoryApi *inventoryApi) getPage(reference string) (*ManagedObjectCollection, *generic.Error) {
 	if reference == "" {
 		log.Print("No page reference given. Returning nil.")
@@ -239,7 +276,7 @@ func (inventoryApi *inventoryApi) getCommon(path string) (*ManagedObjectCollecti
 
 	var result ManagedObjectCollection
 	if len(body) > 0 {
-		err = json.Unmarshal(body, &result)
+		err = generic.ObjectFromJson(body, &result)
 		if err != nil {
 			return nil, generic.ClientError(fmt.Sprintf("Error while parsing response JSON: %s", err.Error()), "GetManagedObjectCollection")
 		}
@@ -253,7 +290,7 @@ func (inventoryApi *inventoryApi) getCommon(path string) (*ManagedObjectCollecti
 func parseManagedObjectResponse(body []byte) (*ManagedObject, *generic.Error) {
 	var result ManagedObject
 	if len(body) > 0 {
-		err := json.Unmarshal(body, &result)
+		err := generic.ObjectFromJson(body, &result)
 		if err != nil {
 			return nil, generic.ClientError(fmt.Sprintf("Error while parsing response JSON: %s", err.Error()), "ResponseParser")
 		}
@@ -0,0 +1,206 @@
+package inventory
+
+import (
+	jsoncompare "github.com/orasik/gocomparejson"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+var fragmentsResponseBody = `{
+	"id": "9963944",
+	"type": "test-type",
+	"name": "Test Device",
+	"creationTime": "2020-07-03T10:16:35.870+02:00",
+	"lastUpdated": "2020-07-03T10:16:35.870+02:00",
+	"self": "https://t0815.cumulocity.com/inventory/managedObjects/9963944",
+	"owner": "gomulocity",
+	"c8y_Hardware": {"model": "RPi 4", "serialNumber": "0815"},
+	"c8y_Status": {"status": "Up", "details": {"restarts": 2}},
+	"custom_Location": {"room": "B 1.02", "floor": 1}
+}`
+
+func TestInventoryApi_Get_CustomFragments(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte(fragmentsResponseBody))
+	}))
+	defer testServer.Close()
+
+	inventoryApi := buildInventoryApi(testServer)
+	managedObject, err := inventoryApi.Get(managedObjectId)
+	if err != nil {
+		t.Fatalf("received an unexpected error: %v", err)
+	}
+
+	// known fragments are decoded into their fields
+	if managedObject.C8YHardware == nil || managedObject.C8YHardware.SerialNumber != "0815" {
+		t.Errorf("unexpected c8y_Hardware: %#v", managedObject.C8YHardware)
+	}
+
+	expectedFields := map[string]interface{}{
+		"c8y_Status":      map[string]interface{}{"status": "Up", "details": map[string]interface{}{"restarts": float64(2)}},
+		"custom_Location": map[string]interface{}{"room": "B 1.02", "floor": float64(1)},
+	}
+	if !reflect.DeepEqual(managedObject.AdditionalFields, expectedFields) {
+		t.Errorf("unexpected additional fields: %#v\nExpected: %#v", managedObject.AdditionalFields, expectedFields)
+	}
+}
+
+func TestInventoryApi_Find_CustomFragments(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte(`{"managedObjects": [` + fragmentsResponseBody + `], "statistics": {"pageSize": 5}}`))
+	}))
+	defer testServer.Close()
+
+	inventoryApi := buildInventoryApi(testServer)
+	collection, err := inventoryApi.Find(&InventoryFilter{}, 5)
+	if err != nil {
+		t.Fatalf("received an unexpected error: %v", err)
+	}
+
+	if len(collection.ManagedObjects) != 1 {
+		t.Fatalf("unexpected number of managed objects: %d", len(collection.ManagedObjects))
+	}
+	if _, ok := collection.ManagedObjects[0].AdditionalFields["custom_Location"]; !ok {
+		t.Errorf("custom_Location is missing in %#v", collection.ManagedObjects[0].AdditionalFields)
+	}
+}
+
+func TestInventoryApi_CreateAndUpdate_CustomFragments(t *testing.T) {
+	var reqBody string
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		reqBodyBytes, _ := ioutil.ReadAll(req.Body)
+		reqBody = string(reqBodyBytes)
+
+		if req.Method == http.MethodPost {
+			res.WriteHeader(http.StatusCreated)
+		} else {
+			res.WriteHeader(http.StatusOK)
+		}
+		_, _ = res.Write([]byte(fragmentsResponseBody))
+	}))
+	defer testServer.Close()
+
+	inventoryApi := buildInventoryApi(testServer)
+
+	_, err := inventoryApi.Create(&NewManagedObject{
+		Type: "test-type",
+		Name: "Test Device",
+		AdditionalFields: map[string]interface{}{
+			"c8y_IsDevice":    map[string]interface{}{},
+			"custom_Location": map[string]interface{}{"room": "B 1.02", "floor": 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("received an unexpected error on create: %v", err)
+	}
+
+	expectedRequestBody := `{"type":"test-type","name":"Test Device","c8y_IsDevice":{},"custom_Location":{"floor":1,"room":"B 1.02"}}`
+	if equal, _ := jsoncompare.CompareJSON(reqBody, expectedRequestBody); !equal {
+		t.Errorf("processed an unexpected c8y create request body %q\nExpected: %q", reqBody, expectedRequestBody)
+	}
+
+	_, err = inventoryApi.Update(managedObjectId, &ManagedObjectUpdate{
+		Name: "renamed Test Device",
+		AdditionalFields: map[string]interface{}{
+			"custom_Location": map[string]interface{}{"room": "C 2.01"},
+			"c8y_Status":      nil,
+		},
+	})
+	if err != nil {
+		t.Fatalf("received an unexpected error on update: %v", err)
+	}
+
+	expectedRequestBody = `{"name":"renamed Test Device","c8y_Status":null,"custom_Location":{"room":"C 2.01"}}`
+	if equal, _ := jsoncompare.CompareJSON(reqBody, expectedRequestBody); !equal {
+		t.Errorf("processed an unexpected c8y update request body %q\nExpected: %q", reqBody, expectedRequestBody)
+	}
+}
+
+func TestInventoryApi_UpdateChanges_CustomFragments(t *testing.T) {
+	var reqBody string
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		reqBodyBytes, _ := ioutil.ReadAll(req.Body)
+		reqBody = string(reqBodyBytes)
+
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte(fragmentsResponseBody))
+	}))
+	defer testServer.Close()
+
+	inventoryApi := buildInventoryApi(testServer)
+
+	// given: A managed object with a changed and a removed custom fragment
+	before := *expectedManagedObject
+	before.AdditionalFields = map[string]interface{}{
+		"custom_Location": map[string]interface{}{"room": "B 1.02"},
+		"c8y_Status":      map[string]interface{}{"status": "Up"},
+	}
+	after := before
+	after.AdditionalFields = map[string]interface{}{
+		"custom_Location": map[string]interface{}{"room": "C 2.01"},
+	}
+
+	_, err := inventoryApi.UpdateChanges(&before, &after)
+	if err != nil {
+		t.Fatalf("received an unexpected error: %v", err)
+	}
+
+	expectedRequestBody := `{"c8y_Status":null,"custom_Location":{"room":"C 2.01"}}`
+	if equal, _ := jsoncompare.CompareJSON(reqBody, expectedRequestBody); !equal {
+		t.Errorf("processed an unexpected c8y request body %q\nExpected: %q", reqBody, expectedRequestBody)
+	}
+}
+
+func TestInventoryReferenceApi_GetMany_CustomFragments(t *testing.T) {
+	for _, referenceType := range []ReferenceType{CHILD_ASSETS, CHILD_DEVICES} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.WriteHeader(http.StatusOK)
+			_, _ = res.Write([]byte(`{"references": [{"managedObject": ` + fragmentsResponseBody + `, "self": "https://t0815.cumulocity.com/inventory/managedObjects/4711/` +
+				string(referenceType) + `/9963944"}], "statistics": {"pageSize": 5}}`))
+		}))
+
+		inventoryReferenceApi := buildInventoryReferenceApi(testServer)
+		collection, err := inventoryReferenceApi.GetMany("4711", referenceType, 5)
+		testServer.Close()
+		if err != nil {
+			t.Fatalf("%s: received an unexpected error: %v", referenceType, err)
+		}
+
+		if len(collection.References) != 1 {
+			t.Fatalf("%s: unexpected number of references: %d", referenceType, len(collection.References))
+		}
+		managedObject := collection.References[0].ManagedObject
+		if managedObject.Id != "9963944" || managedObject.C8YHardware == nil || managedObject.C8YHardware.SerialNumber != "0815" {
+			t.Errorf("%s: unexpected managed object: %#v", referenceType, managedObject)
+		}
+		if _, ok := managedObject.AdditionalFields["c8y_Status"]; !ok {
+			t.Errorf("%s: c8y_Status is missing in %#v", referenceType, managedObject.AdditionalFields)
+		}
+		if _, ok := managedObject.AdditionalFields["custom_Location"]; !ok {
+			t.Errorf("%s: custom_Location is missing in %#v", referenceType, managedObject.AdditionalFields)
+		}
+	}
+}
+
+func TestInventoryReferenceApi_Get_CustomFragments(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte(`{"managedObject": ` + fragmentsResponseBody + `}`))
+	}))
+	defer testServer.Close()
+
+	inventoryReferenceApi := buildInventoryReferenceApi(testServer)
+	reference, err := inventoryReferenceApi.Get("4711", CHILD_DEVICES, "9963944")
+	if err != nil {
+		t.Fatalf("received an unexpected error: %v", err)
+	}
+
+	if _, ok := reference.ManagedObject.AdditionalFields["c8y_Status"]; !ok {
+		t.Errorf("c8y_Status is missing in %#v", reference.ManagedObject.AdditionalFields)
+	}
+}
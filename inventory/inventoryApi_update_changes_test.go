@@ -0,0 +1,95 @@
+package inventory
+
+import (
+	"fmt"
+	jsoncompare "github.com/orasik/gocomparejson"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestInventoryApi_UpdateChanges(t *testing.T) {
+	var reqURL, reqBody string
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		reqURL = req.URL.String()
+		reqBodyBytes, _ := ioutil.ReadAll(req.Body)
+		reqBody = string(reqBodyBytes)
+
+		res.WriteHeader(http.StatusOK)
+		_, _ = res.Write([]byte(givenResponseBody))
+	}))
+	defer testServer.Close()
+
+	inventoryApi := buildInventoryApi(testServer)
+
+	// given: A managed object with a changed name
+	before := *expectedManagedObject
+	after := before
+	after.Name = "renamed Test Device"
+
+	_, err := inventoryApi.UpdateChanges(&before, &after)
+	if err != nil {
+		t.Fatalf("received an unexpected error: %v", err)
+	}
+
+	expectedRequestBody := `{"name":"renamed Test Device"}`
+	if equal, _ := jsoncompare.CompareJSON(reqBody, expectedRequestBody); !equal {
+		t.Errorf("processed an unexpected c8y request body %q\nExpected: %q", reqBody, expectedRequestBody)
+	}
+
+	var expectedC8YRequestURL = fmt.Sprintf("/inventory/managedObjects/%s", managedObjectId)
+	if reqURL != expectedC8YRequestURL {
+		t.Errorf("unexpected c8y request url. Expected %q. Given: %q", expectedC8YRequestURL, reqURL)
+	}
+}
+
+func TestInventoryApi_UpdateChanges_WithoutReadOnlyFields(t *testing.T) {
+	called := false
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		called = true
+	}))
+	defer testServer.Close()
+
+	inventoryApi := buildInventoryApi(testServer)
+
+	// given: A managed object with changed read-only fields only
+	before := *expectedManagedObject
+	after := before
+	after.Owner = "someone else"
+	after.LastUpdated = creationTime.Add(time.Hour)
+	after.ChildDevices = ChildDevices{Self: "https://t0815.cumulocity.com/inventory/managedObjects/4711/childDevices"}
+
+	managedObject, err := inventoryApi.UpdateChanges(&before, &after)
+
+	if err != nil {
+		t.Fatalf("received an unexpected error: %v", err)
+	}
+	if called || managedObject != &before {
+		t.Errorf("sent a request with read-only changes only")
+	}
+}
+
+func TestInventoryApi_UpdateChanges_NoChanges(t *testing.T) {
+	called := false
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		called = true
+	}))
+	defer testServer.Close()
+
+	inventoryApi := buildInventoryApi(testServer)
+	before := *expectedManagedObject
+	after := before
+
+	managedObject, err := inventoryApi.UpdateChanges(&before, &after)
+	if err != nil {
+		t.Fatalf("received an unexpected error: %v", err)
+	}
+	if called {
+		t.Errorf("sent a request without changes")
+	}
+	if managedObject != &before {
+		t.Errorf("received an unexpected ManagedObject: %#v. \nExpected: %#v", managedObject, &before)
+	}
+}
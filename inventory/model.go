@@ -1,25 +1,36 @@
 package inventory
 
 import (
+	"encoding/json"
 	"github.com/tarent/gomulocity/generic"
 	"time"
 )
 
+/*
+Represents a new managed object. Custom fragments, e.g. `c8y_IsDevice` or `c8y_Hardware`, are
+put into `AdditionalFields` by their name.
+*/
 type NewManagedObject struct {
-	Type         string    `json:"type,omitempty"`
-	Name         string    `json:"name,omitempty"`
-	CreationTime *time.Time `json:"creationTime,omitempty"`
+	Type             string                 `json:"type,omitempty"`
+	Name             string                 `json:"name,omitempty"`
+	CreationTime     *time.Time             `json:"creationTime,omitempty"`
+	AdditionalFields map[string]interface{} `jsonc:"flat"`
 }
 
+/*
+Represents the changes of a managed object. Only set fields and the fragments in `AdditionalFields`
+are updated; a fragment with the value nil is removed.
+*/
 type ManagedObjectUpdate struct {
-	Type string `json:"type,omitempty"`
-	Name string `json:"name,omitempty"`
+	Type             string                 `json:"type,omitempty"`
+	Name             string                 `json:"name,omitempty"`
+	AdditionalFields map[string]interface{} `jsonc:"flat"`
 }
 
 type (
 	ManagedObjectCollection struct {
 		Self           string                    `json:"self"`
-		ManagedObjects []ManagedObject           `json:"managedObjects"`
+		ManagedObjects []ManagedObject           `json:"managedObjects" jsonc:"collection"`
 		Statistics     *generic.PagingStatistics `json:"statistics,omitempty"`
 		Prev           string                    `json:"prev,omitempty"`
 		Next           string                    `json:"next,omitempty"`
@@ -52,7 +63,9 @@ type (
 		C8YIsSensorPhone        *interface{}             `json:"c8y_IsSensorPhone,omitempty"`
 		C8YRequiredAvailability *C8YRequiredAvailability `json:"c8y_RequiredAvailability,omitempty"`
 		C8YSupportedOperations  *[]string                `json:"c8y_SupportedOperations,omitempty"`
-		C8YStatus               *C8YStatus               `json:"c8y_Status,omitempty"`
+
+		// All other fragments, e.g. `c8y_Status` or custom ones, by their name
+		AdditionalFields map[string]interface{} `jsonc:"flat"`
 	}
 
 	AssetParents struct {
@@ -109,40 +122,12 @@ type (
 		References []interface{} `json:"references"`
 		Self       string        `json:"self,omitempty"`
 	}
-
-	C8YStatus struct {
-		Details struct {
-			Active              int `json:"active,omitempty"`
-			AggregatedResources struct {
-				CPU    string `json:"cpu,omitempty"`
-				Memory string `json:"memory,omitempty"`
-			} `json:"aggregatedResources,omitempty"`
-			Desired  int `json:"desired,omitempty"`
-			Restarts int `json:"restarts,omitempty"`
-		} `json:"details,omitempty"`
-		Instances struct {
-			DeviceSimulatorScopeManagementDeployment77678578B4Vkn66 struct {
-				CPUInMillis int `json:"cpuInMillis,omitempty"`
-				LastUpdated struct {
-					Date struct {
-						Date *time.Time `json:"date,omitempty"`
-					} `json:"date,omitempty"`
-					Offset int `json:"offset,omitempty"`
-				} `json:"lastUpdated,omitempty"`
-				MemoryInBytes int `json:"memoryInBytes,omitempty"`
-				Restarts      int `json:"restarts,omitempty"`
-			} `json:"device-simulator-scope-management-deployment-77678578b4-vkn66,omitempty"`
-		} `json:"instances,omitempty"`
-		LastUpdated struct {
-			Date struct {
-				Date *time.Time `json:"date,omitempty"`
-			} `json:"date,omitempty"`
-			Offset int `json:"offset,omitempty"`
-		} `json:"lastUpdated,omitempty"`
-		Status string `json:"status,omitempty"`
-	}
 )
 
+// The json fields of a managed object, which can not be updated
+var managedObjectReadOnlyFields = []string{"id", "self", "creationTime", "lastUpdated", "owner",
+	"additionParents", "assetParents", "deviceParents", "childAdditions", "childAssets", "childDevices"}
+
 type ReferenceType string
 
 const (
@@ -163,6 +148,26 @@ type ManagedObjectReference struct {
 	Self          string        `json:"self"`
 }
 
+/*
+Decodes the referenced managed object with the jsonc codec, so that its custom fragments are kept
+in `AdditionalFields`.
+*/
+func (reference *ManagedObjectReference) UnmarshalJSON(j []byte) error {
+	var raw struct {
+		ManagedObject json.RawMessage `json:"managedObject"`
+		Self          string          `json:"self"`
+	}
+	if err := json.Unmarshal(j, &raw); err != nil {
+		return err
+	}
+
+	*reference = ManagedObjectReference{Self: raw.Self}
+	if len(raw.ManagedObject) == 0 || string(raw.ManagedObject) == "null" {
+		return nil
+	}
+	return generic.ObjectFromJson(raw.ManagedObject, &reference.ManagedObject)
+}
+
 type ManagedObjectReferenceCollection struct {
 	Self       string                    `json:"self"`
 	References []ManagedObjectReference  `json:"references"`
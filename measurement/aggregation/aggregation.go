@@ -0,0 +1,208 @@
+/*
+Package aggregation computes windowed statistics of measurement series on client side, e.g. for reports over
+windows the server-side series aggregation does not support.
+
+Measurements are consumed as a stream - e.g. page by page from `MeasurementApi.Find` - and only the statistics
+of each window are kept in memory, not the values. Percentiles are estimated in constant memory per window;
+up to five values per window they are exact.
+*/
+package aggregation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/measurement"
+	"math"
+	"sort"
+	"time"
+)
+
+// Identifies a series of a source.
+type Key struct {
+	Source   string
+	Fragment string
+	Series   string
+}
+
+/*
+Statistics of a series within the time window [Start, End).
+*/
+type Statistics struct {
+	Start       time.Time
+	End         time.Time
+	Count       int
+	Sum         float64
+	Min         float64
+	Max         float64
+	Percentiles map[float64]float64 // By the requested percentile, e.g. 0.95
+	Unit        string              // The unit of the last value of the window
+}
+
+// Returns the average of the window or 0, if it is empty.
+func (s Statistics) Avg() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+/*
+Options configures an Aggregator.
+*/
+type Options struct {
+	Interval    time.Duration // The length of a window. Required
+	Origin      time.Time     // Windows start at Origin + n * Interval. Default: aligned to the zero time, e.g. full hours
+	Percentiles []float64     // The percentiles to estimate, each within [0, 1], e.g. 0.5 for the median
+	Fragment    string        // Only series of this fragment are aggregated, if set
+	Series      string        // Only series with this name are aggregated, if set
+}
+
+/*
+Aggregator computes statistics per source, series and time window. An Aggregator is not safe for concurrent use.
+*/
+type Aggregator struct {
+	options Options
+	windows map[Key]map[int64]*window
+}
+
+type window struct {
+	statistics Statistics
+	quantiles  []*quantileEstimator
+}
+
+// Creates a new aggregator. Returns an error on invalid options.
+func NewAggregator(options Options) (*Aggregator, error) {
+	if options.Interval <= 0 {
+		return nil, errors.New("the interval must be positive")
+	}
+	for _, p := range options.Percentiles {
+		if p < 0 || p > 1 {
+			return nil, fmt.Errorf("the percentile %v is not within [0, 1]", p)
+		}
+	}
+
+	return &Aggregator{options: options, windows: map[Key]map[int64]*window{}}, nil
+}
+
+// Adds all series values of the measurement, which match the fragment and series options.
+// Measurements without time are skipped.
+func (a *Aggregator) Add(m *measurement.Measurement) {
+	if m == nil || m.Time == nil {
+		return
+	}
+
+	for _, fragment := range m.Fragments() {
+		if len(a.options.Fragment) > 0 && fragment != a.options.Fragment {
+			continue
+		}
+		for series, value := range m.Series(fragment) {
+			if len(a.options.Series) > 0 && series != a.options.Series {
+				continue
+			}
+			a.AddValue(Key{Source: m.Source.Id, Fragment: fragment, Series: series}, *m.Time, value)
+		}
+	}
+}
+
+// Adds a single value of a series at the given time.
+func (a *Aggregator) AddValue(key Key, t time.Time, value measurement.ValueFragment) {
+	if math.IsNaN(value.Value) {
+		return
+	}
+
+	windows, ok := a.windows[key]
+	if !ok {
+		windows = map[int64]*window{}
+		a.windows[key] = windows
+	}
+
+	start := a.windowStart(t)
+	w, ok := windows[start.UnixNano()]
+	if !ok {
+		w = &window{statistics: Statistics{Start: start, End: start.Add(a.options.Interval), Min: value.Value, Max: value.Value}}
+		for _, p := range a.options.Percentiles {
+			w.quantiles = append(w.quantiles, newQuantileEstimator(p))
+		}
+		windows[start.UnixNano()] = w
+	}
+
+	s := &w.statistics
+	s.Count++
+	s.Sum += value.Value
+	s.Min = math.Min(s.Min, value.Value)
+	s.Max = math.Max(s.Max, value.Value)
+	if len(value.Unit) > 0 {
+		s.Unit = value.Unit
+	}
+	for _, q := range w.quantiles {
+		q.add(value.Value)
+	}
+}
+
+// Returns the statistics of all series. The windows of a series are ascending by time; empty windows are omitted.
+func (a *Aggregator) Results() map[Key][]Statistics {
+	results := map[Key][]Statistics{}
+	for key, windows := range a.windows {
+		var statistics []Statistics
+		for _, w := range windows {
+			s := w.statistics
+			if len(w.quantiles) > 0 {
+				s.Percentiles = map[float64]float64{}
+				for _, q := range w.quantiles {
+					s.Percentiles[q.p] = q.value()
+				}
+			}
+			statistics = append(statistics, s)
+		}
+		sort.Slice(statistics, func(i, j int) bool {
+			return statistics[i].Start.Before(statistics[j].Start)
+		})
+		results[key] = statistics
+	}
+	return results
+}
+
+/*
+Finds the measurements of the query page by page and returns their statistics.
+Only one page is held in memory at a time.
+*/
+func Aggregate(api measurement.MeasurementApi, query *measurement.MeasurementQuery, pageSize int, options Options) (map[Key][]Statistics, *generic.Error) {
+	aggregator, err := NewAggregator(options)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Invalid aggregation options: %s", err.Error()), "AggregateMeasurements")
+	}
+	if query == nil {
+		query = &measurement.MeasurementQuery{}
+	}
+
+	collection, genErr := api.Find(query, pageSize)
+	for ; collection != nil && genErr == nil; collection, genErr = api.NextPage(collection) {
+		for i := range collection.Measurements {
+			aggregator.Add(&collection.Measurements[i])
+		}
+		if len(collection.Measurements) < pageSize {
+			break
+		}
+	}
+	if genErr != nil {
+		return nil, genErr
+	}
+
+	return aggregator.Results(), nil
+}
+
+// -- internal
+
+func (a *Aggregator) windowStart(t time.Time) time.Time {
+	if a.options.Origin.IsZero() {
+		return t.Truncate(a.options.Interval)
+	}
+
+	offset := t.Sub(a.options.Origin)
+	windows := offset / a.options.Interval
+	if offset < 0 && offset%a.options.Interval != 0 {
+		windows--
+	}
+	return a.options.Origin.Add(windows * a.options.Interval)
+}
@@ -0,0 +1,99 @@
+package aggregation
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/measurement"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var start, _ = time.Parse(time.RFC3339, "2020-06-30T10:00:00Z")
+
+func testMeasurement(source string, minutes int, temperature float64) string {
+	return fmt.Sprintf(`{"id": "%d", "type": "c8y_Weather", "time": "%s", "source": {"id": "%s"},
+		"c8y_Temperature": {"T": {"value": %v, "unit": "C"}}, "c8y_Humidity": {"H": {"value": 50}}}`,
+		minutes, start.Add(time.Duration(minutes)*time.Minute).Format(time.RFC3339), source, temperature)
+}
+
+func TestAggregate_WindowedStatistics(t *testing.T) {
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("currentPage") == "" {
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"next": "http://%s/measurement/measurements?pageSize=3&currentPage=2", "measurements": [%s]}`,
+				r.Host, strings.Join([]string{
+					testMeasurement("4711", 0, 20),
+					testMeasurement("4711", 10, 22),
+					testMeasurement("4711", 59, 30),
+				}, ","))))
+		} else {
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"measurements": [%s, %s]}`,
+				testMeasurement("4711", 61, 25), testMeasurement("4712", 5, 10))))
+		}
+	}))
+	defer ts.Close()
+
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+	api := measurement.NewMeasurementApi(client)
+
+	results, err := Aggregate(api, &measurement.MeasurementQuery{Type: "c8y_Weather"}, 3, Options{
+		Interval:    time.Hour,
+		Percentiles: []float64{0.5},
+		Fragment:    "c8y_Temperature",
+	})
+
+	if err != nil {
+		t.Fatalf("Aggregate() got an unexpected error: %s", err.Error())
+	}
+	if len(queries) != 2 || queries[0] != "pageSize=3&type=c8y_Weather" {
+		t.Errorf("Aggregate() queries = %v", queries)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Aggregate() results = %v, want temperatures of two sources", results)
+	}
+
+	windows := results[Key{Source: "4711", Fragment: "c8y_Temperature", Series: "T"}]
+	if len(windows) != 2 {
+		t.Fatalf("Aggregate() windows = %v, want 2", windows)
+	}
+
+	first := windows[0]
+	if !first.Start.Equal(start) || !first.End.Equal(start.Add(time.Hour)) || first.Count != 3 || first.Sum != 72 ||
+		first.Min != 20 || first.Max != 30 || first.Avg() != 24 || first.Percentiles[0.5] != 22 || first.Unit != "C" {
+		t.Errorf("Aggregate() first window = %+v", first)
+	}
+
+	second := windows[1]
+	if !second.Start.Equal(start.Add(time.Hour)) || second.Count != 1 || second.Min != 25 || second.Max != 25 {
+		t.Errorf("Aggregate() second window = %+v", second)
+	}
+}
+
+func TestAggregator_Origin(t *testing.T) {
+	origin := start.Add(15 * time.Minute)
+	aggregator, _ := NewAggregator(Options{Interval: time.Hour, Origin: origin})
+
+	key := Key{Source: "4711", Fragment: "c8y_Temperature", Series: "T"}
+	aggregator.AddValue(key, start, measurement.ValueFragment{Value: 1})
+	aggregator.AddValue(key, start.Add(20*time.Minute), measurement.ValueFragment{Value: 2})
+
+	windows := aggregator.Results()[key]
+	if len(windows) != 2 || !windows[0].Start.Equal(origin.Add(-time.Hour)) || !windows[1].Start.Equal(origin) {
+		t.Errorf("Results() windows = %+v, want windows aligned to the origin", windows)
+	}
+}
+
+func TestNewAggregator_InvalidOptions(t *testing.T) {
+	for _, options := range []Options{
+		{},
+		{Interval: time.Minute, Percentiles: []float64{95}},
+	} {
+		if _, err := NewAggregator(options); err == nil {
+			t.Errorf("NewAggregator(%+v) expected an error", options)
+		}
+	}
+}
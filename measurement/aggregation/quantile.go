@@ -0,0 +1,106 @@
+package aggregation
+
+import (
+	"sort"
+)
+
+/*
+quantileEstimator estimates a quantile of a stream of values in constant memory with the P² algorithm.
+See: Jain, Chlamtac - The P² algorithm for dynamic calculation of quantiles and histograms without storing
+observations (1985). Up to five values, the quantile is exact.
+*/
+type quantileEstimator struct {
+	p       float64
+	count   int
+	heights [5]float64 // marker heights, the first values until five are observed
+	actual  [5]float64 // actual marker positions
+	desired [5]float64 // desired marker positions
+	deltas  [5]float64 // increments of the desired positions
+}
+
+func newQuantileEstimator(p float64) *quantileEstimator {
+	return &quantileEstimator{p: p}
+}
+
+func (e *quantileEstimator) add(x float64) {
+	if e.count < 5 {
+		e.heights[e.count] = x
+		e.count++
+		if e.count == 5 {
+			sort.Float64s(e.heights[:])
+			p := e.p
+			e.actual = [5]float64{0, 1, 2, 3, 4}
+			e.desired = [5]float64{0, 2 * p, 4 * p, 2 + 2*p, 4}
+			e.deltas = [5]float64{0, p / 2, p, (1 + p) / 2, 1}
+		}
+		return
+	}
+	e.count++
+
+	var k int
+	switch {
+	case x < e.heights[0]:
+		e.heights[0] = x
+		k = 0
+	case x >= e.heights[4]:
+		e.heights[4] = x
+		k = 3
+	default:
+		for k = 0; k < 3 && x >= e.heights[k+1]; k++ {
+		}
+	}
+
+	for i := k + 1; i < 5; i++ {
+		e.actual[i]++
+	}
+	for i := range e.desired {
+		e.desired[i] += e.deltas[i]
+	}
+
+	for i := 1; i < 4; i++ {
+		d := e.desired[i] - e.actual[i]
+		if (d >= 1 && e.actual[i+1]-e.actual[i] > 1) || (d <= -1 && e.actual[i-1]-e.actual[i] < -1) {
+			s := 1.0
+			if d < 0 {
+				s = -1
+			}
+			height := e.parabolic(i, s)
+			if e.heights[i-1] < height && height < e.heights[i+1] {
+				e.heights[i] = height
+			} else {
+				e.heights[i] = e.linear(i, s)
+			}
+			e.actual[i] += s
+		}
+	}
+}
+
+// Returns the estimated quantile or 0, if no value was added.
+func (e *quantileEstimator) value() float64 {
+	if e.count == 0 {
+		return 0
+	}
+	if e.count >= 5 {
+		return e.heights[2]
+	}
+
+	// Exact quantile with linear interpolation between the closest ranks
+	values := append([]float64(nil), e.heights[:e.count]...)
+	sort.Float64s(values)
+	rank := e.p * float64(len(values)-1)
+	lower := int(rank)
+	if lower+1 >= len(values) {
+		return values[lower]
+	}
+	return values[lower] + (rank-float64(lower))*(values[lower+1]-values[lower])
+}
+
+func (e *quantileEstimator) parabolic(i int, s float64) float64 {
+	n, q := e.actual, e.heights
+	return q[i] + s/(n[i+1]-n[i-1])*((n[i]-n[i-1]+s)*(q[i+1]-q[i])/(n[i+1]-n[i])+(n[i+1]-n[i]-s)*(q[i]-q[i-1])/(n[i]-n[i-1]))
+}
+
+func (e *quantileEstimator) linear(i int, s float64) float64 {
+	j := i + int(s)
+	return e.heights[i] + s*(e.heights[j]-e.heights[i])/(e.actual[j]-e.actual[i])
+}
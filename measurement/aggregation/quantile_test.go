@@ -0,0 +1,49 @@
+package aggregation
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestQuantileEstimator_ExactForFewValues(t *testing.T) {
+	tests := []struct {
+		p      float64
+		values []float64
+		want   float64
+	}{
+		{0.5, []float64{}, 0},
+		{0.5, []float64{3}, 3},
+		{0.5, []float64{4, 1, 3, 2}, 2.5},
+		{0.0, []float64{4, 1, 3}, 1},
+		{1.0, []float64{4, 1, 3}, 4},
+		{0.5, []float64{5, 1, 4, 2, 3}, 3},
+	}
+
+	for _, tt := range tests {
+		e := newQuantileEstimator(tt.p)
+		for _, v := range tt.values {
+			e.add(v)
+		}
+		if got := e.value(); got != tt.want {
+			t.Errorf("value() of p=%v for %v = %v, want %v", tt.p, tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestQuantileEstimator_EstimatesLargeStreams(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	values := random.Perm(10000)
+
+	for _, p := range []float64{0.5, 0.9, 0.99} {
+		e := newQuantileEstimator(p)
+		for _, v := range values {
+			e.add(float64(v))
+		}
+
+		want := p * 10000
+		if math.Abs(e.value()-want) > 100 {
+			t.Errorf("value() of p=%v = %v, want about %v", p, e.value(), want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package measurement
+
+import (
+	"errors"
+	"github.com/tarent/gomulocity/generic"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var BatchWriterClosedErr = errors.New("batch writer is closed")
+
+// The value of `BatchWriterOptions.MaxRetries` to send each batch only once. 0 means the default.
+const NoRetries = -1
+
+/*
+BatchWriterOptions configures a BatchWriter. Zero values are replaced by the defaults.
+*/
+type BatchWriterOptions struct {
+	MaxBatchSize    int           // The max number of measurements per request. A flush is triggered, when reached. Default: 200
+	MaxPayloadBytes int           // The max size of a request body. Larger batches are split. Default: 1 MiB
+	FlushInterval   time.Duration // The interval to flush the buffer, even if the batch size is not reached. Default: 1s
+	MaxRetries      int           // The max number of retries of a failed batch. Default: 3, `NoRetries` (or any negative value) disables retries
+	RetryBackoff    time.Duration // The wait before the first retry, doubled for each further retry. Default: 500ms
+	// Is called after each batch is sent or finally failed. Called sequentially from the sending goroutine.
+	OnBatch func(result BatchResult)
+}
+
+/*
+BatchResult is the result of a single batch of a BatchWriter.
+*/
+type BatchResult struct {
+	Measurements []NewMeasurement       // The measurements of the batch
+	Created      *MeasurementCollection // The created measurements, if the batch succeeded
+	Attempts     int                    // The number of requests sent for this batch
+	Err          *generic.Error         // The error of the last attempt, if the batch failed
+}
+
+/*
+BatchWriter buffers measurements and creates them in batches via `CreateMany`. The buffer is flushed, when it
+reaches the max batch size, periodically and on `Flush` and `Close`. Batches are sent sequentially in the order
+of writing. A BatchWriter is safe for concurrent use.
+*/
+type BatchWriter struct {
+	api     MeasurementApi
+	options BatchWriterOptions
+	sleep   func(d time.Duration)
+
+	mutex  sync.Mutex // guards buffer and closed
+	buffer []NewMeasurement
+	closed bool
+
+	sendMutex sync.Mutex // serializes sending of batches
+	trigger   chan struct{}
+	stop      chan struct{}
+	stopped   sync.WaitGroup
+}
+
+// Creates a new batch writer and starts its background flushing.
+// api - The measurement api used to create the measurements.
+// options - The options of the writer. Zero values are replaced by the defaults.
+func NewBatchWriter(api MeasurementApi, options BatchWriterOptions) *BatchWriter {
+	writer := &BatchWriter{
+		api:     api,
+		options: batchWriterOptionsWithDefaults(options),
+		sleep:   time.Sleep,
+		trigger: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+
+	writer.stopped.Add(1)
+	go writer.run()
+	return writer
+}
+
+// Adds measurements to the buffer. Returns `BatchWriterClosedErr`, if the writer is closed.
+func (writer *BatchWriter) Write(measurements ...NewMeasurement) error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.closed {
+		return BatchWriterClosedErr
+	}
+
+	writer.buffer = append(writer.buffer, measurements...)
+	if len(writer.buffer) >= writer.options.MaxBatchSize {
+		select {
+		case writer.trigger <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Sends all buffered measurements and waits until they are sent or finally failed.
+func (writer *BatchWriter) Flush() {
+	writer.sendMutex.Lock()
+	defer writer.sendMutex.Unlock()
+
+	writer.mutex.Lock()
+	measurements := writer.buffer
+	writer.buffer = nil
+	writer.mutex.Unlock()
+
+	for _, batch := range writer.split(measurements) {
+		writer.send(batch)
+	}
+}
+
+// Stops the background flushing and sends all buffered measurements. Further writes fail.
+// Calling Close more than once has no effect.
+func (writer *BatchWriter) Close() {
+	writer.mutex.Lock()
+	if writer.closed {
+		writer.mutex.Unlock()
+		return
+	}
+	writer.closed = true
+	writer.mutex.Unlock()
+
+	close(writer.stop)
+	writer.stopped.Wait()
+	writer.Flush()
+}
+
+// -- internal
+
+func batchWriterOptionsWithDefaults(options BatchWriterOptions) BatchWriterOptions {
+	if options.MaxBatchSize <= 0 {
+		options.MaxBatchSize = 200
+	}
+	if options.MaxPayloadBytes <= 0 {
+		options.MaxPayloadBytes = 1024 * 1024
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = time.Second
+	}
+	if options.MaxRetries < 0 {
+		options.MaxRetries = 0
+	} else if options.MaxRetries == 0 {
+		options.MaxRetries = 3
+	}
+	if options.RetryBackoff <= 0 {
+		options.RetryBackoff = 500 * time.Millisecond
+	}
+	return options
+}
+
+func (writer *BatchWriter) run() {
+	defer writer.stopped.Done()
+
+	ticker := time.NewTicker(writer.options.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-writer.stop:
+			return
+		case <-ticker.C:
+		case <-writer.trigger:
+		}
+		writer.Flush()
+	}
+}
+
+// Splits the measurements into batches of the max batch size. Batches exceeding the max payload are halved.
+func (writer *BatchWriter) split(measurements []NewMeasurement) [][]NewMeasurement {
+	var batches [][]NewMeasurement
+	for start := 0; start < len(measurements); start += writer.options.MaxBatchSize {
+		end := start + writer.options.MaxBatchSize
+		if end > len(measurements) {
+			end = len(measurements)
+		}
+		batches = append(batches, writer.splitByPayload(measurements[start:end])...)
+	}
+	return batches
+}
+
+func (writer *BatchWriter) splitByPayload(batch []NewMeasurement) [][]NewMeasurement {
+	if len(batch) <= 1 {
+		return [][]NewMeasurement{batch}
+	}
+
+	body, err := generic.JsonFromObject(&NewMeasurements{Measurements: batch})
+	if err != nil || len(body) <= writer.options.MaxPayloadBytes {
+		// An unmarshallable batch is sent anyway to report the error by the api
+		return [][]NewMeasurement{batch}
+	}
+
+	half := len(batch) / 2
+	return append(writer.splitByPayload(batch[:half]), writer.splitByPayload(batch[half:])...)
+}
+
+// Sends a batch and retries it on failure. Errors caused by the measurements itself (4xx) are not retried.
+func (writer *BatchWriter) send(batch []NewMeasurement) {
+	result := BatchResult{Measurements: batch}
+	backoff := writer.options.RetryBackoff
+
+	for {
+		result.Attempts++
+		result.Created, result.Err = writer.api.CreateMany(&NewMeasurements{Measurements: batch})
+		if result.Err == nil || result.Attempts > writer.options.MaxRetries || !retryable(result.Err, batch) {
+			break
+		}
+
+		writer.sleep(backoff)
+		backoff *= 2
+	}
+
+	if writer.options.OnBatch != nil {
+		writer.options.OnBatch(result)
+	}
+}
+
+/*
+Client errors (status 4xx) are not retryable - except timeouts (408) and rate limiting (429). Errors raised by the
+client itself are retried on transport failures, e.g. a refused connection, but not, if the batch can not be
+marshalled, because that would fail again.
+*/
+func retryable(err *generic.Error, batch []NewMeasurement) bool {
+	if err.ErrorType == "ClientError" {
+		_, marshalErr := generic.JsonFromObject(&NewMeasurements{Measurements: batch})
+		return marshalErr == nil
+	}
+	status, parseErr := strconv.Atoi(strings.SplitN(err.ErrorType, ":", 2)[0])
+	if parseErr != nil {
+		return true
+	}
+	return status < 400 || status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
@@ -0,0 +1,231 @@
+package measurement
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A test server capturing the measurement types of each batch. The statuses are returned in order, the last one repeatedly.
+func batchHttpServer(batches *[][]string, mutex *sync.Mutex, statuses ...int) *httptest.Server {
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var measurements NewMeasurements
+		_ = generic.ObjectFromJson(body, &measurements)
+
+		mutex.Lock()
+		status := statuses[len(statuses)-1]
+		if requests < len(statuses) {
+			status = statuses[requests]
+		}
+		requests++
+		if status == http.StatusCreated {
+			var types []string
+			for _, m := range measurements.Measurements {
+				types = append(types, m.MeasurementType)
+			}
+			*batches = append(*batches, types)
+		}
+		mutex.Unlock()
+
+		w.WriteHeader(status)
+		if status == http.StatusCreated {
+			_, _ = w.Write(body)
+		} else {
+			_, _ = w.Write([]byte(`{"error": "failed", "message": "batch failed"}`))
+		}
+	}))
+}
+
+func testMeasurements(count int) []NewMeasurement {
+	var measurements []NewMeasurement
+	for i := 0; i < count; i++ {
+		measurements = append(measurements, *New(deviceId, fmt.Sprintf("m%d", i)).At(measurementTime).Value("c8y_Counter", "C", float64(i), "").Build())
+	}
+	return measurements
+}
+
+func TestBatchWriter_FlushesOnBatchSizeAndClose(t *testing.T) {
+	var batches [][]string
+	var mutex sync.Mutex
+	ts := batchHttpServer(&batches, &mutex, http.StatusCreated)
+	defer ts.Close()
+
+	var results []BatchResult
+	writer := NewBatchWriter(buildMeasurementApi(ts.URL), BatchWriterOptions{
+		MaxBatchSize:  2,
+		FlushInterval: time.Hour,
+		OnBatch:       func(result BatchResult) { results = append(results, result) },
+	})
+
+	for _, m := range testMeasurements(5) {
+		if err := writer.Write(m); err != nil {
+			t.Fatalf("Write() got an unexpected error: %s", err.Error())
+		}
+	}
+	writer.Close()
+
+	var written []string
+	for _, batch := range batches {
+		if len(batch) > 2 {
+			t.Errorf("Batch exceeds max batch size: %v", batch)
+		}
+		written = append(written, batch...)
+	}
+	if fmt.Sprint(written) != "[m0 m1 m2 m3 m4]" {
+		t.Errorf("Written measurements = %v, want [m0 m1 m2 m3 m4] in order", written)
+	}
+	for _, result := range results {
+		if result.Err != nil || result.Attempts != 1 || len(result.Created.Measurements) != len(result.Measurements) {
+			t.Errorf("Unexpected batch result %v", result)
+		}
+	}
+
+	if err := writer.Write(testMeasurements(1)...); err != BatchWriterClosedErr {
+		t.Errorf("Write() after close = %v, want %v", err, BatchWriterClosedErr)
+	}
+	writer.Close()
+}
+
+func TestBatchWriter_FlushesOnInterval(t *testing.T) {
+	var batches [][]string
+	var mutex sync.Mutex
+	ts := batchHttpServer(&batches, &mutex, http.StatusCreated)
+	defer ts.Close()
+
+	done := make(chan BatchResult, 1)
+	writer := NewBatchWriter(buildMeasurementApi(ts.URL), BatchWriterOptions{
+		FlushInterval: 10 * time.Millisecond,
+		OnBatch:       func(result BatchResult) { done <- result },
+	})
+	defer writer.Close()
+
+	_ = writer.Write(testMeasurements(1)...)
+
+	select {
+	case result := <-done:
+		if result.Err != nil || len(result.Measurements) != 1 {
+			t.Errorf("Unexpected batch result %v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Buffer was not flushed on interval")
+	}
+}
+
+func TestBatchWriter_SplitsLargePayloads(t *testing.T) {
+	var batches [][]string
+	var mutex sync.Mutex
+	ts := batchHttpServer(&batches, &mutex, http.StatusCreated)
+	defer ts.Close()
+
+	measurements := testMeasurements(4)
+	single, _ := generic.JsonFromObject(&NewMeasurements{Measurements: measurements[:1]})
+
+	writer := NewBatchWriter(buildMeasurementApi(ts.URL), BatchWriterOptions{
+		MaxPayloadBytes: len(single) + 10,
+		FlushInterval:   time.Hour,
+	})
+	_ = writer.Write(measurements...)
+	writer.Close()
+
+	if fmt.Sprint(batches) != "[[m0] [m1] [m2] [m3]]" {
+		t.Errorf("Batches = %v, want single measurement batches", batches)
+	}
+}
+
+func TestBatchWriter_RetriesFailedBatches(t *testing.T) {
+	var batches [][]string
+	var mutex sync.Mutex
+	ts := batchHttpServer(&batches, &mutex, http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusCreated)
+	defer ts.Close()
+
+	var results []BatchResult
+	writer := NewBatchWriter(buildMeasurementApi(ts.URL), BatchWriterOptions{
+		FlushInterval: time.Hour,
+		RetryBackoff:  time.Second,
+		OnBatch:       func(result BatchResult) { results = append(results, result) },
+	})
+	var waits []time.Duration
+	writer.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	_ = writer.Write(testMeasurements(2)...)
+	writer.Close()
+
+	if len(results) != 1 || results[0].Err != nil || results[0].Attempts != 3 {
+		t.Fatalf("Batch results = %v, want one successful batch after 3 attempts", results)
+	}
+	if fmt.Sprint(waits) != "[1s 2s]" {
+		t.Errorf("Retry waits = %v, want [1s 2s]", waits)
+	}
+}
+
+func TestBatchWriter_RetriesUnreachableServer(t *testing.T) {
+	// given: A server, which refuses the connection
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+
+	var results []BatchResult
+	writer := NewBatchWriter(buildMeasurementApi(ts.URL), BatchWriterOptions{
+		MaxRetries:    2,
+		FlushInterval: time.Hour,
+		OnBatch:       func(result BatchResult) { results = append(results, result) },
+	})
+	writer.sleep = func(d time.Duration) {}
+
+	_ = writer.Write(testMeasurements(1)...)
+	writer.Close()
+
+	if len(results) != 1 || results[0].Err == nil || results[0].Attempts != 3 {
+		t.Errorf("Batch results = %v, want one failed batch after 3 attempts", results)
+	}
+}
+
+func TestBatchWriter_ReportsFinallyFailedBatches(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		maxRetries   int
+		measurement  *NewMeasurement
+		wantAttempts int
+	}{
+		{"server error", http.StatusInternalServerError, 2, nil, 3},
+		{"server error without retries", http.StatusInternalServerError, NoRetries, nil, 1},
+		{"bad request", http.StatusBadRequest, 2, nil, 1},
+		{"client error", http.StatusCreated, 2, New("4711", "c8y_Test").Value("c8y_Test", "T", math.NaN(), "").Build(), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var batches [][]string
+			var mutex sync.Mutex
+			ts := batchHttpServer(&batches, &mutex, tt.status)
+			defer ts.Close()
+
+			var results []BatchResult
+			writer := NewBatchWriter(buildMeasurementApi(ts.URL), BatchWriterOptions{
+				MaxRetries:    tt.maxRetries,
+				FlushInterval: time.Hour,
+				OnBatch:       func(result BatchResult) { results = append(results, result) },
+			})
+			writer.sleep = func(d time.Duration) {}
+
+			measurements := testMeasurements(1)
+			if tt.measurement != nil {
+				measurements = []NewMeasurement{*tt.measurement}
+			}
+			_ = writer.Write(measurements...)
+			writer.Close()
+
+			if len(results) != 1 || results[0].Err == nil || results[0].Attempts != tt.wantAttempts {
+				t.Errorf("Batch results = %v, want one failed batch after %d attempts", results, tt.wantAttempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package measurement
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Point is a single point of the InfluxDB line protocol:
+
+	<measurement>[,<tag>=<value>...] <field>=<value>[,<field>=<value>...] [<timestamp>]
+
+Field values are float64, int64 (suffix `i`), uint64 (suffix `u`), bool or string.
+See: https://docs.influxdata.com/influxdb/v1.8/write_protocols/line_protocol_reference/
+*/
+type Point struct {
+	Name   string
+	Tags   map[string]string
+	Fields map[string]interface{}
+	Time   *time.Time // nil, if the point has no timestamp
+}
+
+/*
+Parses points of the InfluxDB line protocol. Empty lines and comments (#) are skipped.
+precision - The unit of the timestamps, e.g. time.Nanosecond or time.Second. 0 means nanoseconds.
+Returns an error with the line number on the first invalid line.
+*/
+func ParseLineProtocol(r io.Reader, precision time.Duration) ([]Point, error) {
+	if precision <= 0 {
+		precision = time.Nanosecond
+	}
+
+	var points []Point
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		point, err := parseLine(line, precision)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNumber, err.Error())
+		}
+		points = append(points, *point)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+/*
+Returns the duration of an InfluxDB precision parameter, e.g. 'ms' or 's'. An empty precision means nanoseconds.
+*/
+func ParsePrecision(precision string) (time.Duration, error) {
+	switch precision {
+	case "", "n", "ns":
+		return time.Nanosecond, nil
+	case "u", "us", "µ":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	}
+	return 0, fmt.Errorf("unknown precision '%s'", precision)
+}
+
+/*
+LineProtocolOptions configures the conversion of line protocol points into measurements.
+The name of a point is used as measurement type and as fragment; its numeric and boolean fields become the series
+of the fragment. String fields and tags other than the source and unit tag are dropped.
+*/
+type LineProtocolOptions struct {
+	SourceTag     string // The tag containing the source. Default: "source"
+	UnitTag       string // The tag containing the unit of all fields of a point. Default: "unit"
+	DefaultSource string // The source id of points without source tag. If empty, these points are invalid
+	// Resolves the value of the source tag to the id of a managed object, e.g. by an external id.
+	// If nil, the tag value is the source id.
+	ResolveSource func(tag string) (string, error)
+}
+
+/*
+Converts line protocol points into measurements. Points without timestamp get the current time.
+Returns an error on the first point, which has no numeric field or whose source can not be resolved.
+*/
+func (options LineProtocolOptions) Convert(points []Point) ([]NewMeasurement, error) {
+	sourceTag, unitTag := options.SourceTag, options.UnitTag
+	if len(sourceTag) == 0 {
+		sourceTag = "source"
+	}
+	if len(unitTag) == 0 {
+		unitTag = "unit"
+	}
+
+	now := time.Now()
+	measurements := make([]NewMeasurement, 0, len(points))
+	for i, point := range points {
+		source, err := options.source(point.Tags[sourceTag])
+		if err != nil {
+			return nil, fmt.Errorf("point %d (%s): %s", i+1, point.Name, err.Error())
+		}
+
+		builder := New(source, point.Name).At(now)
+		if point.Time != nil {
+			builder.At(*point.Time)
+		}
+
+		names := make([]string, 0, len(point.Fields))
+		for name := range point.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		values := 0
+		for _, name := range names {
+			if value, ok := numericField(point.Fields[name]); ok {
+				builder.Value(point.Name, name, value, point.Tags[unitTag])
+				values++
+			}
+		}
+		if values == 0 {
+			return nil, fmt.Errorf("point %d (%s): no numeric field", i+1, point.Name)
+		}
+
+		measurements = append(measurements, *builder.Build())
+	}
+	return measurements, nil
+}
+
+// -- internal
+
+func (options LineProtocolOptions) source(tag string) (string, error) {
+	if len(tag) == 0 {
+		if len(options.DefaultSource) == 0 {
+			return "", errors.New("the source tag is missing")
+		}
+		return options.DefaultSource, nil
+	}
+	if options.ResolveSource == nil {
+		return tag, nil
+	}
+
+	source, err := options.ResolveSource(tag)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve source '%s': %s", tag, err.Error())
+	}
+	return source, nil
+}
+
+func numericField(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func parseLine(line string, precision time.Duration) (*Point, error) {
+	point := &Point{Tags: map[string]string{}, Fields: map[string]interface{}{}}
+
+	name, i := readToken(line, 0, ", ")
+	if len(name) == 0 {
+		return nil, errors.New("the measurement name is missing")
+	}
+	point.Name = name
+
+	for i < len(line) && line[i] == ',' {
+		var key, value string
+		key, i = readToken(line, i+1, "=, ")
+		if i >= len(line) || line[i] != '=' || len(key) == 0 {
+			return nil, fmt.Errorf("invalid tag at %d", i)
+		}
+		value, i = readToken(line, i+1, ", ")
+		if len(value) == 0 {
+			return nil, fmt.Errorf("tag '%s' has no value", key)
+		}
+		point.Tags[key] = value
+	}
+
+	i = skipSpaces(line, i)
+	for first := true; first || (i < len(line) && line[i] == ','); first = false {
+		if !first {
+			i++
+		}
+		var key string
+		key, i = readToken(line, i, "=, ")
+		if i >= len(line) || line[i] != '=' || len(key) == 0 {
+			return nil, fmt.Errorf("invalid field at %d", i)
+		}
+
+		var value interface{}
+		var err error
+		value, i, err = readFieldValue(line, i+1)
+		if err != nil {
+			return nil, fmt.Errorf("field '%s': %s", key, err.Error())
+		}
+		point.Fields[key] = value
+	}
+
+	timestamp := strings.TrimSpace(line[i:])
+	if len(timestamp) > 0 {
+		value, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp '%s'", timestamp)
+		}
+		// Timestamps are nanoseconds internally, larger values would overflow
+		if value > math.MaxInt64/int64(precision) || value < math.MinInt64/int64(precision) {
+			return nil, fmt.Errorf("timestamp '%s' is out of range", timestamp)
+		}
+		t := time.Unix(0, value*int64(precision)).UTC()
+		point.Time = &t
+	}
+	return point, nil
+}
+
+// Reads until one of the stop characters. A backslash escapes the following character.
+func readToken(line string, i int, stops string) (string, int) {
+	var token strings.Builder
+	for ; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' && i+1 < len(line) && strings.ContainsAny(line[i+1:i+2], `, ="\`) {
+			i++
+			token.WriteByte(line[i])
+			continue
+		}
+		if strings.IndexByte(stops, c) >= 0 {
+			break
+		}
+		token.WriteByte(c)
+	}
+	return token.String(), i
+}
+
+func readFieldValue(line string, i int) (interface{}, int, error) {
+	if i < len(line) && line[i] == '"' {
+		var value strings.Builder
+		for i++; i < len(line); i++ {
+			c := line[i]
+			if c == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\') {
+				i++
+				value.WriteByte(line[i])
+				continue
+			}
+			if c == '"' {
+				return value.String(), i + 1, nil
+			}
+			value.WriteByte(c)
+		}
+		return nil, i, errors.New("unterminated string")
+	}
+
+	raw, next := readToken(line, i, ", ")
+	switch {
+	case len(raw) == 0:
+		return nil, next, errors.New("the value is missing")
+	case raw == "t" || raw == "T" || raw == "true" || raw == "True" || raw == "TRUE":
+		return true, next, nil
+	case raw == "f" || raw == "F" || raw == "false" || raw == "False" || raw == "FALSE":
+		return false, next, nil
+	case strings.HasSuffix(raw, "i"):
+		value, err := strconv.ParseInt(strings.TrimSuffix(raw, "i"), 10, 64)
+		return value, next, err
+	case strings.HasSuffix(raw, "u"):
+		value, err := strconv.ParseUint(strings.TrimSuffix(raw, "u"), 10, 64)
+		return value, next, err
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err == nil && (math.IsNaN(value) || math.IsInf(value, 0)) {
+		return nil, next, fmt.Errorf("the value '%s' is not finite", raw)
+	}
+	return value, next, err
+}
+
+func skipSpaces(line string, i int) int {
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	return i
+}
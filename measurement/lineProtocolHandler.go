@@ -0,0 +1,136 @@
+package measurement
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// The max size of a write request body - also of a decompressed gzip body. Like the default of InfluxDB.
+const LINE_PROTOCOL_MAX_BODY_BYTES = 25 * 1024 * 1024
+
+/*
+Returns an HTTP handler compatible with the InfluxDB 1.x `/write` and `/ping` API, so existing line protocol
+clients can write to cumulocity, e.g.
+
+	http.ListenAndServe("localhost:8086", measurement.NewLineProtocolHandler(api, measurement.LineProtocolOptions{}, 0))
+
+Written points are converted (see `LineProtocolOptions`) and created via `CreateMany` in batches of `batchSize`
+(default: 200). The `precision` query parameter and gzip encoded bodies are supported; `db` and `rp` are ignored.
+All points are parsed and converted before the first batch is uploaded, so a request with an invalid point is
+rejected completely with 400 - Bad Request, a body larger than `LINE_PROTOCOL_MAX_BODY_BYTES` with
+413 - Request Entity Too Large. A failed upload is answered with 503 - Service Unavailable, if it can be retried,
+e.g. a server error or a timeout, otherwise - e.g. a batch rejected by cumulocity - with 400 - Bad Request.
+The batches uploaded before it are written; the error message reports how many points were written.
+*/
+func NewLineProtocolHandler(api MeasurementApi, options LineProtocolOptions, batchSize int) http.Handler {
+	return newLineProtocolHandler(api, options, batchSize, LINE_PROTOCOL_MAX_BODY_BYTES)
+}
+
+// -- internal
+
+func newLineProtocolHandler(api MeasurementApi, options LineProtocolOptions, batchSize int, maxBodyBytes int64) http.Handler {
+	if batchSize <= 0 {
+		batchSize = 200
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/write", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeLineProtocolError(w, http.StatusMethodNotAllowed, "only POST is supported")
+			return
+		}
+
+		precision, err := ParsePrecision(r.URL.Query().Get("precision"))
+		if err != nil {
+			writeLineProtocolError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		body := &limitedBody{reader: http.MaxBytesReader(w, r.Body, maxBodyBytes), limit: maxBodyBytes}
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gzipReader, err := gzip.NewReader(body)
+			if err != nil {
+				writeLineProtocolBodyError(w, body, fmt.Sprintf("invalid gzip body: %s", err.Error()))
+				return
+			}
+			defer gzipReader.Close()
+			body = &limitedBody{reader: http.MaxBytesReader(w, gzipReader, maxBodyBytes), limit: maxBodyBytes, compressed: body}
+		}
+
+		points, err := ParseLineProtocol(body, precision)
+		if err != nil {
+			writeLineProtocolBodyError(w, body, err.Error())
+			return
+		}
+		measurements, err := options.Convert(points)
+		if err != nil {
+			writeLineProtocolError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		for start := 0; start < len(measurements); start += batchSize {
+			end := start + batchSize
+			if end > len(measurements) {
+				end = len(measurements)
+			}
+			batch := measurements[start:end]
+			if _, genErr := api.CreateMany(&NewMeasurements{Measurements: batch}); genErr != nil {
+				status := http.StatusBadRequest
+				if retryable(genErr, batch) {
+					status = http.StatusServiceUnavailable
+				}
+				writeLineProtocolError(w, status, fmt.Sprintf("%d of %d points written: %s", start, len(measurements), genErr.Error()))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+/*
+limitedBody reads a request body limited by `http.MaxBytesReader` and notes, whether the limit was exceeded.
+For a gzip body, `compressed` is the limited compressed body.
+*/
+type limitedBody struct {
+	reader     io.Reader
+	limit      int64
+	read       int64
+	exceeded   bool
+	compressed *limitedBody
+}
+
+func (body *limitedBody) Read(p []byte) (int, error) {
+	n, err := body.reader.Read(p)
+	body.read += int64(n)
+	if err != nil && err != io.EOF && body.read >= body.limit {
+		body.exceeded = true
+	}
+	return n, err
+}
+
+func (body *limitedBody) tooLarge() bool {
+	return body.exceeded || (body.compressed != nil && body.compressed.tooLarge())
+}
+
+// Writes 413 - Request Entity Too Large, if the body exceeded the limit. Otherwise 400 - Bad Request.
+func writeLineProtocolBodyError(w http.ResponseWriter, body *limitedBody, message string) {
+	if body.tooLarge() {
+		writeLineProtocolError(w, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+	writeLineProtocolError(w, http.StatusBadRequest, message)
+}
+
+// Writes an error in the InfluxDB format: {"error": "<message>"}
+func writeLineProtocolError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,188 @@
+package measurement
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/tarent/gomulocity/generic"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func lineProtocolServer(createStatus int, batches *[]NewMeasurements) (*httptest.Server, *httptest.Server) {
+	c8y := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var measurements NewMeasurements
+		_ = generic.ObjectFromJson(body, &measurements)
+		*batches = append(*batches, measurements)
+
+		w.WriteHeader(createStatus)
+		if createStatus == http.StatusCreated {
+			_, _ = w.Write(body)
+		} else {
+			_, _ = w.Write([]byte(`{"error": "unavailable", "message": "try again"}`))
+		}
+	}))
+
+	influx := httptest.NewServer(NewLineProtocolHandler(buildMeasurementApi(c8y.URL), LineProtocolOptions{}, 2))
+	return c8y, influx
+}
+
+func TestLineProtocolHandler_Write(t *testing.T) {
+	var batches []NewMeasurements
+	c8y, influx := lineProtocolServer(http.StatusCreated, &batches)
+	defer c8y.Close()
+	defer influx.Close()
+
+	body := "cpu,source=4711 load=0.5 1593505924\ncpu,source=4711 load=0.7 1593505925\ncpu,source=4712 load=0.1 1593505924\n"
+	resp, err := http.Post(influx.URL+"/write?db=edge&precision=s", "text/plain", strings.NewReader(body))
+
+	if err != nil {
+		t.Fatalf("Write got an unexpected error: %s", err.Error())
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Write status = %d, want 204", resp.StatusCode)
+	}
+	if len(batches) != 2 || len(batches[0].Measurements) != 2 || len(batches[1].Measurements) != 1 {
+		t.Fatalf("Write batches = %v, want batches of 2 and 1 measurements", batches)
+	}
+	if m := batches[1].Measurements[0]; m.Source.Id != "4712" || m.Time.Unix() != 1593505924 {
+		t.Errorf("Write last measurement = %+v", m)
+	}
+}
+
+func TestLineProtocolHandler_WriteGzip(t *testing.T) {
+	var batches []NewMeasurements
+	c8y, influx := lineProtocolServer(http.StatusCreated, &batches)
+	defer c8y.Close()
+	defer influx.Close()
+
+	var body bytes.Buffer
+	zip := gzip.NewWriter(&body)
+	_, _ = zip.Write([]byte("cpu,source=4711 load=0.5\n"))
+	_ = zip.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, influx.URL+"/write", &body)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil || resp.StatusCode != http.StatusNoContent || len(batches) != 1 {
+		t.Errorf("Write gzip = %v, %v, %v - want 204 and one batch", resp, err, batches)
+	}
+}
+
+func TestLineProtocolHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		createStatus int
+		wantStatus   int
+		wantBatches  int
+	}{
+		{"ping", http.MethodGet, "/ping", "", http.StatusCreated, http.StatusNoContent, 0},
+		{"method", http.MethodGet, "/write", "", http.StatusCreated, http.StatusMethodNotAllowed, 0},
+		{"precision", http.MethodPost, "/write?precision=d", "cpu,source=1 load=1", http.StatusCreated, http.StatusBadRequest, 0},
+		{"invalid line", http.MethodPost, "/write", "cpu,source=1 load=1\ncpu load", http.StatusCreated, http.StatusBadRequest, 0},
+		{"no source", http.MethodPost, "/write", "cpu load=1", http.StatusCreated, http.StatusBadRequest, 0},
+		{"upload failed", http.MethodPost, "/write", "cpu,source=1 load=1", http.StatusServiceUnavailable, http.StatusServiceUnavailable, 1},
+		{"upload rejected", http.MethodPost, "/write", "cpu,source=1 load=1", http.StatusUnprocessableEntity, http.StatusBadRequest, 1},
+		{"upload rate limited", http.MethodPost, "/write", "cpu,source=1 load=1", http.StatusTooManyRequests, http.StatusServiceUnavailable, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var batches []NewMeasurements
+			c8y, influx := lineProtocolServer(tt.createStatus, &batches)
+			defer c8y.Close()
+			defer influx.Close()
+
+			req, _ := http.NewRequest(tt.method, influx.URL+tt.path, strings.NewReader(tt.body))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request got an unexpected error: %s", err.Error())
+			}
+			respBody, _ := ioutil.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus || len(batches) != tt.wantBatches {
+				t.Errorf("Status = %d, batches = %d, want %d and %d batches", resp.StatusCode, len(batches), tt.wantStatus, tt.wantBatches)
+			}
+			if tt.wantStatus >= 400 && !strings.HasPrefix(string(respBody), `{"error":`) {
+				t.Errorf("Error body = %s, want an InfluxDB error", respBody)
+			}
+		})
+	}
+}
+
+func TestLineProtocolHandler_PartialWrite(t *testing.T) {
+	requests := 0
+	c8y := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if requests++; requests > 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	}))
+	defer c8y.Close()
+	influx := httptest.NewServer(NewLineProtocolHandler(buildMeasurementApi(c8y.URL), LineProtocolOptions{}, 2))
+	defer influx.Close()
+
+	body := "cpu,source=4711 load=0.5\ncpu,source=4711 load=0.7\ncpu,source=4712 load=0.1\n"
+	resp, err := http.Post(influx.URL+"/write", "text/plain", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Write got an unexpected error: %s", err.Error())
+	}
+	respBody, _ := ioutil.ReadAll(resp.Body)
+
+	// then: The second batch failed, the written points are reported
+	if resp.StatusCode != http.StatusServiceUnavailable || requests != 2 {
+		t.Errorf("Status = %d, requests = %d, want 503 and 2 requests", resp.StatusCode, requests)
+	}
+	if !strings.Contains(string(respBody), "2 of 3 points written") {
+		t.Errorf("Error body = %s, want the number of written points", respBody)
+	}
+}
+
+func TestLineProtocolHandler_BodyTooLarge(t *testing.T) {
+	var batches []NewMeasurements
+	c8y, _ := lineProtocolServer(http.StatusCreated, &batches)
+	defer c8y.Close()
+	influx := httptest.NewServer(newLineProtocolHandler(buildMeasurementApi(c8y.URL), LineProtocolOptions{}, 2, 64))
+	defer influx.Close()
+
+	body := strings.Repeat("cpu,source=4711 load=0.5\n", 10)
+	var zipped bytes.Buffer
+	zip := gzip.NewWriter(&zipped)
+	_, _ = zip.Write([]byte(body))
+	_ = zip.Close()
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+	}{
+		{"plain", []byte(body), ""},
+		// The compressed body is smaller than the limit, but not the decompressed one
+		{"gzip", zipped.Bytes(), "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, influx.URL+"/write", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Encoding", tt.encoding)
+			resp, err := http.DefaultClient.Do(req)
+
+			if err != nil {
+				t.Fatalf("Request got an unexpected error: %s", err.Error())
+			}
+			if resp.StatusCode != http.StatusRequestEntityTooLarge || len(batches) != 0 {
+				t.Errorf("Status = %d, batches = %d, want 413 and no batches", resp.StatusCode, len(batches))
+			}
+		})
+	}
+}
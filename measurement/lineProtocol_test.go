@@ -0,0 +1,134 @@
+package measurement
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLineProtocol(t *testing.T) {
+	input := `# weather of the roof
+weather,source=4711,unit=C temperature=21.5,humidity=51i,online=true,label="roof \"north\"" 1593505924000000000
+
+wind\ speed,source=my\,device speed=12.1,gusts=20u 1593505924
+`
+	points, err := ParseLineProtocol(strings.NewReader(input), 0)
+
+	if err != nil {
+		t.Fatalf("ParseLineProtocol() got an unexpected error: %s", err.Error())
+	}
+	if len(points) != 2 {
+		t.Fatalf("ParseLineProtocol() = %d points, want 2", len(points))
+	}
+
+	wantTime := time.Unix(1593505924, 0).UTC()
+	want := Point{
+		Name:   "weather",
+		Tags:   map[string]string{"source": "4711", "unit": "C"},
+		Fields: map[string]interface{}{"temperature": 21.5, "humidity": int64(51), "online": true, "label": `roof "north"`},
+		Time:   &wantTime,
+	}
+	if !reflect.DeepEqual(points[0], want) {
+		t.Errorf("ParseLineProtocol() first point = %+v, want %+v", points[0], want)
+	}
+
+	second := points[1]
+	if second.Name != "wind speed" || second.Tags["source"] != "my,device" || second.Fields["speed"] != 12.1 || second.Fields["gusts"] != uint64(20) {
+		t.Errorf("ParseLineProtocol() second point = %+v", second)
+	}
+}
+
+func TestParseLineProtocol_Precision(t *testing.T) {
+	precision, err := ParsePrecision("s")
+	if err != nil || precision != time.Second {
+		t.Fatalf("ParsePrecision(s) = %v, %v", precision, err)
+	}
+
+	points, err := ParseLineProtocol(strings.NewReader("cpu,source=1 load=1i 1593505924\n"), precision)
+	if err != nil || !points[0].Time.Equal(time.Unix(1593505924, 0)) {
+		t.Errorf("ParseLineProtocol() = %v, %v - want the time in seconds", points, err)
+	}
+
+	if _, err := ParsePrecision("d"); err == nil {
+		t.Errorf("ParsePrecision(d) expected an error")
+	}
+
+	// Timestamps, which do not fit into nanoseconds, are invalid
+	for _, line := range []string{"cpu,source=1 load=1 9223372037", "cpu,source=1 load=1 -9223372037"} {
+		if _, err := ParseLineProtocol(strings.NewReader(line), precision); err == nil || !strings.Contains(err.Error(), "out of range") {
+			t.Errorf("ParseLineProtocol(%q) = %v, want an out of range error", line, err)
+		}
+	}
+}
+
+func TestParseLineProtocol_InvalidLines(t *testing.T) {
+	for _, line := range []string{
+		"weather",
+		"weather temperature",
+		"weather temperature=",
+		"weather temperature=abc",
+		"weather temperature=NaN",
+		"weather temperature=+Inf",
+		"weather temperature=-inf",
+		`weather label="unterminated`,
+		"weather,source temperature=1",
+		"weather temperature=1 yesterday",
+		",source=1 temperature=1",
+	} {
+		_, err := ParseLineProtocol(strings.NewReader("cpu load=1\n"+line), 0)
+		if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+			t.Errorf("ParseLineProtocol(%q) = %v, want an error in line 2", line, err)
+		}
+	}
+}
+
+func TestLineProtocolOptions_Convert(t *testing.T) {
+	at := time.Unix(1593505924, 0).UTC()
+	points := []Point{
+		{Name: "weather", Tags: map[string]string{"device": "roof", "unit": "C"}, Fields: map[string]interface{}{"temperature": 21.5, "label": "north"}, Time: &at},
+		{Name: "power", Fields: map[string]interface{}{"on": true, "watts": uint64(250)}},
+	}
+
+	options := LineProtocolOptions{
+		SourceTag:     "device",
+		DefaultSource: "1111111",
+		ResolveSource: func(tag string) (string, error) {
+			if tag == "roof" {
+				return "4711", nil
+			}
+			return "", errors.New("unknown device")
+		},
+	}
+	measurements, err := options.Convert(points)
+
+	if err != nil {
+		t.Fatalf("Convert() got an unexpected error: %s", err.Error())
+	}
+
+	weather := measurements[0]
+	if weather.Source.Id != "4711" || weather.MeasurementType != "weather" || !weather.Time.Equal(at) ||
+		!reflect.DeepEqual(weather.Series("weather"), map[string]ValueFragment{"temperature": {Value: 21.5, Unit: "C"}}) {
+		t.Errorf("Convert() first measurement = %+v", weather)
+	}
+
+	power := measurements[1]
+	if power.Source.Id != "1111111" || power.Time == nil ||
+		!reflect.DeepEqual(power.Series("power"), map[string]ValueFragment{"on": {Value: 1}, "watts": {Value: 250}}) {
+		t.Errorf("Convert() second measurement = %+v", power)
+	}
+
+	for _, invalid := range []Point{
+		{Name: "weather", Tags: map[string]string{"device": "cellar"}, Fields: map[string]interface{}{"temperature": 1.0}},
+		{Name: "weather", Fields: map[string]interface{}{"label": "north"}},
+	} {
+		if _, err := options.Convert([]Point{invalid}); err == nil {
+			t.Errorf("Convert(%+v) expected an error", invalid)
+		}
+	}
+
+	if _, err := (LineProtocolOptions{}).Convert(points[1:]); err == nil {
+		t.Errorf("Convert() without source and default source expected an error")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 const (
@@ -43,6 +44,10 @@ type MeasurementApi interface {
 	// All query parameters are AND concatenated.
 	Find(measurementQuery *MeasurementQuery, pageSize int) (*MeasurementCollection, *generic.Error)
 
+	// Gets the values of series of a source within a time window, optionally aggregated per minute, hour or day.
+	// The series are given as '<fragment type>.<series name>'. If no series is given, all series are returned.
+	Series(sourceId string, series []string, dateFrom time.Time, dateTo time.Time, aggregation Aggregation) (*MeasurementSeries, *generic.Error)
+
 	// Gets the next page from an existing measurement collection.
 	// If there is no next page, nil is returned.
 	NextPage(c *MeasurementCollection) (*MeasurementCollection, *generic.Error)
@@ -0,0 +1,129 @@
+package measurement
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var seriesResponseBody = `{
+	"values": {
+		"2020-06-29T11:00:00.000Z": [{"min": 21.5, "max": 23.0}, null],
+		"2020-06-29T10:00:00.000Z": [{"min": 20.0, "max": 22.5}, {"min": 1011.2, "max": 1012.0}]
+	},
+	"series": [
+		{"unit": "C", "name": "T", "type": "c8y_Temperature"},
+		{"unit": "hPa", "name": "P", "type": "c8y_AirPressure"}
+	],
+	"truncated": true
+}`
+
+func TestMeasurementApi_Series_Aggregated(t *testing.T) {
+	var capturedUrl string
+	var capturedAccept string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUrl = r.URL.String()
+		capturedAccept = r.Header.Get("Accept")
+		_, _ = w.Write([]byte(seriesResponseBody))
+	}))
+	defer ts.Close()
+
+	api := buildMeasurementApi(ts.URL)
+
+	series, err := api.Series(deviceId, []string{"c8y_Temperature.T", "c8y_AirPressure.P"}, dateFrom, dateTo, HOURLY)
+
+	if err != nil {
+		t.Fatalf("Series() got an unexpected error: %s", err.Error())
+	}
+
+	wantUrl := "/measurement/measurements/series?aggregationType=HOURLY&dateFrom=2020-06-29T10%3A11%3A12Z&dateTo=2020-06-30T13%3A14%3A15Z" +
+		"&series=c8y_Temperature.T&series=c8y_AirPressure.P&source=1111111"
+	if capturedUrl != wantUrl {
+		t.Errorf("Series() url = %s, want %s", capturedUrl, wantUrl)
+	}
+	if capturedAccept != MEASUREMENT_SERIES_TYPE {
+		t.Errorf("Series() accept header = %s, want %s", capturedAccept, MEASUREMENT_SERIES_TYPE)
+	}
+
+	if len(series.Series) != 2 || series.Series[1].Unit != "hPa" || series.IndexOf("c8y_Temperature.T") != 0 || series.IndexOf("c8y_Foo.X") != -1 {
+		t.Errorf("Series() series definitions = %v", series.Series)
+	}
+	if !series.Truncated {
+		t.Errorf("Series() truncated = false, want true")
+	}
+
+	if len(series.Buckets) != 2 {
+		t.Fatalf("Series() buckets count = %d, want 2", len(series.Buckets))
+	}
+	first, second := series.Buckets[0], series.Buckets[1]
+	if first.Time.Format(time.RFC3339) != "2020-06-29T10:00:00Z" || first.Values[0].Min != 20.0 || first.Values[1].Max != 1012.0 {
+		t.Errorf("Series() first bucket = %v", first)
+	}
+	if second.Time.Format(time.RFC3339) != "2020-06-29T11:00:00Z" || second.Values[0].Max != 23.0 || second.Values[1] != nil {
+		t.Errorf("Series() second bucket = %v", second)
+	}
+}
+
+func TestMeasurementApi_Series_WithoutAggregation(t *testing.T) {
+	var capturedUrl string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUrl = r.URL.String()
+		_, _ = w.Write([]byte(`{"values": {}, "series": [], "truncated": false}`))
+	}))
+	defer ts.Close()
+
+	api := buildMeasurementApi(ts.URL)
+
+	series, err := api.Series(deviceId, nil, dateFrom, dateTo, NONE)
+
+	if err != nil {
+		t.Fatalf("Series() got an unexpected error: %s", err.Error())
+	}
+	if strings.Contains(capturedUrl, "aggregationType") || strings.Contains(capturedUrl, "series=") {
+		t.Errorf("Series() url = %s, want no aggregation and series parameters", capturedUrl)
+	}
+	if len(series.Buckets) != 0 || series.Truncated {
+		t.Errorf("Series() = %v, want an empty result", series)
+	}
+}
+
+func TestMeasurementApi_Series_InvalidParameters(t *testing.T) {
+	api := buildMeasurementApi("")
+
+	tests := []struct {
+		name        string
+		source      string
+		from        time.Time
+		to          time.Time
+		aggregation Aggregation
+	}{
+		{"no source", "", dateFrom, dateTo, DAILY},
+		{"no dateFrom", deviceId, time.Time{}, dateTo, DAILY},
+		{"reverted window", deviceId, dateTo, dateFrom, DAILY},
+		{"unknown aggregation", deviceId, dateFrom, dateTo, Aggregation("WEEKLY")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := api.Series(tt.source, nil, tt.from, tt.to, tt.aggregation)
+			if err == nil {
+				t.Errorf("Series() expected an error")
+			}
+		})
+	}
+}
+
+func TestMeasurementApi_Series_BadRequest(t *testing.T) {
+	ts := buildHttpServer(400, `{"error": "bad request", "message": "Invalid series!"}`)
+	defer ts.Close()
+
+	api := buildMeasurementApi(ts.URL)
+
+	_, err := api.Series(deviceId, []string{"foo"}, dateFrom, dateTo, MINUTELY)
+
+	if err == nil || err.Message != "Invalid series!" {
+		t.Errorf("Series() expected error 'Invalid series!', got %v", err)
+	}
+}
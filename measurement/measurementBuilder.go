@@ -0,0 +1,179 @@
+package measurement
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+/*
+Builder creates a `NewMeasurement` fluently, e.g.
+
+	m := measurement.New("4711", "c8y_Weather").
+		At(t).
+		Value("c8y_Temperature", "T", 21.5, "C").
+		Value("c8y_Wind", "speed", 12.1, "km/h").
+		Value("c8y_Wind", "direction", 270, "deg").
+		Build()
+
+A fragment may contain multiple series. Setting a series twice overwrites its value.
+*/
+type Builder struct {
+	measurement NewMeasurement
+}
+
+// Starts a new measurement of the given source and type.
+func New(sourceId string, measurementType string) *Builder {
+	return &Builder{measurement: NewMeasurement{
+		MeasurementType: measurementType,
+		Source:          Source{Id: sourceId},
+		Metrics:         map[string]interface{}{},
+	}}
+}
+
+// Sets the time of the measurement. Default: the time `Build` is called.
+func (b *Builder) At(t time.Time) *Builder {
+	b.measurement.Time = &t
+	return b
+}
+
+// Adds a value with unit to a series of a fragment. The unit is omitted, if it is empty.
+func (b *Builder) Value(fragment string, series string, value float64, unit string) *Builder {
+	seriesMap, ok := b.measurement.Metrics[fragment].(map[string]ValueFragment)
+	if !ok {
+		seriesMap = map[string]ValueFragment{}
+		b.measurement.Metrics[fragment] = seriesMap
+	}
+	seriesMap[series] = ValueFragment{Value: value, Unit: unit}
+	return b
+}
+
+// Returns the built measurement. Further changes of the builder do not affect it.
+func (b *Builder) Build() *NewMeasurement {
+	result := b.measurement
+	if result.Time == nil {
+		now := time.Now()
+		result.Time = &now
+	}
+
+	result.Metrics = make(map[string]interface{}, len(b.measurement.Metrics))
+	for fragment, series := range b.measurement.Metrics {
+		seriesCopy := map[string]ValueFragment{}
+		for name, value := range series.(map[string]ValueFragment) {
+			seriesCopy[name] = value
+		}
+		result.Metrics[fragment] = seriesCopy
+	}
+	return &result
+}
+
+// Returns the value of a series of a fragment, e.g. `Value("c8y_Temperature", "T")`.
+// The second return value is false, if the series does not exist or is not a value fragment.
+func (m *Measurement) Value(fragment string, series string) (ValueFragment, bool) {
+	return metricValue(m.Metrics, fragment, series)
+}
+
+// Returns all series of a fragment by their name. Entries, which are no value fragments, are skipped.
+func (m *Measurement) Series(fragment string) map[string]ValueFragment {
+	return metricSeries(m.Metrics, fragment)
+}
+
+// Returns the names of all fragments containing at least one series, sorted by name.
+func (m *Measurement) Fragments() []string {
+	return metricFragments(m.Metrics)
+}
+
+// Returns the value of a series of a fragment, e.g. `Value("c8y_Temperature", "T")`.
+// The second return value is false, if the series does not exist or is not a value fragment.
+func (m *NewMeasurement) Value(fragment string, series string) (ValueFragment, bool) {
+	return metricValue(m.Metrics, fragment, series)
+}
+
+// Returns all series of a fragment by their name. Entries, which are no value fragments, are skipped.
+func (m *NewMeasurement) Series(fragment string) map[string]ValueFragment {
+	return metricSeries(m.Metrics, fragment)
+}
+
+// Returns the names of all fragments containing at least one series, sorted by name.
+func (m *NewMeasurement) Fragments() []string {
+	return metricFragments(m.Metrics)
+}
+
+// -- internal
+
+func metricValue(metrics map[string]interface{}, fragment string, series string) (ValueFragment, bool) {
+	value, ok := metricSeries(metrics, fragment)[series]
+	return value, ok
+}
+
+/*
+Metrics are either built by the builder (map[string]ValueFragment) or parsed from JSON (map[string]interface{}
+with the keys 'value' and 'unit').
+*/
+func metricSeries(metrics map[string]interface{}, fragment string) map[string]ValueFragment {
+	result := map[string]ValueFragment{}
+
+	switch series := metrics[fragment].(type) {
+	case map[string]ValueFragment:
+		for name, value := range series {
+			result[name] = value
+		}
+	case map[string]*ValueFragment:
+		for name, value := range series {
+			if value != nil {
+				result[name] = *value
+			}
+		}
+	case map[string]interface{}:
+		for name, raw := range series {
+			if value, ok := valueFragment(raw); ok {
+				result[name] = value
+			}
+		}
+	}
+
+	return result
+}
+
+func metricFragments(metrics map[string]interface{}) []string {
+	var fragments []string
+	for fragment := range metrics {
+		if len(metricSeries(metrics, fragment)) > 0 {
+			fragments = append(fragments, fragment)
+		}
+	}
+	sort.Strings(fragments)
+	return fragments
+}
+
+func valueFragment(raw interface{}) (ValueFragment, bool) {
+	switch v := raw.(type) {
+	case ValueFragment:
+		return v, true
+	case *ValueFragment:
+		if v != nil {
+			return *v, true
+		}
+	case map[string]interface{}:
+		value, ok := number(v["value"])
+		if !ok {
+			return ValueFragment{}, false
+		}
+		unit, _ := v["unit"].(string)
+		return ValueFragment{Value: value, Unit: unit}, true
+	}
+	return ValueFragment{}, false
+}
+
+func number(raw interface{}) (float64, bool) {
+	switch n := raw.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,90 @@
+package measurement
+
+import (
+	"github.com/tarent/gomulocity/generic"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	builder := New(deviceId, "c8y_Weather").
+		At(measurementTime).
+		Value("c8y_Temperature", "T", 21.5, "C").
+		Value("c8y_Wind", "speed", 12.1, "km/h").
+		Value("c8y_Wind", "direction", 270, "")
+
+	m := builder.Build()
+
+	if m.Source.Id != deviceId || m.MeasurementType != "c8y_Weather" || !m.Time.Equal(measurementTime) {
+		t.Errorf("Build() measurement = %v", m)
+	}
+
+	wantWind := map[string]ValueFragment{"speed": {Value: 12.1, Unit: "km/h"}, "direction": {Value: 270}}
+	if !reflect.DeepEqual(m.Series("c8y_Wind"), wantWind) {
+		t.Errorf("Build() wind series = %v, want %v", m.Series("c8y_Wind"), wantWind)
+	}
+
+	// Later changes of the builder do not affect the built measurement
+	builder.Value("c8y_Temperature", "T", 30, "C")
+	if value, _ := m.Value("c8y_Temperature", "T"); value.Value != 21.5 {
+		t.Errorf("Build() temperature changed to %v after build", value)
+	}
+
+	json, err := generic.JsonFromObject(m)
+	if err != nil {
+		t.Fatalf("JsonFromObject() got an unexpected error: %s", err.Error())
+	}
+	for _, want := range []string{
+		`"c8y_Temperature":{"T":{"value":21.5,"unit":"C"}}`,
+		`"direction":{"value":270}`,
+		`"source":{"id":"1111111"}`,
+	} {
+		if !strings.Contains(string(json), want) {
+			t.Errorf("JsonFromObject() json = %s, want to contain %s", json, want)
+		}
+	}
+}
+
+func TestBuilder_DefaultTime(t *testing.T) {
+	before := time.Now()
+	m := New(deviceId, "c8y_Weather").Build()
+
+	if m.Time == nil || m.Time.Before(before) {
+		t.Errorf("Build() time = %v, want now", m.Time)
+	}
+}
+
+func TestMeasurement_TypedAccessors(t *testing.T) {
+	var m Measurement
+	err := generic.ObjectFromJson([]byte(`{
+		"id": "2222222",
+		"type": "c8y_Weather",
+		"c8y_Temperature": {"T": {"value": 23.45, "unit": "C"}},
+		"c8y_Wind": {"speed": {"value": 12, "unit": "km/h"}, "direction": {"value": 270}, "label": "west"},
+		"c8y_Position": "not a fragment"
+	}`), &m)
+	if err != nil {
+		t.Fatalf("ObjectFromJson() got an unexpected error: %s", err.Error())
+	}
+
+	if value, ok := m.Value("c8y_Temperature", "T"); !ok || value != (ValueFragment{Value: 23.45, Unit: "C"}) {
+		t.Errorf("Value() = %v, %v, want {23.45 C}", value, ok)
+	}
+	if _, ok := m.Value("c8y_Temperature", "X"); ok {
+		t.Errorf("Value() of an unknown series expected to be not found")
+	}
+	if _, ok := m.Value("c8y_Position", "T"); ok {
+		t.Errorf("Value() of a non fragment expected to be not found")
+	}
+
+	wantWind := map[string]ValueFragment{"speed": {Value: 12, Unit: "km/h"}, "direction": {Value: 270}}
+	if !reflect.DeepEqual(m.Series("c8y_Wind"), wantWind) {
+		t.Errorf("Series() = %v, want %v", m.Series("c8y_Wind"), wantWind)
+	}
+
+	if fragments := m.Fragments(); !reflect.DeepEqual(fragments, []string{"c8y_Temperature", "c8y_Wind"}) {
+		t.Errorf("Fragments() = %v, want [c8y_Temperature c8y_Wind]", fragments)
+	}
+}
@@ -0,0 +1,419 @@
+package measurement
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+The columns of the long CSV format: one row per series value. Rows of the same time, source and type are
+imported as one measurement.
+*/
+var LongCsvColumns = []string{"time", "source", "type", "fragment", "series", "value", "unit"}
+
+// The leading columns of the wide CSV format, followed by one column per series, e.g. `c8y_Temperature.T`.
+var WideCsvColumns = []string{"time", "source", "type"}
+
+/*
+Exports all measurements of the query in the long CSV format, page by page: one row per series value with the
+columns `LongCsvColumns`. Returns the number of exported measurements.
+*/
+func ExportCsvLong(api MeasurementApi, query *MeasurementQuery, pageSize int, w io.Writer) (int, *generic.Error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(LongCsvColumns); err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while exporting measurements: %s", err.Error()), "ExportMeasurementsCsv")
+	}
+
+	return exportCsv(api, query, pageSize, writer, func(m *Measurement) [][]string {
+		var rows [][]string
+		for _, fragment := range m.Fragments() {
+			series := m.Series(fragment)
+			for _, name := range sortedSeriesNames(series) {
+				value := series[name]
+				rows = append(rows, []string{csvTime(m.Time), m.Source.Id, m.MeasurementType, fragment, name, csvValue(value.Value), value.Unit})
+			}
+		}
+		return rows
+	})
+}
+
+/*
+Exports all measurements of the query in the wide CSV format, page by page: one row per measurement with the
+columns `WideCsvColumns` followed by the given series in the format '<fragment>.<series>'. Units are not exported.
+Cells of series missing in a measurement are empty. Returns the number of exported measurements.
+*/
+func ExportCsvWide(api MeasurementApi, query *MeasurementQuery, pageSize int, w io.Writer, series ...string) (int, *generic.Error) {
+	if len(series) == 0 {
+		return 0, generic.ClientError("At least one series is needed for a wide csv export", "ExportMeasurementsCsv")
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(append([]string{}, WideCsvColumns...), series...)); err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while exporting measurements: %s", err.Error()), "ExportMeasurementsCsv")
+	}
+
+	return exportCsv(api, query, pageSize, writer, func(m *Measurement) [][]string {
+		row := []string{csvTime(m.Time), m.Source.Id, m.MeasurementType}
+		for _, column := range series {
+			fragment, name := splitSeriesColumn(column)
+			if value, ok := m.Value(fragment, name); ok {
+				row = append(row, csvValue(value.Value))
+			} else {
+				row = append(row, "")
+			}
+		}
+		return [][]string{row}
+	})
+}
+
+/*
+ImportOptions configures a CSV import.
+*/
+type ImportOptions struct {
+	BatchSize int    // The number of measurements per `CreateMany` request. Default: 100
+	Type      string // The measurement type of rows without type or if there is no type column
+	// Is called after each uploaded batch.
+	Progress func(progress ImportProgress)
+}
+
+// The progress of a CSV import.
+type ImportProgress struct {
+	Rows     int // The rows read so far, without the header
+	Uploaded int // The measurements created so far
+	Failed   int // The measurements of failed batches so far
+}
+
+// An invalid row of a CSV import. Rows are counted from 1 for the first row after the header.
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Err.Error())
+}
+
+// A batch of a CSV import, which could not be created.
+type BatchError struct {
+	FirstRow int
+	LastRow  int
+	Err      *generic.Error
+}
+
+/*
+ImportReport is the result of a CSV import. Invalid rows are skipped and reported, failed batches are reported
+and the import continues with the next batch.
+*/
+type ImportReport struct {
+	ImportProgress
+	RowErrors   []RowError
+	BatchErrors []BatchError
+}
+
+/*
+Imports measurements from CSV and creates them in batches via `CreateMany`. The format is detected by the header:
+a header with the columns `fragment`, `series` and `value` is the long format (see `LongCsvColumns`), otherwise
+the wide format (see `WideCsvColumns`) is expected. The columns `time` and `source` are required in both formats.
+Times are expected in RFC 3339.
+
+Returns an error only, if the CSV can not be read or has an invalid header. Row and batch errors are reported.
+*/
+func ImportCsv(api MeasurementApi, r io.Reader, options ImportOptions) (*ImportReport, *generic.Error) {
+	if options.BatchSize <= 0 {
+		options.BatchSize = 100
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while reading the csv header: %s", err.Error()), "ImportMeasurementsCsv")
+	}
+
+	parser, err := newCsvRowParser(header, options.Type)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Invalid csv header: %s", err.Error()), "ImportMeasurementsCsv")
+	}
+
+	importer := &csvImporter{api: api, options: options, report: &ImportReport{}}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		importer.report.Rows++
+		row := importer.report.Rows
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return importer.report, generic.ClientError(fmt.Sprintf("Error while reading the csv: %s", err.Error()), "ImportMeasurementsCsv")
+			}
+			importer.report.RowErrors = append(importer.report.RowErrors, RowError{Row: row, Err: err})
+			continue
+		}
+
+		if err := importer.add(parser, record, row); err != nil {
+			importer.report.RowErrors = append(importer.report.RowErrors, RowError{Row: row, Err: err})
+		}
+	}
+
+	importer.finishMeasurement()
+	importer.upload()
+	return importer.report, nil
+}
+
+// -- internal
+
+func exportCsv(api MeasurementApi, query *MeasurementQuery, pageSize int, writer *csv.Writer, rows func(m *Measurement) [][]string) (int, *generic.Error) {
+	if query == nil {
+		query = &MeasurementQuery{}
+	}
+
+	exported := 0
+	collection, err := api.Find(query, pageSize)
+	for ; collection != nil && err == nil; collection, err = api.NextPage(collection) {
+		for i := range collection.Measurements {
+			if writeErr := writer.WriteAll(rows(&collection.Measurements[i])); writeErr != nil {
+				return exported, generic.ClientError(fmt.Sprintf("Error while exporting measurement %s: %s", collection.Measurements[i].Id, writeErr.Error()), "ExportMeasurementsCsv")
+			}
+			exported++
+		}
+		if len(collection.Measurements) < pageSize {
+			break
+		}
+	}
+	if err != nil {
+		return exported, err
+	}
+
+	writer.Flush()
+	if flushErr := writer.Error(); flushErr != nil {
+		return exported, generic.ClientError(fmt.Sprintf("Error while exporting measurements: %s", flushErr.Error()), "ExportMeasurementsCsv")
+	}
+	return exported, nil
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func csvValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func sortedSeriesNames(series map[string]ValueFragment) []string {
+	names := make([]string, 0, len(series))
+	for name := range series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Splits a column '<fragment>.<series>' at the first dot.
+func splitSeriesColumn(column string) (string, string) {
+	parts := strings.SplitN(column, ".", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// A parsed CSV row: the values of one measurement.
+type csvRow struct {
+	time   time.Time
+	source string
+	typ    string
+	values []csvRowValue
+}
+
+type csvRowValue struct {
+	fragment string
+	series   string
+	value    float64
+	unit     string
+}
+
+type csvRowParser struct {
+	columns     map[string]int
+	long        bool
+	wideSeries  map[int]string // wide format: column index -> '<fragment>.<series>'
+	defaultType string
+}
+
+func newCsvRowParser(header []string, defaultType string) (*csvRowParser, error) {
+	parser := &csvRowParser{columns: map[string]int{}, wideSeries: map[int]string{}, defaultType: defaultType}
+	for i, column := range header {
+		parser.columns[strings.TrimSpace(column)] = i
+	}
+
+	for _, required := range []string{"time", "source"} {
+		if _, ok := parser.columns[required]; !ok {
+			return nil, fmt.Errorf("the column '%s' is missing", required)
+		}
+	}
+
+	_, hasFragment := parser.columns["fragment"]
+	_, hasSeries := parser.columns["series"]
+	_, hasValue := parser.columns["value"]
+	parser.long = hasFragment && hasSeries && hasValue
+	if parser.long {
+		return parser, nil
+	}
+
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		if column == "time" || column == "source" || column == "type" {
+			continue
+		}
+		if fragment, series := splitSeriesColumn(column); len(fragment) == 0 || len(series) == 0 {
+			return nil, fmt.Errorf("the column '%s' is no series in the format '<fragment>.<series>'", column)
+		}
+		parser.wideSeries[i] = column
+	}
+	if len(parser.wideSeries) == 0 {
+		return nil, errors.New("neither the columns 'fragment', 'series' and 'value' nor series columns found")
+	}
+	return parser, nil
+}
+
+func (parser *csvRowParser) cell(record []string, column string) string {
+	if i, ok := parser.columns[column]; ok && i < len(record) {
+		return strings.TrimSpace(record[i])
+	}
+	return ""
+}
+
+func (parser *csvRowParser) parse(record []string) (*csvRow, error) {
+	t, err := time.Parse(time.RFC3339Nano, parser.cell(record, "time"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid time: %s", err.Error())
+	}
+	row := &csvRow{time: t, source: parser.cell(record, "source"), typ: parser.cell(record, "type")}
+	if len(row.source) == 0 {
+		return nil, errors.New("the source is missing")
+	}
+	if len(row.typ) == 0 {
+		row.typ = parser.defaultType
+	}
+	if len(row.typ) == 0 {
+		return nil, errors.New("the type is missing")
+	}
+
+	if parser.long {
+		fragment, series := parser.cell(record, "fragment"), parser.cell(record, "series")
+		if len(fragment) == 0 || len(series) == 0 {
+			return nil, errors.New("the fragment or series is missing")
+		}
+		value, err := strconv.ParseFloat(parser.cell(record, "value"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %s", err.Error())
+		}
+		row.values = []csvRowValue{{fragment: fragment, series: series, value: value, unit: parser.cell(record, "unit")}}
+		return row, nil
+	}
+
+	indexes := make([]int, 0, len(parser.wideSeries))
+	for i := range parser.wideSeries {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		if i >= len(record) || len(strings.TrimSpace(record[i])) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of '%s': %s", parser.wideSeries[i], err.Error())
+		}
+		fragment, series := splitSeriesColumn(parser.wideSeries[i])
+		row.values = append(row.values, csvRowValue{fragment: fragment, series: series, value: value})
+	}
+	if len(row.values) == 0 {
+		return nil, errors.New("the row has no values")
+	}
+	return row, nil
+}
+
+// Collects parsed rows into measurements and uploads them in batches.
+type csvImporter struct {
+	api     MeasurementApi
+	options ImportOptions
+	report  *ImportReport
+
+	current     *csvRow
+	builder     *Builder
+	currentRows [2]int // first and last row of the current measurement
+
+	batch     []NewMeasurement
+	batchRows [2]int
+}
+
+func (importer *csvImporter) add(parser *csvRowParser, record []string, rowNumber int) error {
+	row, err := parser.parse(record)
+	if err != nil {
+		return err
+	}
+
+	// Rows of the long format with the same time, source and type belong to the same measurement
+	if importer.current == nil || !parser.long || !row.time.Equal(importer.current.time) ||
+		row.source != importer.current.source || row.typ != importer.current.typ {
+		importer.finishMeasurement()
+		importer.current = row
+		importer.builder = New(row.source, row.typ).At(row.time)
+		importer.currentRows[0] = rowNumber
+	}
+	importer.currentRows[1] = rowNumber
+
+	for _, value := range row.values {
+		importer.builder.Value(value.fragment, value.series, value.value, value.unit)
+	}
+	return nil
+}
+
+func (importer *csvImporter) finishMeasurement() {
+	if importer.builder == nil {
+		return
+	}
+
+	if len(importer.batch) == 0 {
+		importer.batchRows[0] = importer.currentRows[0]
+	}
+	importer.batchRows[1] = importer.currentRows[1]
+	importer.batch = append(importer.batch, *importer.builder.Build())
+	importer.current, importer.builder = nil, nil
+
+	if len(importer.batch) >= importer.options.BatchSize {
+		importer.upload()
+	}
+}
+
+func (importer *csvImporter) upload() {
+	if len(importer.batch) == 0 {
+		return
+	}
+
+	_, err := importer.api.CreateMany(&NewMeasurements{Measurements: importer.batch})
+	if err != nil {
+		importer.report.Failed += len(importer.batch)
+		importer.report.BatchErrors = append(importer.report.BatchErrors, BatchError{FirstRow: importer.batchRows[0], LastRow: importer.batchRows[1], Err: err})
+	} else {
+		importer.report.Uploaded += len(importer.batch)
+	}
+	importer.batch = nil
+
+	if importer.options.Progress != nil {
+		importer.options.Progress(importer.report.ImportProgress)
+	}
+}
@@ -0,0 +1,163 @@
+package measurement
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var csvMeasurements = `{"measurements": [
+	{"id": "1", "type": "c8y_Weather", "time": "2020-06-30T08:32:04.261Z", "source": {"id": "1111111"},
+		"c8y_Temperature": {"T": {"value": 23.45, "unit": "C"}},
+		"c8y_Wind": {"speed": {"value": 12, "unit": "km/h"}, "direction": {"value": 270, "unit": "deg"}}},
+	{"id": "2", "type": "c8y_Weather", "time": "2020-06-30T08:33:04Z", "source": {"id": "1111111"},
+		"c8y_Temperature": {"T": {"value": 23.5, "unit": "C"}}}
+]}`
+
+func TestExportCsvLong(t *testing.T) {
+	ts := buildHttpServer(200, csvMeasurements)
+	defer ts.Close()
+
+	var out bytes.Buffer
+	count, err := ExportCsvLong(buildMeasurementApi(ts.URL), &MeasurementQuery{SourceId: deviceId}, 5, &out)
+
+	if err != nil {
+		t.Fatalf("ExportCsvLong() got an unexpected error: %s", err.Error())
+	}
+	want := "time,source,type,fragment,series,value,unit\n" +
+		"2020-06-30T08:32:04.261Z,1111111,c8y_Weather,c8y_Temperature,T,23.45,C\n" +
+		"2020-06-30T08:32:04.261Z,1111111,c8y_Weather,c8y_Wind,direction,270,deg\n" +
+		"2020-06-30T08:32:04.261Z,1111111,c8y_Weather,c8y_Wind,speed,12,km/h\n" +
+		"2020-06-30T08:33:04Z,1111111,c8y_Weather,c8y_Temperature,T,23.5,C\n"
+	if count != 2 || out.String() != want {
+		t.Errorf("ExportCsvLong() = %d\n%s\nwant 2\n%s", count, out.String(), want)
+	}
+}
+
+func TestExportCsvWide(t *testing.T) {
+	ts := buildHttpServer(200, csvMeasurements)
+	defer ts.Close()
+
+	var out bytes.Buffer
+	count, err := ExportCsvWide(buildMeasurementApi(ts.URL), nil, 5, &out, "c8y_Temperature.T", "c8y_Wind.speed")
+
+	if err != nil {
+		t.Fatalf("ExportCsvWide() got an unexpected error: %s", err.Error())
+	}
+	want := "time,source,type,c8y_Temperature.T,c8y_Wind.speed\n" +
+		"2020-06-30T08:32:04.261Z,1111111,c8y_Weather,23.45,12\n" +
+		"2020-06-30T08:33:04Z,1111111,c8y_Weather,23.5,\n"
+	if count != 2 || out.String() != want {
+		t.Errorf("ExportCsvWide() = %d\n%s\nwant 2\n%s", count, out.String(), want)
+	}
+
+	if _, err := ExportCsvWide(buildMeasurementApi(ts.URL), nil, 5, &out); err == nil {
+		t.Errorf("ExportCsvWide() without series expected an error")
+	}
+}
+
+// A test server capturing the created batches. Batches containing a measurement of the source 'fail' are rejected.
+func importHttpServer(batches *[]NewMeasurements) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(body), `"id":"fail"`) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"error": "invalid", "message": "invalid source"}`))
+			return
+		}
+
+		var measurements NewMeasurements
+		_ = generic.ObjectFromJson(body, &measurements)
+		*batches = append(*batches, measurements)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	}))
+}
+
+func TestImportCsv_LongFormat(t *testing.T) {
+	var batches []NewMeasurements
+	ts := importHttpServer(&batches)
+	defer ts.Close()
+
+	csv := "time,source,type,fragment,series,value,unit\n" +
+		"2020-06-30T08:32:04.261Z,1111111,c8y_Weather,c8y_Temperature,T,23.45,C\n" +
+		"2020-06-30T08:32:04.261Z,1111111,c8y_Weather,c8y_Wind,speed,12,km/h\n" +
+		"2020-06-30T08:33:04Z,1111111,c8y_Weather,c8y_Temperature,T,abc,C\n" +
+		"2020-06-30T08:33:04Z,1111111,c8y_Weather,c8y_Temperature,T,23.5,C\n" +
+		"yesterday,1111111,c8y_Weather,c8y_Temperature,T,23.5,C\n" +
+		"2020-06-30T08:34:04Z,fail,c8y_Weather,c8y_Temperature,T,24,C\n"
+
+	var progress []ImportProgress
+	report, err := ImportCsv(buildMeasurementApi(ts.URL), strings.NewReader(csv), ImportOptions{
+		BatchSize: 2,
+		Progress:  func(p ImportProgress) { progress = append(progress, p) },
+	})
+
+	if err != nil {
+		t.Fatalf("ImportCsv() got an unexpected error: %s", err.Error())
+	}
+	if report.Rows != 6 || report.Uploaded != 2 || report.Failed != 1 {
+		t.Errorf("ImportCsv() report = %+v, want 6 rows, 2 uploaded and 1 failed", report.ImportProgress)
+	}
+	if len(report.RowErrors) != 2 || report.RowErrors[0].Row != 3 || report.RowErrors[1].Row != 5 {
+		t.Errorf("ImportCsv() row errors = %v, want rows 3 and 5", report.RowErrors)
+	}
+	if len(report.BatchErrors) != 1 || report.BatchErrors[0].FirstRow != 6 || report.BatchErrors[0].LastRow != 6 {
+		t.Errorf("ImportCsv() batch errors = %v, want row 6", report.BatchErrors)
+	}
+	if len(progress) != 2 || progress[0].Uploaded != 2 {
+		t.Errorf("ImportCsv() progress = %v", progress)
+	}
+
+	if len(batches) != 1 || len(batches[0].Measurements) != 2 {
+		t.Fatalf("ImportCsv() batches = %v, want one batch of 2 measurements", batches)
+	}
+	first := batches[0].Measurements[0]
+	if len(first.Fragments()) != 2 || first.Source.Id != "1111111" || first.MeasurementType != "c8y_Weather" {
+		t.Errorf("ImportCsv() first measurement = %v, want temperature and wind", first)
+	}
+	if value, _ := first.Value("c8y_Wind", "speed"); value != (ValueFragment{Value: 12, Unit: "km/h"}) {
+		t.Errorf("ImportCsv() wind speed = %v", value)
+	}
+}
+
+func TestImportCsv_WideFormat(t *testing.T) {
+	var batches []NewMeasurements
+	ts := importHttpServer(&batches)
+	defer ts.Close()
+
+	csv := "time,source,c8y_Temperature.T,c8y_Wind.speed\n" +
+		"2020-06-30T08:32:04Z,1111111,23.45,12\n" +
+		"2020-06-30T08:33:04Z,1111111,23.5,\n" +
+		"2020-06-30T08:34:04Z,1111111,,\n"
+
+	report, err := ImportCsv(buildMeasurementApi(ts.URL), strings.NewReader(csv), ImportOptions{Type: "c8y_Weather"})
+
+	if err != nil {
+		t.Fatalf("ImportCsv() got an unexpected error: %s", err.Error())
+	}
+	if report.Uploaded != 2 || len(report.RowErrors) != 1 || report.RowErrors[0].Row != 3 {
+		t.Errorf("ImportCsv() report = %+v, want 2 uploaded and row 3 without values", report)
+	}
+	if len(batches) != 1 || fmt.Sprint(batches[0].Measurements[1].Fragments()) != "[c8y_Temperature]" ||
+		batches[0].Measurements[1].MeasurementType != "c8y_Weather" {
+		t.Errorf("ImportCsv() batches = %v", batches)
+	}
+}
+
+func TestImportCsv_InvalidHeader(t *testing.T) {
+	for _, csv := range []string{
+		"",
+		"source,fragment,series,value\n",
+		"time,source,temperature\n",
+	} {
+		if _, err := ImportCsv(buildMeasurementApi(""), strings.NewReader(csv), ImportOptions{}); err == nil {
+			t.Errorf("ImportCsv() of %q expected an error", csv)
+		}
+	}
+}
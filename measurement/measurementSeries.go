@@ -0,0 +1,149 @@
+package measurement
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+const (
+	MEASUREMENT_SERIES_API  = "/measurement/measurements/series"
+	MEASUREMENT_SERIES_TYPE = "application/json"
+)
+
+// The aggregation of a measurement series. With an aggregation, the min and max values of each bucket are returned.
+type Aggregation string
+
+const (
+	NONE     Aggregation = ""
+	MINUTELY Aggregation = "MINUTELY"
+	HOURLY   Aggregation = "HOURLY"
+	DAILY    Aggregation = "DAILY"
+)
+
+/*
+Describes a series of a measurement series result, e.g. the series 'T' of the fragment 'c8y_Temperature'.
+*/
+type SeriesDefinition struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Unit string `json:"unit,omitempty"`
+}
+
+/*
+The min and max value of a series within a bucket. Without aggregation both values are equal.
+*/
+type SeriesValue struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+/*
+The values of all series at the time of a bucket. The values are in the order of the series definitions.
+A value is nil, if the series has no value in this bucket.
+*/
+type SeriesBucket struct {
+	Time   time.Time
+	Values []*SeriesValue
+}
+
+/*
+Represents the result of a measurement series request.
+See: https://cumulocity.com/guides/reference/measurements/#measurement-series
+*/
+type MeasurementSeries struct {
+	Series    []SeriesDefinition
+	Buckets   []SeriesBucket // Ascending by time
+	Truncated bool           // True, if cumulocity has cut the result, because it contains too many values
+}
+
+// Returns the index of a series in the definitions or -1, if it is not part of the result.
+// series - The series in the format '<fragment type>.<series name>', e.g. 'c8y_Temperature.T'
+func (s *MeasurementSeries) IndexOf(series string) int {
+	for i, definition := range s.Series {
+		if definition.Type+"."+definition.Name == series {
+			return i
+		}
+	}
+	return -1
+}
+
+/*
+Gets the values of series of a source within a time window. With an aggregation, the values are aggregated on
+server side into buckets of a minute, an hour or a day.
+
+The series are given in the format '<fragment type>.<series name>', e.g. 'c8y_Temperature.T'.
+If no series is given, all series of the source are returned.
+*/
+func (measurementApi *measurementApi) Series(sourceId string, series []string, dateFrom time.Time, dateTo time.Time, aggregation Aggregation) (*MeasurementSeries, *generic.Error) {
+	if len(sourceId) == 0 {
+		return nil, generic.ClientError("Getting a measurement series without a source is not allowed", "GetMeasurementSeries")
+	}
+	if dateFrom.IsZero() || dateTo.IsZero() || dateFrom.After(dateTo) {
+		return nil, generic.ClientError("A valid time window is required: 'dateFrom' and 'dateTo' must be set and 'dateFrom' must not be after 'dateTo'", "GetMeasurementSeries")
+	}
+
+	params := url.Values{}
+	params.Add("source", sourceId)
+	params.Add("dateFrom", dateFrom.Format(time.RFC3339))
+	params.Add("dateTo", dateTo.Format(time.RFC3339))
+	for _, s := range series {
+		params.Add("series", s)
+	}
+	switch aggregation {
+	case NONE:
+	case MINUTELY, HOURLY, DAILY:
+		params.Add("aggregationType", string(aggregation))
+	default:
+		return nil, generic.ClientError(fmt.Sprintf("Unknown aggregation '%s'", aggregation), "GetMeasurementSeries")
+	}
+
+	path := fmt.Sprintf("%s?%s", MEASUREMENT_SERIES_API, params.Encode())
+	body, status, err := measurementApi.client.Get(path, generic.AcceptHeader(MEASUREMENT_SERIES_TYPE))
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while getting a measurement series: %s", err.Error()), "GetMeasurementSeries")
+	}
+	if status != http.StatusOK {
+		return nil, generic.CreateErrorFromResponse(body, status)
+	}
+
+	return parseMeasurementSeriesResponse(body)
+}
+
+// -- internal
+
+// The series response as sent by cumulocity: the values are a map from the bucket time to the values.
+type seriesResponse struct {
+	Values    map[string][]*SeriesValue `json:"values"`
+	Series    []SeriesDefinition        `json:"series"`
+	Truncated bool                      `json:"truncated"`
+}
+
+func parseMeasurementSeriesResponse(body []byte) (*MeasurementSeries, *generic.Error) {
+	if len(body) == 0 {
+		return nil, generic.ClientError("Response body was empty", "SeriesResponseParser")
+	}
+
+	var response seriesResponse
+	err := generic.ObjectFromJson(body, &response)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while parsing response JSON: %s", err.Error()), "SeriesResponseParser")
+	}
+
+	result := &MeasurementSeries{Series: response.Series, Truncated: response.Truncated}
+	for key, values := range response.Values {
+		bucketTime, err := time.Parse(time.RFC3339, key)
+		if err != nil {
+			return nil, generic.ClientError(fmt.Sprintf("Error while parsing the time of a series bucket '%s': %s", key, err.Error()), "SeriesResponseParser")
+		}
+		result.Buckets = append(result.Buckets, SeriesBucket{Time: bucketTime, Values: values})
+	}
+
+	sort.Slice(result.Buckets, func(i, j int) bool {
+		return result.Buckets[i].Time.Before(result.Buckets[j].Time)
+	})
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package measurement
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/measurement/units"
+)
+
+/*
+Returns the value of a series converted into the given unit, e.g. `ValueIn("c8y_Temperature", "T", "°F")`.
+Returns an error, if the series does not exist, has no unit or its unit can not be converted.
+*/
+func (m *Measurement) ValueIn(fragment string, series string, unit string) (ValueFragment, error) {
+	value, ok := m.Value(fragment, series)
+	if !ok {
+		return ValueFragment{}, fmt.Errorf("the series %s.%s does not exist", fragment, series)
+	}
+	return convertValue(value, unit)
+}
+
+/*
+Returns the values of a series of all buckets converted into the given unit. The unit of the series is taken
+from its definition. A value is nil, if the series has no value in the bucket.
+*/
+func (s *MeasurementSeries) ValuesIn(series string, unit string) ([]*SeriesValue, error) {
+	index := s.IndexOf(series)
+	if index < 0 {
+		return nil, fmt.Errorf("the series %s is not part of the result", series)
+	}
+
+	from, err := units.Parse(s.Series[index].Unit)
+	if err != nil {
+		return nil, fmt.Errorf("the series %s: %s", series, err.Error())
+	}
+	to, err := units.Parse(unit)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]*SeriesValue, len(s.Buckets))
+	for i, bucket := range s.Buckets {
+		if index >= len(bucket.Values) || bucket.Values[index] == nil {
+			continue
+		}
+		min, err := from.Convert(bucket.Values[index].Min, to)
+		if err != nil {
+			return nil, err
+		}
+		max, err := from.Convert(bucket.Values[index].Max, to)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = &SeriesValue{Min: min, Max: max}
+	}
+	return values, nil
+}
+
+/*
+Checks, that the units of all series of the measurement are known (see package `units`). Series without unit
+are valid. Returns an error naming the first series with an unknown unit.
+*/
+func ValidateUnits(m *NewMeasurement) error {
+	for _, fragment := range m.Fragments() {
+		for name, value := range m.Series(fragment) {
+			if len(value.Unit) == 0 {
+				continue
+			}
+			if _, err := units.Parse(value.Unit); err != nil {
+				return fmt.Errorf("series %s.%s: %s", fragment, name, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+/*
+Returns a measurement api, which validates the units of new measurements before creating them (see
+`ValidateUnits`). Measurements with unknown units are rejected with a client error and none of them is created.
+*/
+func NewUnitValidatingApi(api MeasurementApi) MeasurementApi {
+	return &unitValidatingApi{api}
+}
+
+// -- internal
+
+func convertValue(value ValueFragment, unit string) (ValueFragment, error) {
+	if len(value.Unit) == 0 {
+		return ValueFragment{}, fmt.Errorf("the value %v has no unit", value.Value)
+	}
+
+	converted, err := units.Convert(value.Value, value.Unit, unit)
+	if err != nil {
+		return ValueFragment{}, err
+	}
+	return ValueFragment{Value: converted, Unit: unit}, nil
+}
+
+type unitValidatingApi struct {
+	MeasurementApi
+}
+
+func (api *unitValidatingApi) Create(measurement *NewMeasurement) (*Measurement, *generic.Error) {
+	if err := ValidateUnits(measurement); err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Invalid measurement: %s", err.Error()), "CreateMeasurement")
+	}
+	return api.MeasurementApi.Create(measurement)
+}
+
+func (api *unitValidatingApi) CreateMany(measurements *NewMeasurements) (*MeasurementCollection, *generic.Error) {
+	for i := range measurements.Measurements {
+		if err := ValidateUnits(&measurements.Measurements[i]); err != nil {
+			return nil, generic.ClientError(fmt.Sprintf("Invalid measurement %d: %s", i, err.Error()), "CreateManyMeasurement")
+		}
+	}
+	return api.MeasurementApi.CreateMany(measurements)
+}
@@ -0,0 +1,80 @@
+package measurement
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMeasurement_ValueIn(t *testing.T) {
+	m := Measurement{Metrics: map[string]interface{}{
+		"c8y_Temperature": map[string]interface{}{"T": map[string]interface{}{"value": 100.0, "unit": "C"}},
+		"c8y_Counter":     map[string]interface{}{"N": map[string]interface{}{"value": 3.0}},
+	}}
+
+	value, err := m.ValueIn("c8y_Temperature", "T", "°F")
+	if err != nil || math.Abs(value.Value-212) > 1e-9 || value.Unit != "°F" {
+		t.Errorf("ValueIn() = %v, %v - want 212 °F", value, err)
+	}
+
+	for _, tt := range []struct{ fragment, series, unit string }{
+		{"c8y_Temperature", "X", "°F"},
+		{"c8y_Temperature", "T", "bar"},
+		{"c8y_Counter", "N", "%"},
+	} {
+		if _, err := m.ValueIn(tt.fragment, tt.series, tt.unit); err == nil {
+			t.Errorf("ValueIn(%s, %s, %s) expected an error", tt.fragment, tt.series, tt.unit)
+		}
+	}
+}
+
+func TestMeasurementSeries_ValuesIn(t *testing.T) {
+	series := &MeasurementSeries{
+		Series: []SeriesDefinition{{Name: "T", Type: "c8y_Temperature", Unit: "C"}, {Name: "P", Type: "c8y_Pressure", Unit: "hPa"}},
+		Buckets: []SeriesBucket{
+			{Values: []*SeriesValue{{Min: 0, Max: 100}, {Min: 1000, Max: 1013}}},
+			{Values: []*SeriesValue{{Min: 10, Max: 20}, nil}},
+		},
+	}
+
+	values, err := series.ValuesIn("c8y_Pressure.P", "bar")
+	if err != nil {
+		t.Fatalf("ValuesIn() got an unexpected error: %s", err.Error())
+	}
+	if len(values) != 2 || values[0].Min != 1 || math.Abs(values[0].Max-1.013) > 1e-9 || values[1] != nil {
+		t.Errorf("ValuesIn() = %v, want [{1 1.013} nil]", values)
+	}
+
+	if _, err := series.ValuesIn("c8y_Humidity.H", "%"); err == nil {
+		t.Errorf("ValuesIn() of an unknown series expected an error")
+	}
+	if _, err := series.ValuesIn("c8y_Temperature.T", "W"); err == nil {
+		t.Errorf("ValuesIn() into another dimension expected an error")
+	}
+}
+
+func TestUnitValidatingApi(t *testing.T) {
+	ts := createMeasurementHttpServer(http.StatusCreated)
+	defer ts.Close()
+
+	api := NewUnitValidatingApi(buildMeasurementApi(ts.URL))
+
+	valid := New(deviceId, "c8y_Weather").At(measurementTime).Value("c8y_Temperature", "T", 21.5, "°C").Value("c8y_Counter", "N", 1, "").Build()
+	invalid := New(deviceId, "c8y_Weather").At(measurementTime).Value("c8y_Humidity", "H", 51, "%RH").Build()
+
+	if _, err := api.Create(valid); err != nil {
+		t.Errorf("Create() with known units got an unexpected error: %s", err.Error())
+	}
+
+	requestCapture = nil
+	_, err := api.Create(invalid)
+	if err == nil || !strings.Contains(err.Message, "c8y_Humidity.H") || requestCapture != nil {
+		t.Errorf("Create() with unknown unit = %v, want a client error without request", err)
+	}
+
+	_, err = api.CreateMany(&NewMeasurements{Measurements: []NewMeasurement{*valid, *invalid}})
+	if err == nil || !strings.Contains(err.Message, "measurement 1") {
+		t.Errorf("CreateMany() with unknown unit = %v, want a client error", err)
+	}
+}
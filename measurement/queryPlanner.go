@@ -0,0 +1,272 @@
+package measurement
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"sync"
+	"time"
+)
+
+/*
+PlannerOptions configures a QueryPlanner. Zero values are replaced by the defaults.
+*/
+type PlannerOptions struct {
+	InitialWindow time.Duration // The size of the first sub-window. Default: 24h
+	MinWindow     time.Duration // Sub-windows are not split below this size. Default: 1m
+	MaxWindow     time.Duration // Sub-windows do not grow beyond this size. Default: 30 days
+	PageSize      int           // The page size to fetch sub-windows. Default: 1000
+	Concurrency   int           // The max number of sub-windows fetched in parallel. Default: 4
+}
+
+/*
+QueryPlanner fetches measurements of large time windows by splitting them into sub-windows.
+
+The size of the sub-windows adapts to the density of the data: a sub-window, whose first page is full, is continued
+after the last full second of the page and the following sub-windows are halved. After sparse sub-windows the
+size is doubled.
+Sub-windows are fetched in parallel, but the measurements are passed to the handler in order.
+*/
+type QueryPlanner struct {
+	api     MeasurementApi
+	options PlannerOptions
+}
+
+// Creates a new query planner.
+// api - The measurement api used to find the measurements of the sub-windows.
+// options - The options of the planner. Zero values are replaced by the defaults.
+func NewQueryPlanner(api MeasurementApi, options PlannerOptions) *QueryPlanner {
+	return &QueryPlanner{api: api, options: plannerOptionsWithDefaults(options)}
+}
+
+/*
+Passes all measurements of the query within [DateFrom, DateTo) to the handler: in chronological order or, if
+`Revert` is set, in reverse chronological order. `DateFrom` and `DateTo` are required. Cumulocity accepts times in
+seconds only, so sub-windows are aligned to full seconds and measurements outside of the exact window are dropped.
+
+Returning an error from the handler stops the query. It is returned as client error.
+*/
+func (planner *QueryPlanner) Query(query *MeasurementQuery, handler func(m *Measurement) error) *generic.Error {
+	if query == nil || query.DateFrom == nil || query.DateTo == nil {
+		return generic.ClientError("A planned query requires 'DateFrom' and 'DateTo'", "PlanMeasurementQuery")
+	}
+	if query.DateFrom.After(*query.DateTo) {
+		return generic.ClientError("'DateFrom' must not be after 'DateTo'", "PlanMeasurementQuery")
+	}
+
+	jobs := make(chan *windowJob, planner.options.Concurrency)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go planner.plan(query, jobs, stop)
+
+	for job := range jobs {
+		result := <-job.result
+		if result.err != nil {
+			return result.err
+		}
+		for i := range result.measurements {
+			if err := handler(&result.measurements[i]); err != nil {
+				return generic.ClientError(fmt.Sprintf("Query stopped by handler: %s", err.Error()), "PlanMeasurementQuery")
+			}
+		}
+	}
+	return nil
+}
+
+// -- internal
+
+type windowJob struct {
+	result chan windowResult
+}
+
+type windowResult struct {
+	measurements []Measurement
+	dense        bool // true, if the first page of the window was full
+	err          *generic.Error
+}
+
+var queryStoppedErr = generic.ClientError("The query was stopped", "PlanMeasurementQuery")
+
+func plannerOptionsWithDefaults(options PlannerOptions) PlannerOptions {
+	if options.MinWindow < time.Second {
+		options.MinWindow = time.Minute
+	}
+	if options.MaxWindow <= 0 {
+		options.MaxWindow = 30 * 24 * time.Hour
+	}
+	if options.MaxWindow < options.MinWindow {
+		options.MaxWindow = options.MinWindow
+	}
+	if options.InitialWindow <= 0 {
+		options.InitialWindow = 24 * time.Hour
+	}
+	options.InitialWindow = clampWindow(options.InitialWindow, options)
+	if options.PageSize <= 0 {
+		options.PageSize = 1000
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 4
+	}
+	return options
+}
+
+func clampWindow(size time.Duration, options PlannerOptions) time.Duration {
+	size = size.Truncate(time.Second)
+	if size < options.MinWindow {
+		return options.MinWindow
+	}
+	if size > options.MaxWindow {
+		return options.MaxWindow
+	}
+	return size
+}
+
+/*
+Plans the sub-windows in order and starts fetching them with bounded concurrency. The jobs are passed in order
+to the consumer. The size of the next sub-window depends on the density of the sub-windows fetched so far.
+*/
+func (planner *QueryPlanner) plan(query *MeasurementQuery, jobs chan<- *windowJob, stop <-chan struct{}) {
+	defer close(jobs)
+
+	var mutex sync.Mutex
+	size := planner.options.InitialWindow
+	running := make(chan struct{}, planner.options.Concurrency)
+
+	// The window is widened to full seconds. The measurements outside of the query window are dropped by fetchWindow.
+	from, to := query.DateFrom.Truncate(time.Second), query.DateTo.Truncate(time.Second)
+	if to.Before(*query.DateTo) {
+		to = to.Add(time.Second)
+	}
+	for from.Before(to) {
+		select {
+		case running <- struct{}{}:
+		case <-stop:
+			return
+		}
+
+		mutex.Lock()
+		windowSize := size
+		mutex.Unlock()
+
+		var windowFrom, windowTo time.Time
+		if query.Revert {
+			windowFrom, windowTo = to.Add(-windowSize), to
+			if windowFrom.Before(from) {
+				windowFrom = from
+			}
+			to = windowFrom
+		} else {
+			windowFrom, windowTo = from, from.Add(windowSize)
+			if windowTo.After(to) {
+				windowTo = to
+			}
+			from = windowTo
+		}
+
+		job := &windowJob{result: make(chan windowResult, 1)}
+		go func() {
+			result := planner.fetchWindow(query, windowFrom, windowTo, stop)
+
+			mutex.Lock()
+			if result.dense {
+				size = clampWindow(size/2, planner.options)
+			} else if len(result.measurements) < planner.options.PageSize/4 {
+				size = clampWindow(size*2, planner.options)
+			}
+			mutex.Unlock()
+
+			<-running
+			job.result <- result
+		}()
+
+		select {
+		case jobs <- job:
+		case <-stop:
+			return
+		}
+	}
+}
+
+/*
+Fetches the measurements of a sub-window. If the first page is full, the page is kept up to its last full second
+and the rest of the sub-window is fetched as new window, so no page is fetched twice. Only if a single second
+holds more than a page, the remaining sub-window is paged. Stops before each request, once `stop` is closed.
+*/
+func (planner *QueryPlanner) fetchWindow(query *MeasurementQuery, from time.Time, to time.Time, stop <-chan struct{}) windowResult {
+	pageSize := planner.options.PageSize
+	var result windowResult
+
+	for from.Before(to) {
+		if stopped(stop) {
+			return windowResult{err: queryStoppedErr}
+		}
+
+		windowQuery := *query
+		windowFrom, windowTo := from, to
+		windowQuery.DateFrom, windowQuery.DateTo = &windowFrom, &windowTo
+
+		collection, err := planner.api.Find(&windowQuery, pageSize)
+		if err != nil {
+			return windowResult{err: err}
+		}
+		page := collection.Measurements
+		if len(page) < pageSize {
+			result.measurements = appendWithin(result.measurements, page, query, from, to)
+			return result
+		}
+		result.dense = true
+
+		// The page holds all measurements of the window before (or after, if reverted) the second of its last measurement
+		if last := page[len(page)-1].Time; last != nil {
+			if !query.Revert && last.Truncate(time.Second).After(from) {
+				boundary := last.Truncate(time.Second)
+				result.measurements = appendWithin(result.measurements, page, query, from, boundary)
+				from = boundary
+				continue
+			}
+			if query.Revert && last.Truncate(time.Second).Add(time.Second).Before(to) {
+				boundary := last.Truncate(time.Second).Add(time.Second)
+				result.measurements = appendWithin(result.measurements, page, query, boundary, to)
+				to = boundary
+				continue
+			}
+		}
+
+		for ; collection != nil && err == nil; collection, err = planner.api.NextPage(collection) {
+			result.measurements = appendWithin(result.measurements, collection.Measurements, query, from, to)
+			if len(collection.Measurements) < pageSize {
+				break
+			}
+			if stopped(stop) {
+				return windowResult{err: queryStoppedErr}
+			}
+		}
+		if err != nil {
+			return windowResult{err: err}
+		}
+		return result
+	}
+	return result
+}
+
+/*
+Appends the measurements within [from, to) and the window of the query. The end is exclusive to avoid duplicates
+on the boundaries of sub-windows.
+*/
+func appendWithin(result []Measurement, measurements []Measurement, query *MeasurementQuery, from time.Time, to time.Time) []Measurement {
+	for _, m := range measurements {
+		if m.Time != nil && !m.Time.Before(from) && m.Time.Before(to) && !m.Time.Before(*query.DateFrom) && m.Time.Before(*query.DateTo) {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
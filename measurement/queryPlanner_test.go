@@ -0,0 +1,262 @@
+package measurement
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var plannerStart, _ = time.Parse(time.RFC3339, "2020-01-01T00:00:00Z")
+
+/*
+A test server with measurements at the given times. It filters by [dateFrom, dateTo), sorts by `revert` and pages
+by `pageSize` and `currentPage`. The requested windows and pages are captured.
+*/
+func plannerHttpServer(times []time.Time, windows *[]string, mutex *sync.Mutex) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		from, _ := time.Parse(time.RFC3339, query.Get("dateFrom"))
+		to, _ := time.Parse(time.RFC3339, query.Get("dateTo"))
+		pageSize, _ := strconv.Atoi(query.Get("pageSize"))
+		currentPage, _ := strconv.Atoi(query.Get("currentPage"))
+		if currentPage == 0 {
+			currentPage = 1
+		}
+		mutex.Lock()
+		*windows = append(*windows, fmt.Sprintf("%s/%s#%d", query.Get("dateFrom"), query.Get("dateTo"), currentPage))
+		mutex.Unlock()
+
+		var matching []string
+		for _, t := range times {
+			if !t.Before(from) && t.Before(to) {
+				m := fmt.Sprintf(`{"id": "%d", "time": "%s", "type": "c8y_Counter", "source": {"id": "1111111"}}`, t.UnixNano(), t.Format(time.RFC3339Nano))
+				if query.Get("revert") == "true" {
+					matching = append([]string{m}, matching...)
+				} else {
+					matching = append(matching, m)
+				}
+			}
+		}
+
+		start, end := (currentPage-1)*pageSize, currentPage*pageSize
+		if start > len(matching) {
+			start = len(matching)
+		}
+		if end > len(matching) {
+			end = len(matching)
+		}
+
+		query.Set("currentPage", strconv.Itoa(currentPage+1))
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"next": "http://%s%s?%s", "measurements": [%s]}`,
+			r.Host, r.URL.Path, query.Encode(), strings.Join(matching[start:end], ","))))
+	}))
+}
+
+func minutes(from time.Time, count int) []time.Time {
+	var times []time.Time
+	for i := 0; i < count; i++ {
+		times = append(times, from.Add(time.Duration(i)*time.Minute))
+	}
+	return times
+}
+
+func collectIds(planner *QueryPlanner, query *MeasurementQuery) ([]string, error) {
+	var ids []string
+	err := planner.Query(query, func(m *Measurement) error {
+		ids = append(ids, m.Id)
+		return nil
+	})
+	if err != nil {
+		return ids, err
+	}
+	return ids, nil
+}
+
+func expectedIds(times []time.Time, revert bool) []string {
+	var ids []string
+	for _, t := range times {
+		id := strconv.FormatInt(t.UnixNano(), 10)
+		if revert {
+			ids = append([]string{id}, ids...)
+		} else {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestQueryPlanner_Query(t *testing.T) {
+	// Sparse data on the first day, dense data on the third day
+	times := append(minutes(plannerStart.Add(time.Hour), 5), minutes(plannerStart.Add(50*time.Hour), 300)...)
+
+	for _, revert := range []bool{false, true} {
+		t.Run(fmt.Sprintf("revert=%v", revert), func(t *testing.T) {
+			var windows []string
+			var mutex sync.Mutex
+			ts := plannerHttpServer(times, &windows, &mutex)
+			defer ts.Close()
+
+			planner := NewQueryPlanner(buildMeasurementApi(ts.URL), PlannerOptions{
+				InitialWindow: 24 * time.Hour,
+				MinWindow:     time.Hour,
+				PageSize:      100,
+				Concurrency:   3,
+			})
+
+			dateTo := plannerStart.Add(4 * 24 * time.Hour)
+			ids, err := collectIds(planner, &MeasurementQuery{DateFrom: &plannerStart, DateTo: &dateTo, Revert: revert})
+
+			if err != nil {
+				t.Fatalf("Query() got an unexpected error: %s", err.Error())
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(expectedIds(times, revert)) {
+				t.Errorf("Query() returned %d measurements, want %d in order", len(ids), len(times))
+			}
+			if len(windows) <= 4 {
+				t.Errorf("Query() windows = %v, want the dense day to be split", windows)
+			}
+		})
+	}
+}
+
+func TestQueryPlanner_HandlerStopsQuery(t *testing.T) {
+	var windows []string
+	var mutex sync.Mutex
+	ts := plannerHttpServer(minutes(plannerStart, 100), &windows, &mutex)
+	defer ts.Close()
+
+	planner := NewQueryPlanner(buildMeasurementApi(ts.URL), PlannerOptions{InitialWindow: 10 * time.Minute})
+
+	dateTo := plannerStart.Add(24 * time.Hour)
+	count := 0
+	err := planner.Query(&MeasurementQuery{DateFrom: &plannerStart, DateTo: &dateTo}, func(m *Measurement) error {
+		count++
+		if count == 3 {
+			return errors.New("enough")
+		}
+		return nil
+	})
+
+	if err == nil || !strings.Contains(err.Message, "enough") || count != 3 {
+		t.Errorf("Query() = %d measurements, %v - want to stop after 3", count, err)
+	}
+}
+
+func TestQueryPlanner_SubSecondBounds(t *testing.T) {
+	times := []time.Time{
+		plannerStart.Add(100 * time.Millisecond),
+		plannerStart.Add(300 * time.Millisecond),
+		plannerStart.Add(10*time.Minute + 500*time.Millisecond),
+		plannerStart.Add(10*time.Minute + 900*time.Millisecond),
+	}
+	var windows []string
+	var mutex sync.Mutex
+	ts := plannerHttpServer(times, &windows, &mutex)
+	defer ts.Close()
+
+	planner := NewQueryPlanner(buildMeasurementApi(ts.URL), PlannerOptions{})
+
+	// The query window starts and ends within a second
+	dateFrom, dateTo := plannerStart.Add(200*time.Millisecond), plannerStart.Add(10*time.Minute+700*time.Millisecond)
+	ids, err := collectIds(planner, &MeasurementQuery{DateFrom: &dateFrom, DateTo: &dateTo})
+
+	if err != nil {
+		t.Fatalf("Query() got an unexpected error: %s", err.Error())
+	}
+	if want := expectedIds(times[1:3], false); fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("Query() = %v, want %v", ids, want)
+	}
+}
+
+func TestQueryPlanner_DenseWindowWithoutExtraRequests(t *testing.T) {
+	for _, revert := range []bool{false, true} {
+		t.Run(fmt.Sprintf("revert=%v", revert), func(t *testing.T) {
+			times := minutes(plannerStart, 250)
+			var windows []string
+			var mutex sync.Mutex
+			ts := plannerHttpServer(times, &windows, &mutex)
+			defer ts.Close()
+
+			planner := NewQueryPlanner(buildMeasurementApi(ts.URL), PlannerOptions{
+				InitialWindow: 24 * time.Hour,
+				MaxWindow:     24 * time.Hour,
+				PageSize:      100,
+			})
+
+			dateTo := plannerStart.Add(24 * time.Hour)
+			ids, err := collectIds(planner, &MeasurementQuery{DateFrom: &plannerStart, DateTo: &dateTo, Revert: revert})
+
+			if err != nil {
+				t.Fatalf("Query() got an unexpected error: %s", err.Error())
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(expectedIds(times, revert)) {
+				t.Errorf("Query() returned %d measurements, want %d in order", len(ids), len(times))
+			}
+			// then: Each full page is used, so 250 measurements take 3 requests
+			if len(windows) != 3 {
+				t.Errorf("Query() windows = %v, want 3 requests", windows)
+			}
+		})
+	}
+}
+
+func TestQueryPlanner_NoRequestsAfterStop(t *testing.T) {
+	var windows []string
+	var mutex sync.Mutex
+	ts := plannerHttpServer(minutes(plannerStart, 2*24*60), &windows, &mutex)
+	defer ts.Close()
+
+	options := PlannerOptions{InitialWindow: time.Hour, MinWindow: time.Hour, MaxWindow: time.Hour, PageSize: 5, Concurrency: 4}
+	planner := NewQueryPlanner(buildMeasurementApi(ts.URL), options)
+
+	dateTo := plannerStart.Add(2 * 24 * time.Hour)
+	err := planner.Query(&MeasurementQuery{DateFrom: &plannerStart, DateTo: &dateTo}, func(m *Measurement) error {
+		return errors.New("enough")
+	})
+	if err == nil {
+		t.Fatalf("Query() expected the handler error")
+	}
+
+	mutex.Lock()
+	requests := len(windows)
+	mutex.Unlock()
+	time.Sleep(100 * time.Millisecond)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// then: Each running sub-window completes at most its current request
+	if len(windows) > requests+options.Concurrency {
+		t.Errorf("Query() sent %d requests after it was stopped", len(windows)-requests)
+	}
+}
+
+func TestQueryPlanner_Errors(t *testing.T) {
+	ts := buildHttpServer(500, `{"error": "server", "message": "timeout"}`)
+	defer ts.Close()
+
+	planner := NewQueryPlanner(buildMeasurementApi(ts.URL), PlannerOptions{})
+	dateTo := plannerStart.Add(72 * time.Hour)
+
+	tests := []struct {
+		name  string
+		query *MeasurementQuery
+	}{
+		{"no window", &MeasurementQuery{}},
+		{"reverted window", &MeasurementQuery{DateFrom: &dateTo, DateTo: &plannerStart}},
+		{"server error", &MeasurementQuery{DateFrom: &plannerStart, DateTo: &dateTo}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := collectIds(planner, tt.query); err == nil {
+				t.Errorf("Query() expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+/*
+Package units parses the units of measurement values and converts values between units of the same dimension,
+e.g. from °F to °C or from kWh to Wh.
+
+Units are looked up by their symbol or an alias, e.g. `°C`, `C` and `degC` are the same unit. Further units can
+be added with `Register`.
+*/
+package units
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The physical dimension of a unit. Only units of the same dimension can be converted into each other.
+type Dimension string
+
+const (
+	TEMPERATURE Dimension = "TEMPERATURE"
+	PRESSURE    Dimension = "PRESSURE"
+	POWER       Dimension = "POWER"
+	ENERGY      Dimension = "ENERGY"
+	VOLTAGE     Dimension = "VOLTAGE"
+	CURRENT     Dimension = "CURRENT"
+	FREQUENCY   Dimension = "FREQUENCY"
+	LENGTH      Dimension = "LENGTH"
+	SPEED       Dimension = "SPEED"
+	RATIO       Dimension = "RATIO"
+)
+
+/*
+Unit is a unit of a dimension. A value of the unit is converted into the base unit of its dimension
+by `value * Factor + Offset`, e.g. °C into K by `value * 1 + 273.15`.
+*/
+type Unit struct {
+	Symbol    string
+	Dimension Dimension
+	Factor    float64
+	Offset    float64
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[string]Unit{}
+)
+
+func init() {
+	for _, u := range []struct {
+		unit    Unit
+		aliases []string
+	}{
+		{Unit{"K", TEMPERATURE, 1, 0}, []string{"kelvin"}},
+		{Unit{"°C", TEMPERATURE, 1, 273.15}, []string{"C", "degC", "℃", "celsius"}},
+		{Unit{"°F", TEMPERATURE, 5.0 / 9.0, 273.15 - 32*5.0/9.0}, []string{"F", "degF", "℉", "fahrenheit"}},
+
+		{Unit{"Pa", PRESSURE, 1, 0}, nil},
+		{Unit{"hPa", PRESSURE, 100, 0}, nil},
+		{Unit{"kPa", PRESSURE, 1e3, 0}, nil},
+		{Unit{"MPa", PRESSURE, 1e6, 0}, nil},
+		{Unit{"mbar", PRESSURE, 100, 0}, nil},
+		{Unit{"bar", PRESSURE, 1e5, 0}, nil},
+		{Unit{"psi", PRESSURE, 6894.757293168, 0}, nil},
+		{Unit{"atm", PRESSURE, 101325, 0}, nil},
+
+		{Unit{"mW", POWER, 1e-3, 0}, nil},
+		{Unit{"W", POWER, 1, 0}, []string{"watt"}},
+		{Unit{"kW", POWER, 1e3, 0}, nil},
+		{Unit{"MW", POWER, 1e6, 0}, nil},
+
+		{Unit{"J", ENERGY, 1, 0}, nil},
+		{Unit{"kJ", ENERGY, 1e3, 0}, nil},
+		{Unit{"Wh", ENERGY, 3600, 0}, nil},
+		{Unit{"kWh", ENERGY, 3.6e6, 0}, nil},
+		{Unit{"MWh", ENERGY, 3.6e9, 0}, nil},
+
+		{Unit{"mV", VOLTAGE, 1e-3, 0}, nil},
+		{Unit{"V", VOLTAGE, 1, 0}, []string{"volt"}},
+		{Unit{"kV", VOLTAGE, 1e3, 0}, nil},
+
+		{Unit{"mA", CURRENT, 1e-3, 0}, nil},
+		{Unit{"A", CURRENT, 1, 0}, []string{"ampere"}},
+
+		{Unit{"Hz", FREQUENCY, 1, 0}, nil},
+		{Unit{"kHz", FREQUENCY, 1e3, 0}, nil},
+		{Unit{"MHz", FREQUENCY, 1e6, 0}, nil},
+
+		{Unit{"mm", LENGTH, 1e-3, 0}, nil},
+		{Unit{"cm", LENGTH, 1e-2, 0}, nil},
+		{Unit{"m", LENGTH, 1, 0}, nil},
+		{Unit{"km", LENGTH, 1e3, 0}, nil},
+
+		{Unit{"m/s", SPEED, 1, 0}, nil},
+		{Unit{"km/h", SPEED, 1 / 3.6, 0}, []string{"kmh"}},
+		{Unit{"mph", SPEED, 0.44704, 0}, nil},
+
+		{Unit{"%", RATIO, 0.01, 0}, []string{"percent"}},
+	} {
+		if err := Register(u.unit, u.aliases...); err != nil {
+			panic(err)
+		}
+	}
+}
+
+/*
+Registers a unit by its symbol and aliases. Returns an error, if the symbol or an alias is already registered
+or the factor is 0.
+*/
+func Register(unit Unit, aliases ...string) error {
+	if len(unit.Symbol) == 0 || len(unit.Dimension) == 0 || unit.Factor == 0 {
+		return fmt.Errorf("the unit '%s' needs a symbol, a dimension and a factor", unit.Symbol)
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	names := append([]string{unit.Symbol}, aliases...)
+	for _, name := range names {
+		if _, ok := registry[normalize(name)]; ok {
+			return fmt.Errorf("the unit '%s' is already registered", name)
+		}
+	}
+	for _, name := range names {
+		registry[normalize(name)] = unit
+	}
+	return nil
+}
+
+// Returns the unit of a symbol or alias. Returns an error for unknown units.
+func Parse(symbol string) (Unit, error) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	unit, ok := registry[normalize(symbol)]
+	if !ok {
+		return Unit{}, fmt.Errorf("unknown unit '%s'", symbol)
+	}
+	return unit, nil
+}
+
+// Returns the symbols of all registered units of a dimension, sorted.
+func Symbols(dimension Dimension) []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	seen := map[string]bool{}
+	var symbols []string
+	for _, unit := range registry {
+		if unit.Dimension == dimension && !seen[unit.Symbol] {
+			seen[unit.Symbol] = true
+			symbols = append(symbols, unit.Symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Converts a value of this unit into the target unit. Returns an error, if the dimensions differ.
+func (u Unit) Convert(value float64, target Unit) (float64, error) {
+	if u.Dimension != target.Dimension {
+		return 0, fmt.Errorf("can not convert %s (%s) into %s (%s)", u.Symbol, strings.ToLower(string(u.Dimension)),
+			target.Symbol, strings.ToLower(string(target.Dimension)))
+	}
+	if u == target {
+		return value, nil
+	}
+
+	base := value*u.Factor + u.Offset
+	return (base - target.Offset) / target.Factor, nil
+}
+
+// Converts a value between units given by symbol or alias, e.g. `Convert(70, "°F", "C")`.
+func Convert(value float64, from string, to string) (float64, error) {
+	fromUnit, err := Parse(from)
+	if err != nil {
+		return 0, err
+	}
+	toUnit, err := Parse(to)
+	if err != nil {
+		return 0, err
+	}
+	return fromUnit.Convert(value, toUnit)
+}
+
+// -- internal
+
+// Symbols are case sensitive (mW vs. MW), only surrounding spaces are ignored.
+func normalize(symbol string) string {
+	return strings.TrimSpace(symbol)
+}
@@ -0,0 +1,76 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value float64
+		from  string
+		to    string
+		want  float64
+	}{
+		{100, "°C", "°F", 212},
+		{32, "F", "C", 0},
+		{0, "degC", "K", 273.15},
+		{1, "bar", "hPa", 1000},
+		{14.5037738, "psi", "bar", 1},
+		{1500, "W", "kW", 1.5},
+		{2, "kWh", "Wh", 2000},
+		{1, "kWh", "J", 3.6e6},
+		{36, "km/h", "m/s", 10},
+		{42, "%", "%", 42},
+		{21.5, " °C ", "celsius", 21.5},
+	}
+
+	for _, tt := range tests {
+		got, err := Convert(tt.value, tt.from, tt.to)
+		if err != nil {
+			t.Errorf("Convert(%v, %s, %s) got an unexpected error: %s", tt.value, tt.from, tt.to, err.Error())
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("Convert(%v, %s, %s) = %v, want %v", tt.value, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestConvert_Errors(t *testing.T) {
+	for _, tt := range []struct{ from, to string }{
+		{"°C", "bar"},
+		{"furlong", "m"},
+		{"W", "parsec"},
+		{"mw", "W"},
+	} {
+		if _, err := Convert(1, tt.from, tt.to); err == nil {
+			t.Errorf("Convert(1, %s, %s) expected an error", tt.from, tt.to)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if err := Register(Unit{Symbol: "mmHg", Dimension: PRESSURE, Factor: 133.322387415}, "torr"); err != nil {
+		t.Fatalf("Register() got an unexpected error: %s", err.Error())
+	}
+
+	got, err := Convert(760, "torr", "atm")
+	if err != nil || math.Abs(got-1) > 1e-6 {
+		t.Errorf("Convert(760, torr, atm) = %v, %v - want 1", got, err)
+	}
+
+	if err := Register(Unit{Symbol: "C", Dimension: CURRENT, Factor: 1}); err == nil {
+		t.Errorf("Register() of an existing alias expected an error")
+	}
+	if err := Register(Unit{Symbol: "x", Dimension: RATIO}); err == nil {
+		t.Errorf("Register() without factor expected an error")
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	symbols := Symbols(TEMPERATURE)
+	if len(symbols) != 3 || symbols[0] != "K" {
+		t.Errorf("Symbols(TEMPERATURE) = %v, want [K °C °F]", symbols)
+	}
+}
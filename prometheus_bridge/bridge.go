@@ -0,0 +1,338 @@
+/*
+Package prometheus_bridge scrapes Prometheus exporters and writes their metrics as measurements to cumulocity.
+
+Which samples are written and how they are mapped to sources, fragments and series is configured by rules.
+*/
+package prometheus_bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/tarent/gomulocity/measurement"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const textFormatAcceptHeader = "text/plain;version=0.0.4"
+
+/*
+Rule maps the samples of matching metrics to a series of a measurement. A sample is mapped by the first
+matching rule of its target; samples without matching rule are dropped. Samples mapped to the same series of a
+measurement are reported and only the first one is written, so `SeriesLabel` must distinguish them.
+*/
+type Rule struct {
+	Metric      string            // A regular expression matching the whole metric name, e.g. `node_cpu_.*`
+	Labels      map[string]string // Only samples with these label values match, if set
+	Type        string            // The measurement type. Default: the fragment
+	Fragment    string            // The fragment. Default: the metric name
+	Series      string            // The series. Default: the value of `SeriesLabel` or the metric name
+	SeriesLabel string            // The label containing the series name, e.g. `cpu`
+	SourceLabel string            // The label containing the source. Default: the source of the target
+	Unit        string            // The unit of the series
+
+	metric *regexp.Regexp
+}
+
+/*
+Target is an exporter to scrape.
+*/
+type Target struct {
+	URL    string // The URL of the metrics endpoint, e.g. http://localhost:9100/metrics
+	Source string // The source id of samples, whose rule has no source label
+	Rules  []Rule
+}
+
+/*
+Config configures a Bridge.
+*/
+type Config struct {
+	Targets   []Target
+	Interval  time.Duration // The scrape interval of Run. Default: 1m
+	BatchSize int           // The number of measurements per `CreateMany` request. Default: 200
+	// Resolves the value of a source label to the id of a managed object, e.g. by an external id.
+	// If nil, the label value is the source id.
+	ResolveSource func(label string) (string, error)
+	HTTPClient    *http.Client // The client to scrape the targets. Default: a client with a timeout of 10s
+}
+
+/*
+ScrapeResult is the result of scraping all targets once.
+*/
+type ScrapeResult struct {
+	Samples      int     // The scraped samples of all targets
+	Measurements int     // The created measurements
+	Errors       []error // Errors of single targets or uploads. The other targets are written anyway
+}
+
+/*
+Bridge scrapes Prometheus exporters and writes the samples as measurements via `CreateMany`.
+The samples of a scrape are grouped into one measurement per source, type and time.
+*/
+type Bridge struct {
+	api    measurement.MeasurementApi
+	config Config
+	now    func() time.Time
+}
+
+// Creates a new bridge. Returns an error, if the config is invalid.
+func NewBridge(api measurement.MeasurementApi, config Config) (*Bridge, error) {
+	if len(config.Targets) == 0 {
+		return nil, errors.New("at least one target is required")
+	}
+
+	// Copy the targets and rules, so the compiled expressions are not stored in the config of the caller
+	config.Targets = append([]Target(nil), config.Targets...)
+	for t := range config.Targets {
+		target := &config.Targets[t]
+		if len(target.URL) == 0 {
+			return nil, fmt.Errorf("target %d has no url", t)
+		}
+		target.Rules = append([]Rule(nil), target.Rules...)
+		for r := range target.Rules {
+			rule := &target.Rules[r]
+			metric, err := regexp.Compile("^(?:" + rule.Metric + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid metric of rule %d of target %s: %s", r, target.URL, err.Error())
+			}
+			rule.metric = metric
+			if len(rule.SourceLabel) == 0 && len(target.Source) == 0 {
+				return nil, fmt.Errorf("rule %d of target %s has no source label and the target has no source", r, target.URL)
+			}
+		}
+	}
+
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 200
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Bridge{api: api, config: config, now: time.Now}, nil
+}
+
+// Scrapes all targets once and writes the samples as measurements.
+func (bridge *Bridge) Scrape() ScrapeResult {
+	var result ScrapeResult
+	var measurements []measurement.NewMeasurement
+
+	for _, target := range bridge.config.Targets {
+		samples, err := bridge.scrape(target)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to scrape %s: %s", target.URL, err.Error()))
+			continue
+		}
+		result.Samples += len(samples)
+
+		targetMeasurements, errs := bridge.measurements(target, samples)
+		measurements = append(measurements, targetMeasurements...)
+		result.Errors = append(result.Errors, errs...)
+	}
+
+	for start := 0; start < len(measurements); start += bridge.config.BatchSize {
+		end := start + bridge.config.BatchSize
+		if end > len(measurements) {
+			end = len(measurements)
+		}
+		if _, err := bridge.api.CreateMany(&measurement.NewMeasurements{Measurements: measurements[start:end]}); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to create measurements: %s", err.Error()))
+			continue
+		}
+		result.Measurements += end - start
+	}
+	return result
+}
+
+/*
+Scrapes immediately and then periodically, until the context is done.
+The result of each scrape is passed to `report`, if it is not nil.
+*/
+func (bridge *Bridge) Run(ctx context.Context, report func(result ScrapeResult)) {
+	ticker := time.NewTicker(bridge.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		result := bridge.Scrape()
+		if report != nil {
+			report(result)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// -- internal
+
+func (bridge *Bridge) scrape(target Target) ([]Sample, error) {
+	req, err := http.NewRequest(http.MethodGet, target.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", textFormatAcceptHeader)
+
+	resp, err := bridge.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return ParseTextFormat(resp.Body)
+}
+
+type measurementKey struct {
+	source string
+	typ    string
+	time   int64
+}
+
+type seriesKey struct {
+	measurementKey
+	fragment string
+	series   string
+}
+
+/*
+Maps the samples by the rules of the target. Samples, whose source can not be resolved, are reported and skipped.
+Samples, which map to the series of an earlier sample, e.g. because they differ only in labels not used for the
+series name, are reported and dropped, so no value is overwritten silently.
+*/
+func (bridge *Bridge) measurements(target Target, samples []Sample) ([]measurement.NewMeasurement, []error) {
+	var errs []error
+	var keys []measurementKey
+	builders := map[measurementKey]*measurement.Builder{}
+	written := map[seriesKey]Sample{}
+	now := bridge.now()
+
+	for _, sample := range samples {
+		rule := matchingRule(target.Rules, sample)
+		if rule == nil || math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+			continue
+		}
+
+		source, err := bridge.source(target, rule, sample)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		fragment := rule.Fragment
+		if len(fragment) == 0 {
+			fragment = sample.Name
+		}
+		series := rule.Series
+		if len(series) == 0 {
+			series = sample.Labels[rule.SeriesLabel]
+		}
+		if len(series) == 0 {
+			series = sample.Name
+		}
+		typ := rule.Type
+		if len(typ) == 0 {
+			typ = fragment
+		}
+		t := now
+		if sample.Time != nil {
+			t = *sample.Time
+		}
+
+		key := measurementKey{source: source, typ: typ, time: t.UnixNano()}
+		if earlier, ok := written[seriesKey{key, fragment, series}]; ok {
+			errs = append(errs, fmt.Errorf("sample %s of %s is dropped: it maps to the series %s.%s of source %s like %s",
+				formatSample(sample), target.URL, fragment, series, source, formatSample(earlier)))
+			continue
+		}
+		written[seriesKey{key, fragment, series}] = sample
+
+		builder, ok := builders[key]
+		if !ok {
+			builder = measurement.New(source, typ).At(t)
+			builders[key] = builder
+			keys = append(keys, key)
+		}
+		builder.Value(fragment, series, sample.Value, rule.Unit)
+	}
+
+	measurements := make([]measurement.NewMeasurement, 0, len(keys))
+	for _, key := range keys {
+		measurements = append(measurements, *builders[key].Build())
+	}
+	return measurements, errs
+}
+
+// Formats the name and the sorted labels of a sample, e.g. `node_cpu_usage{cpu="0"}`.
+func formatSample(sample Sample) string {
+	names := make([]string, 0, len(sample.Labels))
+	for name := range sample.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	labels := make([]string, 0, len(names))
+	for _, name := range names {
+		labels = append(labels, fmt.Sprintf("%s=%q", name, sample.Labels[name]))
+	}
+	return sample.Name + "{" + strings.Join(labels, ",") + "}"
+}
+
+func matchingRule(rules []Rule, sample Sample) *Rule {
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.metric.MatchString(sample.Name) {
+			continue
+		}
+
+		matches := true
+		for label, value := range rule.Labels {
+			if sample.Labels[label] != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (bridge *Bridge) source(target Target, rule *Rule, sample Sample) (string, error) {
+	if len(rule.SourceLabel) == 0 {
+		return target.Source, nil
+	}
+
+	label := sample.Labels[rule.SourceLabel]
+	if len(label) == 0 {
+		if len(target.Source) > 0 {
+			return target.Source, nil
+		}
+		return "", fmt.Errorf("sample %s of %s has no label '%s'", sample.Name, target.URL, rule.SourceLabel)
+	}
+	if bridge.config.ResolveSource == nil {
+		return label, nil
+	}
+
+	source, err := bridge.config.ResolveSource(label)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve source '%s' of %s: %s", label, target.URL, err.Error())
+	}
+	return source, nil
+}
@@ -0,0 +1,221 @@
+package prometheus_bridge
+
+import (
+	"context"
+	"errors"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/measurement"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var scrapeTime, _ = time.Parse(time.RFC3339, "2020-06-30T10:00:00Z")
+
+const exporterMetrics = `# TYPE node_cpu_usage gauge
+node_cpu_usage{cpu="0"} 0.25
+node_cpu_usage{cpu="1"} 0.75
+node_memory_free_bytes 1024
+node_filesystem_free_bytes{device="sda1",mountpoint="/"} 2048
+node_filesystem_free_bytes{device="sda1",mountpoint="/boot"} 512
+node_unmapped 1
+node_broken NaN
+`
+
+const gatewayMetrics = `device_temperature{device="roof"} 21.5
+device_temperature{device="cellar"} 12.5
+device_temperature{device="unknown"} 30
+`
+
+// A cumulocity test server capturing the created measurements.
+func measurementHttpServer(created *[]measurement.NewMeasurement) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var measurements measurement.NewMeasurements
+		_ = generic.ObjectFromJson(body, &measurements)
+		*created = append(*created, measurements.Measurements...)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	}))
+}
+
+func exporterHttpServer(metrics string, accept *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept != nil {
+			*accept = r.Header.Get("Accept")
+		}
+		_, _ = w.Write([]byte(metrics))
+	}))
+}
+
+func buildBridge(t *testing.T, c8yUrl string, config Config) *Bridge {
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: c8yUrl, Username: "foo", Password: "bar"}
+	bridge, err := NewBridge(measurement.NewMeasurementApi(client), config)
+	if err != nil {
+		t.Fatalf("NewBridge() got an unexpected error: %s", err.Error())
+	}
+	bridge.now = func() time.Time { return scrapeTime }
+	return bridge
+}
+
+func TestBridge_Scrape(t *testing.T) {
+	var created []measurement.NewMeasurement
+	c8y := measurementHttpServer(&created)
+	defer c8y.Close()
+
+	var accept string
+	exporter := exporterHttpServer(exporterMetrics, &accept)
+	defer exporter.Close()
+	gateway := exporterHttpServer(gatewayMetrics, nil)
+	defer gateway.Close()
+
+	bridge := buildBridge(t, c8y.URL, Config{
+		Targets: []Target{
+			{URL: exporter.URL, Source: "4711", Rules: []Rule{
+				{Metric: "node_cpu_usage", Type: "c8y_Node", Fragment: "c8y_CPU", SeriesLabel: "cpu", Unit: "%"},
+				{Metric: "node_.*_free_bytes", Labels: map[string]string{"mountpoint": "/"}, Type: "c8y_Node", Fragment: "c8y_Free", Unit: "B"},
+				{Metric: "node_broken"},
+			}},
+			{URL: gateway.URL, Rules: []Rule{
+				{Metric: "device_temperature", Fragment: "c8y_Temperature", Series: "T", SourceLabel: "device", Unit: "C"},
+			}},
+		},
+		ResolveSource: func(label string) (string, error) {
+			switch label {
+			case "roof":
+				return "1001", nil
+			case "cellar":
+				return "1002", nil
+			}
+			return "", errors.New("unknown device")
+		},
+	})
+
+	result := bridge.Scrape()
+
+	if result.Samples != 10 || result.Measurements != 3 || len(result.Errors) != 1 {
+		t.Errorf("Scrape() = %+v, want 10 samples, 3 measurements and 1 error", result)
+	}
+	if accept != textFormatAcceptHeader {
+		t.Errorf("Scrape() accept header = %s, want %s", accept, textFormatAcceptHeader)
+	}
+	if len(created) != 3 {
+		t.Fatalf("Scrape() created %d measurements, want 3", len(created))
+	}
+
+	node := created[0]
+	if node.Source.Id != "4711" || node.MeasurementType != "c8y_Node" || !node.Time.Equal(scrapeTime) {
+		t.Errorf("Scrape() node measurement = %+v", node)
+	}
+	if want := map[string]measurement.ValueFragment{"0": {Value: 0.25, Unit: "%"}, "1": {Value: 0.75, Unit: "%"}}; !reflect.DeepEqual(node.Series("c8y_CPU"), want) {
+		t.Errorf("Scrape() cpu series = %v, want %v", node.Series("c8y_CPU"), want)
+	}
+	if want := map[string]measurement.ValueFragment{"node_filesystem_free_bytes": {Value: 2048, Unit: "B"}}; !reflect.DeepEqual(node.Series("c8y_Free"), want) {
+		t.Errorf("Scrape() free series = %v, want %v", node.Series("c8y_Free"), want)
+	}
+
+	roof, cellar := created[1], created[2]
+	if roof.Source.Id != "1001" || roof.MeasurementType != "c8y_Temperature" || cellar.Source.Id != "1002" {
+		t.Errorf("Scrape() temperature measurements = %+v, %+v", roof, cellar)
+	}
+	if value, _ := cellar.Value("c8y_Temperature", "T"); value != (measurement.ValueFragment{Value: 12.5, Unit: "C"}) {
+		t.Errorf("Scrape() cellar temperature = %v", value)
+	}
+}
+
+func TestBridge_ScrapeFailingTarget(t *testing.T) {
+	var created []measurement.NewMeasurement
+	c8y := measurementHttpServer(&created)
+	defer c8y.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	exporter := exporterHttpServer("up 1\n", nil)
+	defer exporter.Close()
+
+	bridge := buildBridge(t, c8y.URL, Config{Targets: []Target{
+		{URL: failing.URL, Source: "4711", Rules: []Rule{{Metric: ".*"}}},
+		{URL: exporter.URL, Source: "4712", Rules: []Rule{{Metric: ".*"}}},
+	}})
+
+	result := bridge.Scrape()
+
+	if len(result.Errors) != 1 || result.Measurements != 1 || len(created) != 1 || created[0].Source.Id != "4712" {
+		t.Errorf("Scrape() = %+v, created %v - want the second target written", result, created)
+	}
+}
+
+func TestBridge_ScrapeSeriesCollision(t *testing.T) {
+	var created []measurement.NewMeasurement
+	c8y := measurementHttpServer(&created)
+	defer c8y.Close()
+	exporter := exporterHttpServer(`node_cpu_usage{cpu="0",mode="user"} 0.25
+node_cpu_usage{cpu="0",mode="system"} 0.5
+node_cpu_usage{cpu="1",mode="user"} 0.75
+`, nil)
+	defer exporter.Close()
+
+	// given: The series label does not distinguish the modes
+	bridge := buildBridge(t, c8y.URL, Config{Targets: []Target{
+		{URL: exporter.URL, Source: "4711", Rules: []Rule{{Metric: "node_cpu_usage", Fragment: "c8y_CPU", SeriesLabel: "cpu"}}},
+	}})
+
+	result := bridge.Scrape()
+
+	// then: The colliding sample is reported and dropped, the first one is kept
+	if len(result.Errors) != 1 || result.Measurements != 1 || len(created) != 1 {
+		t.Fatalf("Scrape() = %+v, want 1 measurement and 1 error", result)
+	}
+	if want := `node_cpu_usage{cpu="0",mode="system"}`; !strings.Contains(result.Errors[0].Error(), want) {
+		t.Errorf("Scrape() error = %s, want it to name %s", result.Errors[0], want)
+	}
+	if want := map[string]measurement.ValueFragment{"0": {Value: 0.25}, "1": {Value: 0.75}}; !reflect.DeepEqual(created[0].Series("c8y_CPU"), want) {
+		t.Errorf("Scrape() cpu series = %v, want %v", created[0].Series("c8y_CPU"), want)
+	}
+}
+
+func TestBridge_Run(t *testing.T) {
+	var created []measurement.NewMeasurement
+	c8y := measurementHttpServer(&created)
+	defer c8y.Close()
+	exporter := exporterHttpServer("up 1\n", nil)
+	defer exporter.Close()
+
+	bridge := buildBridge(t, c8y.URL, Config{
+		Interval: time.Millisecond,
+		Targets:  []Target{{URL: exporter.URL, Source: "4711", Rules: []Rule{{Metric: "up"}}}},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scrapes := 0
+	bridge.Run(ctx, func(result ScrapeResult) {
+		scrapes++
+		if scrapes == 3 {
+			cancel()
+		}
+	})
+
+	if scrapes != 3 || len(created) != 3 {
+		t.Errorf("Run() scraped %d times and created %d measurements, want 3", scrapes, len(created))
+	}
+}
+
+func TestNewBridge_InvalidConfig(t *testing.T) {
+	for name, config := range map[string]Config{
+		"no targets":   {},
+		"no url":       {Targets: []Target{{Source: "4711"}}},
+		"invalid rule": {Targets: []Target{{URL: "http://localhost", Source: "4711", Rules: []Rule{{Metric: "("}}}}},
+		"no source":    {Targets: []Target{{URL: "http://localhost", Rules: []Rule{{Metric: "up"}}}}},
+	} {
+		if _, err := NewBridge(nil, config); err == nil {
+			t.Errorf("NewBridge() with %s expected an error", name)
+		}
+	}
+}
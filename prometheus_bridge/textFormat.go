@@ -0,0 +1,147 @@
+package prometheus_bridge
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Sample is a single sample of the Prometheus text exposition format:
+
+	metric_name{label="value",...} value [timestamp]
+
+See: https://prometheus.io/docs/instrumenting/exposition_formats/#text-based-format
+*/
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+	Time   *time.Time // nil, if the sample has no timestamp
+}
+
+/*
+Parses samples of the Prometheus text format. Comments, `# HELP` and `# TYPE` lines are skipped.
+Returns an error with the line number on the first invalid line.
+*/
+func ParseTextFormat(r io.Reader) ([]Sample, error) {
+	var samples []Sample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sample, err := parseSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNumber, err.Error())
+		}
+		samples = append(samples, *sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// -- internal
+
+func parseSample(line string) (*Sample, error) {
+	sample := &Sample{Labels: map[string]string{}}
+
+	i := strings.IndexAny(line, "{ \t")
+	if i <= 0 {
+		return nil, errors.New("the value is missing")
+	}
+	sample.Name = line[:i]
+
+	if line[i] == '{' {
+		var err error
+		i, err = parseLabels(line, i+1, sample.Labels)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fields := strings.Fields(line[i:])
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, errors.New("expected a value and an optional timestamp")
+	}
+
+	value, err := parseValue(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid value '%s'", fields[0])
+	}
+	sample.Value = value
+
+	if len(fields) == 2 {
+		millis, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp '%s'", fields[1])
+		}
+		t := time.Unix(0, millis*int64(time.Millisecond)).UTC()
+		sample.Time = &t
+	}
+	return sample, nil
+}
+
+// Parses the labels after the opening brace. Returns the index after the closing brace.
+func parseLabels(line string, i int, labels map[string]string) (int, error) {
+	for {
+		for i < len(line) && (line[i] == ' ' || line[i] == ',') {
+			i++
+		}
+		if i >= len(line) {
+			return i, errors.New("unterminated labels")
+		}
+		if line[i] == '}' {
+			return i + 1, nil
+		}
+
+		equals := strings.IndexByte(line[i:], '=')
+		if equals <= 0 || i+equals+1 >= len(line) || line[i+equals+1] != '"' {
+			return i, fmt.Errorf("invalid label at %d", i)
+		}
+		name := strings.TrimSpace(line[i : i+equals])
+
+		var value strings.Builder
+		i += equals + 2
+		for ; i < len(line) && line[i] != '"'; i++ {
+			if line[i] == '\\' && i+1 < len(line) {
+				i++
+				switch line[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(line[i])
+				}
+				continue
+			}
+			value.WriteByte(line[i])
+		}
+		if i >= len(line) {
+			return i, fmt.Errorf("unterminated value of label '%s'", name)
+		}
+		labels[name] = value.String()
+		i++
+	}
+}
+
+func parseValue(value string) (float64, error) {
+	switch value {
+	case "+Inf", "Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
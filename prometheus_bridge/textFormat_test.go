@@ -0,0 +1,61 @@
+package prometheus_bridge
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTextFormat(t *testing.T) {
+	input := `# HELP node_load1 1m load average.
+# TYPE node_load1 gauge
+node_load1 0.42
+node_cpu_seconds_total{cpu="0",mode="idle"} 1234.5 1593505924000
+http_requests_total{path="/a \"b\"\\c",code="200",} 17
+node_temperature{sensor="a"} NaN
+node_limit +Inf
+`
+	samples, err := ParseTextFormat(strings.NewReader(input))
+
+	if err != nil {
+		t.Fatalf("ParseTextFormat() got an unexpected error: %s", err.Error())
+	}
+	if len(samples) != 5 {
+		t.Fatalf("ParseTextFormat() = %d samples, want 5", len(samples))
+	}
+
+	if !reflect.DeepEqual(samples[0], Sample{Name: "node_load1", Labels: map[string]string{}, Value: 0.42}) {
+		t.Errorf("ParseTextFormat() first sample = %+v", samples[0])
+	}
+
+	wantTime := time.Unix(1593505924, 0).UTC()
+	want := Sample{Name: "node_cpu_seconds_total", Labels: map[string]string{"cpu": "0", "mode": "idle"}, Value: 1234.5, Time: &wantTime}
+	if !reflect.DeepEqual(samples[1], want) {
+		t.Errorf("ParseTextFormat() second sample = %+v, want %+v", samples[1], want)
+	}
+
+	if samples[2].Labels["path"] != `/a "b"\c` || samples[2].Labels["code"] != "200" {
+		t.Errorf("ParseTextFormat() escaped labels = %v", samples[2].Labels)
+	}
+	if !math.IsNaN(samples[3].Value) || !math.IsInf(samples[4].Value, 1) {
+		t.Errorf("ParseTextFormat() special values = %v, %v", samples[3].Value, samples[4].Value)
+	}
+}
+
+func TestParseTextFormat_InvalidLines(t *testing.T) {
+	for _, line := range []string{
+		"node_load1",
+		"node_load1 abc",
+		"node_load1 1 2 3",
+		"node_load1 1 yesterday",
+		`node_cpu{cpu="0" 1`,
+		`node_cpu{cpu=0} 1`,
+	} {
+		_, err := ParseTextFormat(strings.NewReader("up 1\n" + line))
+		if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+			t.Errorf("ParseTextFormat(%q) = %v, want an error in line 2", line, err)
+		}
+	}
+}
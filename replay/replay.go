@@ -0,0 +1,178 @@
+/*
+Package replay replays the events and alarms of a device in chronological order, e.g. for incident analysis.
+*/
+package replay
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/events"
+	"github.com/tarent/gomulocity/generic"
+	"sort"
+	"time"
+)
+
+const defaultPageSize = 100
+
+/*
+Record is a single event or alarm of a replay. Exactly one of `Event` and `Alarm` is set.
+*/
+type Record struct {
+	Time  time.Time
+	Event *events.Event
+	Alarm *alarm.Alarm
+}
+
+// Handles a record of a replay. Returning an error stops the replay.
+type Handler func(record Record) error
+
+/*
+Replayer merges the events and alarms of a device by their time and passes them to a handler.
+*/
+type Replayer struct {
+	PageSize int // The page size to fetch events and alarms. Default: 100
+	// The playback speed. If 0, the records are replayed as fast as possible. Otherwise the replay waits between two
+	// records for the time between them, divided by the speed: 1 means real time, 60 means one hour per minute.
+	Speed float64
+
+	eventsApi events.Events
+	alarmApi  alarm.AlarmApi
+	sleep     func(d time.Duration)
+}
+
+// Creates a new replayer, which replays as fast as possible.
+// eventsApi - The events api used to find events. If nil, no events are replayed.
+// alarmApi - The alarm api used to find alarms. If nil, no alarms are replayed.
+func NewReplayer(eventsApi events.Events, alarmApi alarm.AlarmApi) *Replayer {
+	return &Replayer{PageSize: defaultPageSize, eventsApi: eventsApi, alarmApi: alarmApi, sleep: time.Sleep}
+}
+
+/*
+Replays all events and alarms of the source within the time window [from, to] in ascending order of their time.
+On the same time, events are replayed before alarms.
+
+Events are fetched page by page while replaying. Alarms can not be requested in ascending order, so all alarms
+of the time window are fetched before the replay starts.
+*/
+func (replayer *Replayer) Replay(sourceId string, from time.Time, to time.Time, handler Handler) *generic.Error {
+	if from.After(to) {
+		return generic.ClientError("The start of the replay must not be after its end", "Replay")
+	}
+	pageSize := replayer.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	alarms, err := replayer.findAlarms(sourceId, from, to, pageSize)
+	if err != nil {
+		return err
+	}
+
+	eventStream := &eventStream{api: replayer.eventsApi, pageSize: pageSize,
+		filter: &events.EventFilter{Source: sourceId, DateFrom: &from, DateTo: &to, Revert: true}}
+
+	var previous *time.Time
+	for {
+		event, err := eventStream.peek()
+		if err != nil {
+			return err
+		}
+
+		var record Record
+		switch {
+		case event == nil && len(alarms) == 0:
+			return nil
+		case event != nil && (len(alarms) == 0 || !alarms[0].Time.Before(event.Time)):
+			record = Record{Time: event.Time, Event: event}
+			eventStream.next()
+		default:
+			record = Record{Time: *alarms[0].Time, Alarm: &alarms[0]}
+			alarms = alarms[1:]
+		}
+
+		if previous != nil && replayer.Speed > 0 && record.Time.After(*previous) {
+			replayer.sleep(time.Duration(float64(record.Time.Sub(*previous)) / replayer.Speed))
+		}
+		previous = &record.Time
+
+		if handlerErr := handler(record); handlerErr != nil {
+			return generic.ClientError(fmt.Sprintf("Replay stopped by handler at %s: %s", record.Time.Format(time.RFC3339), handlerErr.Error()), "Replay")
+		}
+	}
+}
+
+// -- internal
+
+// Returns all alarms of the time window sorted by time. Alarms without time are skipped.
+func (replayer *Replayer) findAlarms(sourceId string, from time.Time, to time.Time, pageSize int) ([]alarm.Alarm, *generic.Error) {
+	if replayer.alarmApi == nil {
+		return nil, nil
+	}
+
+	var alarms []alarm.Alarm
+	filter := &alarm.AlarmFilter{SourceId: sourceId, DateFrom: &from, DateTo: &to}
+	collection, err := replayer.alarmApi.Find(filter, pageSize)
+	for ; collection != nil && err == nil; collection, err = replayer.alarmApi.NextPage(collection) {
+		for _, a := range collection.Alarms {
+			if a.Time != nil {
+				alarms = append(alarms, a)
+			}
+		}
+		if len(collection.Alarms) < pageSize {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(alarms, func(i, j int) bool {
+		return alarms[i].Time.Before(*alarms[j].Time)
+	})
+	return alarms, nil
+}
+
+// eventStream iterates over the pages of events in ascending order of time.
+type eventStream struct {
+	api      events.Events
+	filter   *events.EventFilter
+	pageSize int
+
+	collection *events.EventCollection
+	index      int
+	done       bool
+}
+
+// Returns the current event without consuming it or nil, if there are no more events.
+func (stream *eventStream) peek() (*events.Event, *generic.Error) {
+	for !stream.done && (stream.collection == nil || stream.index >= len(stream.collection.Events)) {
+		var collection *events.EventCollection
+		var err *generic.Error
+
+		switch {
+		case stream.api == nil:
+		case stream.collection == nil:
+			collection, err = stream.api.Find(stream.filter, stream.pageSize)
+		case len(stream.collection.Events) == stream.pageSize:
+			collection, err = stream.api.NextPage(stream.collection)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if collection == nil || len(collection.Events) == 0 {
+			stream.done = true
+		}
+		stream.collection = collection
+		stream.index = 0
+	}
+
+	if stream.done {
+		return nil, nil
+	}
+	return &stream.collection.Events[stream.index], nil
+}
+
+func (stream *eventStream) next() {
+	stream.index++
+}
@@ -0,0 +1,156 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/events"
+	"github.com/tarent/gomulocity/generic"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var replayStart, _ = time.Parse(time.RFC3339, "2020-06-30T10:00:00Z")
+
+// A test server with two pages of events in ascending order and one page of alarms in descending order.
+func buildReplayHttpServer(queries *[]string) *httptest.Server {
+	event := `{"id": "%s", "type": "replay-Event", "time": "%s", "source": {"id": "4711"}}`
+	alarm := `{"id": "%s", "type": "replay-Alarm", "time": "%s", "source": {"id": "4711"}, "status": "ACTIVE", "severity": "MAJOR"}`
+	at := func(minutes int) string {
+		return replayStart.Add(time.Duration(minutes) * time.Minute).Format(time.RFC3339)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.URL.String())
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/alarm/alarms"):
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"alarms": [%s, %s]}`,
+				fmt.Sprintf(alarm, "a2", at(3)), fmt.Sprintf(alarm, "a1", at(1)))))
+		case r.URL.Query().Get("currentPage") == "":
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"next": "http://%s/event/events?pageSize=2&currentPage=2", "events": [%s, %s]}`,
+				r.Host, fmt.Sprintf(event, "e1", at(0)), fmt.Sprintf(event, "e2", at(1)))))
+		default:
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"events": [%s]}`, fmt.Sprintf(event, "e3", at(5)))))
+		}
+	}))
+}
+
+func buildReplayer(url string) *Replayer {
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: url, Username: "foo", Password: "bar"}
+	replayer := NewReplayer(events.NewEventsApi(client), alarm.NewAlarmApi(client))
+	replayer.PageSize = 2
+	return replayer
+}
+
+func TestReplay_MergesEventsAndAlarms(t *testing.T) {
+	var queries []string
+	ts := buildReplayHttpServer(&queries)
+	defer ts.Close()
+
+	replayer := buildReplayer(ts.URL)
+
+	var ids []string
+	err := replayer.Replay("4711", replayStart, replayStart.Add(time.Hour), func(record Record) error {
+		if record.Event != nil {
+			ids = append(ids, record.Event.Id)
+		} else {
+			ids = append(ids, record.Alarm.Id)
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Replay() got an unexpected error: %s", err.Error())
+	}
+	if strings.Join(ids, ",") != "e1,e2,a1,a2,e3" {
+		t.Errorf("Replay() order = %v, want [e1 e2 a1 a2 e3]", ids)
+	}
+
+	wantEventQuery := "/event/events?dateFrom=2020-06-30T10%3A00%3A00Z&dateTo=2020-06-30T11%3A00%3A00Z&pageSize=2&revert=true&source=4711"
+	if len(queries) != 3 || queries[1] != wantEventQuery {
+		t.Errorf("Replay() queries = %v, want event query %s", queries, wantEventQuery)
+	}
+}
+
+func TestReplay_SpeedScaledPlayback(t *testing.T) {
+	var queries []string
+	ts := buildReplayHttpServer(&queries)
+	defer ts.Close()
+
+	replayer := buildReplayer(ts.URL)
+	replayer.Speed = 60
+
+	var waits []time.Duration
+	replayer.sleep = func(d time.Duration) {
+		waits = append(waits, d)
+	}
+
+	err := replayer.Replay("4711", replayStart, replayStart.Add(time.Hour), func(record Record) error { return nil })
+
+	if err != nil {
+		t.Fatalf("Replay() got an unexpected error: %s", err.Error())
+	}
+
+	// e1 -> e2: 1 minute, e2 -> a1: none, a1 -> a2: 2 minutes, a2 -> e3: 2 minutes - each one minute per second
+	if fmt.Sprint(waits) != "[1s 2s 2s]" {
+		t.Errorf("Replay() waits = %v, want [1s 2s 2s]", waits)
+	}
+}
+
+func TestReplay_StoppedByHandler(t *testing.T) {
+	var queries []string
+	ts := buildReplayHttpServer(&queries)
+	defer ts.Close()
+
+	replayer := buildReplayer(ts.URL)
+
+	count := 0
+	err := replayer.Replay("4711", replayStart, replayStart.Add(time.Hour), func(record Record) error {
+		count++
+		if count == 2 {
+			return errors.New("enough")
+		}
+		return nil
+	})
+
+	if err == nil || !strings.Contains(err.Message, "enough") || count != 2 {
+		t.Errorf("Replay() expected to stop after 2 records with error, got %d records, %v", count, err)
+	}
+}
+
+func TestReplay_InvalidWindow(t *testing.T) {
+	replayer := buildReplayer("")
+
+	err := replayer.Replay("4711", replayStart.Add(time.Hour), replayStart, func(record Record) error { return nil })
+
+	if err == nil {
+		t.Errorf("Replay() expected an error for a reverted time window")
+	}
+}
+
+func TestReplay_EventsOnly(t *testing.T) {
+	var queries []string
+	ts := buildReplayHttpServer(&queries)
+	defer ts.Close()
+
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL}
+	replayer := NewReplayer(events.NewEventsApi(client), nil)
+
+	count := 0
+	err := replayer.Replay("4711", replayStart, replayStart.Add(time.Hour), func(record Record) error {
+		if record.Alarm != nil {
+			t.Errorf("Replay() unexpected alarm %v", record.Alarm)
+		}
+		count++
+		return nil
+	})
+
+	// The first page is smaller than the default page size, so no further page is requested
+	if err != nil || count != 2 {
+		t.Errorf("Replay() = %d records, %v - want 2 events", count, err)
+	}
+}